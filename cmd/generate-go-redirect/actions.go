@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/pkg/vanity"
+)
+
+// actions integrates the commands into a GitHub Actions workflow, when they run in one, by the GITHUB_ACTIONS env variable:
+// the failures and the findings become annotations, the phases of a command are grouped in the log,
+// and the outcome of a command is written to the outputs of its step.
+// The workflow commands go to the standard error, so they keep their order with the logs,
+// and they don't mix with the output of the commands, like list -o json.
+var actions = githubActions{Out: os.Stderr}
+
+type githubActions struct {
+	// Enabled turns on the workflow commands.
+	Enabled bool
+	// Out is where the workflow commands are written.
+	Out io.Writer
+}
+
+// group starts a collapsible group of the log lines, which the returned function ends.
+func (a githubActions) group(title string) (end func()) {
+	if !a.Enabled {
+		return func() {}
+	}
+	fmt.Fprintf(a.Out, "::group::%s\n", escapeWorkflowData(title))
+	return func() { fmt.Fprintln(a.Out, "::endgroup::") }
+}
+
+// annotate writes an error or a warning annotation, which GitHub shows on the summary of the run,
+// and on the file in the pull request when it is set.
+func (a githubActions) annotate(severity, file, title, message string) {
+	if !a.Enabled {
+		return
+	}
+	var props []string
+	if file != "" {
+		props = append(props, "file="+escapeWorkflowProperty(file))
+	}
+	if title != "" {
+		props = append(props, "title="+escapeWorkflowProperty(title))
+	}
+	command := severity
+	if 0 < len(props) {
+		command += " " + strings.Join(props, ",")
+	}
+	fmt.Fprintf(a.Out, "::%s::%s\n", command, escapeWorkflowData(message))
+}
+
+// annotateError annotates the failure of a command.
+// The invalid entries of an imports file are annotated one by one, like the failed modules of a generation.
+func (a githubActions) annotateError(err error) {
+	if !a.Enabled {
+		return
+	}
+	var failures vanity.ModuleErrors
+	if errors.As(err, &failures) {
+		for _, failure := range failures {
+			a.annotate("error", "", failure.Prefix, failure.Err.Error())
+		}
+		return
+	}
+	var invalid vanity.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, v := range invalid {
+			a.annotate("error", "", "invalid imports file", v.Error())
+		}
+		return
+	}
+	a.annotate("error", "", "", err.Error())
+}
+
+// annotatedFile is the imports file that an annotation points to,
+// which is left out for a remote imports file, or a directory of module files.
+func annotatedFile(imports string) string {
+	if vanity.IsRemoteImports(imports) {
+		return ""
+	}
+	if info, err := os.Stat(imports); err != nil || info.IsDir() {
+		return ""
+	}
+	return filepath.ToSlash(imports)
+}
+
+// setOutputs appends the outputs of the step to the file of the GITHUB_OUTPUT env variable, in the order of their names.
+func (a githubActions) setOutputs(outputs map[string]string) error {
+	path := getenv("GITHUB_OUTPUT")
+	if !a.Enabled || path == "" {
+		return nil
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		value := outputs[name]
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&b, "%s=%s\n", name, value)
+			continue
+		}
+		// a multiline value is written between the lines of a delimiter, which the value doesn't contain
+		delimiter := "EOF"
+		for strings.Contains(value, delimiter) {
+			delimiter += "_EOF"
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the step outputs: %w", err)
+	}
+	_, err = io.WriteString(f, b.String())
+	return errorkit.Merge(err, f.Close())
+}
+
+// changeOutputs are the step outputs of the changes of a generation or a deployment:
+// the number of the changed files, and the modules whose files changed, as a JSON array.
+func changeOutputs(changes []vanity.Change) map[string]string {
+	var (
+		counts  = map[vanity.ChangeKind]int{}
+		seen    = map[string]struct{}{}
+		modules = []string{}
+	)
+	for _, change := range changes {
+		counts[change.Kind]++
+		if change.Kind == vanity.ChangeUnchanged || change.File.Module == "" {
+			continue
+		}
+		if _, ok := seen[change.File.Module]; !ok {
+			seen[change.File.Module] = struct{}{}
+			modules = append(modules, change.File.Module)
+		}
+	}
+	sort.Strings(modules)
+	return map[string]string{
+		"changed-files":   fmt.Sprint(counts[vanity.ChangeCreate] + counts[vanity.ChangeUpdate] + counts[vanity.ChangeDelete]),
+		"unchanged-files": fmt.Sprint(counts[vanity.ChangeUnchanged]),
+		"changed-modules": jsonOutput(modules),
+	}
+}
+
+// jsonOutput is a step output that a workflow can read with the fromJSON expression.
+func jsonOutput(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
+	"gopkg.in/yaml.v3"
+)
+
+func addCommand(ctx context.Context, args []string) error {
+	var (
+		conf       Config
+		entry      vanity.ImportDTO
+		submodules string
+	)
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&entry.ImportPrefix, "prefix", "", "import prefix of the module (default: derived from the repository name)")
+	fs.StringVar(&entry.RootRepo, "repo", "", "repository root URL of the module")
+	fs.StringVar(&entry.VCS, "vcs", "git", "version control system of the repository: git, hg, svn, bzr, fossil or mod")
+	fs.StringVar(&entry.Origin, "origin", "", "git repository of a mod module, which the built-in module proxy serves it from")
+	fs.StringVar(&entry.HomepageURL, "homepage", "", "homepage URL of the module (default: the repository)")
+	fs.StringVar(&submodules, "submodules", "", "comma separated list of nested module directories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	if vanity.IsRemoteImports(conf.Imports) {
+		return fmt.Errorf("modules can't be added to a remote imports file: %s", conf.Imports)
+	}
+	if entry.ImportPrefix == "" && entry.RootRepo != "" {
+		entry.ImportPrefix = defaultPrefix(conf.primaryDomain(), entry.RootRepo)
+	}
+	if submodules != "" {
+		entry.Submodules = strings.Split(submodules, ",")
+	}
+
+	dtos, err := vanity.ReadImports(ctx, conf.Imports, conf.ImportsOptions)
+	if err != nil {
+		return err
+	}
+	for _, dto := range dtos {
+		if dto.ImportPrefix == entry.ImportPrefix {
+			return fmt.Errorf("%s is already in the imports file", entry.ImportPrefix)
+		}
+	}
+	if err := vanity.ValidateImports(append(dtos, entry), conf.Domains()...); err != nil {
+		return fmt.Errorf("invalid module:\n%w", err)
+	}
+
+	entry, err = pinSource(ctx, conf, entry)
+	if err != nil {
+		return err
+	}
+
+	location, err := appendImport(conf, entry)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s is added to %s\n", entry.ImportPrefix, location)
+	return nil
+}
+
+// pinSource fills the go-source patterns of the entry with their defaults,
+// so they are visible and editable in the imports file.
+// The patterns point to the detected default branch, since a pinned pattern is not detected again later.
+func pinSource(ctx context.Context, conf Config, entry vanity.ImportDTO) (vanity.ImportDTO, error) {
+	branch := entry.Branch
+	if conf.DetectBranch {
+		entry = conf.Branches.DetectBranches(ctx, []vanity.ImportDTO{entry})[0]
+	}
+	meta, err := vanity.ToMeta(entry)
+	if err != nil {
+		return entry, err
+	}
+	entry.DirectoryPattern = meta.Source.DirectoryPattern
+	entry.FilePattern = meta.Source.FilePattern
+	entry.Branch = branch
+	return entry, nil
+}
+
+// appendImport adds an entry to the imports file, while it keeps the existing content, including the comments.
+// When the imports location is a directory, the entry is written into a new module file.
+func appendImport(conf Config, entry vanity.ImportDTO) (string, error) {
+	info, err := os.Stat(conf.Imports)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return addModuleFile(conf, entry)
+	}
+
+	format, err := vanity.ImportsFormat(conf.Imports, conf.ImportsOptions.Format)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(conf.Imports)
+	if err != nil {
+		return "", err
+	}
+	var out []byte
+	switch format {
+	case vanity.FormatJSON:
+		out, err = appendJSONImport(data, entry)
+	case vanity.FormatYAML:
+		out, err = appendYAMLImport(data, entry)
+	case vanity.FormatTOML:
+		out, err = appendTOMLImport(data, entry)
+	}
+	if err != nil {
+		return "", err
+	}
+	return conf.Imports, os.WriteFile(conf.Imports, out, info.Mode().Perm())
+}
+
+func appendJSONImport(data []byte, entry vanity.ImportDTO) ([]byte, error) {
+	// the standardized JSON has the same offsets as the original, but without the comments
+	std := vanity.StandardizeJSON(data)
+	start, end := bytes.IndexByte(std, '['), bytes.LastIndexByte(std, ']')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the imports file is not a JSON array")
+	}
+
+	prefix, indent := jsonIndentation(std[start:end])
+	body, err := json.MarshalIndent(entry, prefix, indent)
+	if err != nil {
+		return nil, err
+	}
+
+	// the entry goes after the line of the last entry, so a trailing comment stays on the line of the entry that it's about
+	last := len(bytes.TrimRight(std[:end], " \t\r\n")) - 1
+	lineEnd := end
+	if i := bytes.IndexByte(data[last+1:end], '\n'); 0 <= i {
+		lineEnd = last + 1 + i
+	}
+	if bytes.Contains(data[last+1:lineEnd], []byte("/*")) {
+		// a block comment may go on to the next lines
+		lineEnd = last + 1
+	}
+	// the trailing comma of the last entry is blanked out in the standardized JSON
+	comma := ","
+	if last == start || bytes.HasPrefix(bytes.TrimLeft(data[last+1:lineEnd], " \t"), []byte(",")) {
+		comma = ""
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:last+1])
+	out.WriteString(comma)
+	out.Write(data[last+1 : lineEnd])
+	out.WriteString("\n" + prefix)
+	out.Write(body)
+	out.Write(data[lineEnd:])
+	return out.Bytes(), nil
+}
+
+// jsonIndentation tells the indentation of the entries in a JSON array,
+// so the appended entry looks like the rest of the file.
+func jsonIndentation(array []byte) (prefix, indent string) {
+	prefix, indent = "  ", "  "
+	lines := strings.Split(string(array), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "{" || i+1 == len(lines) {
+			continue
+		}
+		prefix = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		next := lines[i+1]
+		if fieldIndent := next[:len(next)-len(strings.TrimLeft(next, " \t"))]; strings.HasPrefix(fieldIndent, prefix) && len(prefix) < len(fieldIndent) {
+			indent = fieldIndent[len(prefix):]
+		}
+		break
+	}
+	return prefix, indent
+}
+
+func appendYAMLImport(data []byte, entry vanity.ImportDTO) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 { // empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.SequenceNode, Tag: "!!seq"}}}
+	}
+	seq := doc.Content[0]
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("the imports file is not a YAML list")
+	}
+	var node yaml.Node
+	if err := node.Encode(entry); err != nil {
+		return nil, err
+	}
+	seq.Content = append(seq.Content, &node)
+	seq.Style = 0 // an empty list is often written in flow style as []
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func appendTOMLImport(data []byte, entry vanity.ImportDTO) ([]byte, error) {
+	var out bytes.Buffer
+	out.Write(data)
+	if 0 < len(data) && !bytes.HasSuffix(data, []byte("\n")) {
+		out.WriteString("\n")
+	}
+	out.WriteString("\n[[modules]]\n")
+	if err := toml.NewEncoder(&out).Encode(entry); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// addModuleFile writes the entry into a new file of the imports directory.
+// The file is named after the import path, and it uses the same format as the other module files.
+func addModuleFile(conf Config, entry vanity.ImportDTO) (string, error) {
+	format := conf.ImportsOptions.Format
+	if format == "" {
+		format = vanity.FormatJSON
+		entries, err := os.ReadDir(conf.Imports)
+		if err != nil {
+			return "", err
+		}
+		for _, e := range entries {
+			if f, err := vanity.ImportsFormat(e.Name(), ""); err == nil && !e.IsDir() {
+				format = f
+				break
+			}
+		}
+	}
+	format, err := vanity.ImportsFormat("", format)
+	if err != nil {
+		return "", err
+	}
+
+	name := entry.ImportPrefix
+	for _, domain := range conf.Domains() {
+		if rest, ok := strings.CutPrefix(entry.ImportPrefix, domain); ok && (rest == "" || rest[0] == '/') {
+			name = strings.TrimPrefix(rest, "/")
+			break
+		}
+	}
+	name = strings.ReplaceAll(zerokit.Coalesce(name, "root"), "/", "-") + "." + format
+	location := filepath.Join(conf.Imports, name)
+	if _, err := os.Stat(location); err == nil {
+		return "", fmt.Errorf("%s already exists", location)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	var data []byte
+	switch format {
+	case vanity.FormatJSON:
+		data, err = json.MarshalIndent(entry, "", "  ")
+		data = append(data, '\n')
+	case vanity.FormatYAML:
+		data, err = yaml.Marshal(entry)
+	case vanity.FormatTOML:
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(entry)
+		data = buf.Bytes()
+	}
+	if err != nil {
+		return "", err
+	}
+	return location, os.WriteFile(location, data, 0644)
+}
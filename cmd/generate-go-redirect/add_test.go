@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func TestAppendJSONImport(t *testing.T) {
+	entry := vanity.ImportDTO{VCS: "git", ImportPrefix: "go.llib.dev/b", RootRepo: "https://github.com/x/b"}
+	const b = `  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/b",
+    "root-repo": "https://github.com/x/b"
+  }`
+	for name, tc := range map[string]struct{ in, want string }{
+		"trailing line comment": {
+			in: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  } // the a module
+]
+`,
+			want: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  }, // the a module
+` + b + `
+]
+`,
+		},
+		"trailing comma": {
+			in: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  },
+]
+`,
+			want: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  },
+` + b + `
+]
+`,
+		},
+		"trailing comma and line comment": {
+			in: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  }, // the a module
+]
+`,
+			want: `[
+  {
+    "vcs": "git",
+    "import-prefix": "go.llib.dev/a",
+    "root-repo": "https://github.com/x/a"
+  }, // the a module
+` + b + `
+]
+`,
+		},
+		"empty array": {
+			in:   "[\n]\n",
+			want: "[\n" + b + "\n]\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, err := appendJSONImport([]byte(tc.in), entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tc.want {
+				t.Fatalf("unexpected imports file:\n%s\nwant:\n%s", out, tc.want)
+			}
+			path := filepath.Join(t.TempDir(), "imports.json")
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				t.Fatal(err)
+			}
+			dtos, err := vanity.ReadImports(context.Background(), path, vanity.ImportsOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dtos[len(dtos)-1].ImportPrefix; got != entry.ImportPrefix {
+				t.Fatalf("the last entry is %s, want %s", got, entry.ImportPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/pkg/vanity"
+)
+
+func checkCommand(ctx context.Context, args []string) error {
+	var conf Config
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	conf.Bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports, flagOut); err != nil {
+		return err
+	}
+	// the branch only matters for the go-source meta, and its detection would need the network
+	conf.DetectBranch = false
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return err
+	}
+
+	var (
+		resolutions []vanity.Resolution
+		errs        []error
+	)
+	sites := gen.Sites()
+	if len(sites) == 0 {
+		sites = []string{gen.Domain}
+	}
+	for _, domain := range sites {
+		dir := conf.WebDirPath
+		if 1 < len(sites) || domain != gen.Domain {
+			// the site of a multi-domain or wildcard domain is in the subdirectory of each domain
+			dir = filepath.Join(dir, domain)
+		}
+		res, err := vanity.CheckSite(os.DirFS(dir), domain, gen.Modules)
+		resolutions = append(resolutions, res...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errorkit.Merge(errs...); err != nil {
+		return fmt.Errorf("go get resolution check failed:\n%w", err)
+	}
+	sort.Slice(resolutions, func(i, j int) bool { return resolutions[i].ImportPath < resolutions[j].ImportPath })
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, res := range resolutions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", res.ImportPath, res.VCS, res.RepoRoot)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d import paths resolve\n", len(resolutions))
+	return nil
+}
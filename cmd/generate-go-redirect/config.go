@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/pkg/vanity"
+)
+
+// Config holds the settings that the commands share.
+// Each setting defaults to its environment variable, and a flag can override it.
+type Config struct {
+	// Domain is the vanity domain, e.g. go.llib.dev, with the base path of a site hosted under a path, like example.com/go,
+	// or the comma separated list of the domains of a multi-domain site, like go.llib.dev,go.example.dev
+	Domain string
+	// WebDirPath is the directory where the static site is generated.
+	WebDirPath string
+	// Imports is the location of the imports file.
+	// It can be a file, a directory of module files, or an http(s) URL.
+	Imports string
+	// ImportsOptions configure how the imports file is read.
+	ImportsOptions vanity.ImportsOptions
+	// ScanSubmodules adds the nested modules found in the repositories to the configured submodules.
+	ScanSubmodules bool
+	// Scanner finds the nested modules of the repositories.
+	Scanner vanity.SubmoduleScanner
+	// DetectMajors adds the major versions found in the version tags of the repositories to the configured majors.
+	DetectMajors bool
+	// DetectBranch turns on the detection of the repositories' default branches.
+	DetectBranch bool
+	// Branches detects the default branches of the repositories.
+	Branches vanity.BranchDetector
+}
+
+const (
+	flagDomain  = "domain"
+	flagOut     = "out"
+	flagImports = "imports"
+)
+
+func (c *Config) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.Domain, flagDomain, getenv("DOMAIN"), "vanity domain of the import paths, or a comma separated list of domains (env: DOMAIN)")
+	fs.StringVar(&c.WebDirPath, flagOut, getenv("WEB_DIR_PATH"), "output directory of the static site (env: WEB_DIR_PATH)")
+	fs.StringVar(&c.Imports, flagImports, getImportsLocation(), "imports file, directory or URL (env: IMPORTS_FILE_PATH or IMPORTS_URL)")
+	fs.StringVar(&c.ImportsOptions.Format, "format", "", "format of the imports file (json, yaml, toml)")
+	fs.BoolVar(&c.ImportsOptions.Strict, "strict", false, "reject comments and trailing commas in JSON imports files")
+	c.ImportsOptions.Remote = remoteImports
+	fs.BoolVar(&c.ScanSubmodules, "scan-submodules", getenv("SCAN_SUBMODULES") == "true", "find the nested modules by the go.mod files of the repositories (env: SCAN_SUBMODULES)")
+	fs.BoolVar(&c.DetectMajors, "detect-majors", getenv("DETECT_MAJORS") == "true", "generate the pages of the major versions found in the version tags of the repositories (env: DETECT_MAJORS)")
+	fs.BoolVar(&c.DetectBranch, "detect-branch", getenv("DETECT_BRANCH") != "false", "point the go-source links to the default branch of the repositories, rather than to master (env: DETECT_BRANCH)")
+	fs.DurationVar(&c.Branches.TTL, "branch-ttl", 24*time.Hour, "how long a detected default branch is cached")
+	c.Branches.CacheFile = defaultBranchCacheFile()
+	fs.StringVar(&c.Scanner.CheckoutDir, "checkouts", getenv("CHECKOUTS_DIR"), "directory of local repository checkouts to scan instead of cloning them (env: CHECKOUTS_DIR)")
+	fs.Var(logLevelFlag{}, "log-level", "level of the logged messages: debug, info, warn or error (env: LOG_LEVEL)")
+	fs.Var(logFormatFlag{}, "log-format", "format of the log output: text or json (default text) (env: LOG_FORMAT)")
+	fs.BoolVar(&actions.Enabled, "github-actions", actions.Enabled, "write the annotations, the log groups and the step outputs of a GitHub Actions workflow (env: GITHUB_ACTIONS)")
+}
+
+// Require checks that the settings behind the given flag names are set.
+func (c Config) Require(flagNames ...string) error {
+	var errs []error
+	for _, name := range flagNames {
+		var value, envKey string
+		switch name {
+		case flagDomain:
+			value, envKey = c.Domain, "DOMAIN"
+		case flagOut:
+			value, envKey = c.WebDirPath, "WEB_DIR_PATH"
+		case flagImports:
+			value, envKey = c.Imports, "IMPORTS_FILE_PATH"
+		default:
+			continue
+		}
+		if value == "" {
+			errs = append(errs, fmt.Errorf("missing -%s flag or %s env variable", name, envKey))
+		}
+	}
+	return errorkit.Merge(errs...)
+}
+
+// Domains lists the vanity domains of the Domain setting.
+func (c Config) Domains() []string {
+	var domains []string
+	for _, domain := range strings.Split(c.Domain, ",") {
+		if domain = strings.TrimSuffix(strings.TrimSpace(domain), "/"); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// primaryDomain is the first of the domains, which the new modules are added under by default.
+func (c Config) primaryDomain() string {
+	if domains := c.Domains(); len(domains) > 0 {
+		return domains[0]
+	}
+	return ""
+}
+
+// Load configures the generator with the domains, and the modules of the imports file.
+func (c Config) Load(ctx context.Context, gen *vanity.Generator) error {
+	gen.Domain = c.primaryDomain()
+	if domains := c.Domains(); len(domains) > 1 {
+		gen.Domains = domains
+	}
+	if c.DetectBranch {
+		gen.BranchDetector = &c.Branches
+	}
+	if err := gen.LoadConfig(ctx, c.Imports, c.ImportsOptions); err != nil {
+		return err
+	}
+	if c.ScanSubmodules {
+		metas, err := vanity.ScanSubmodules(ctx, gen.Modules, c.Scanner)
+		if err != nil {
+			return fmt.Errorf("submodule scan failed:\n%w", err)
+		}
+		gen.Modules = metas
+	}
+	if c.DetectMajors {
+		// after the scan, so the tags of the scanned submodules count too
+		metas, err := vanity.DetectMajors(ctx, gen.Modules)
+		if err != nil {
+			return fmt.Errorf("major version detection failed:\n%w", err)
+		}
+		gen.Modules = metas
+	}
+	return nil
+}
+
+// LocaleConfig configures the languages of the pages, which generate and serve share.
+type LocaleConfig struct {
+	// Language is the language tag of the default pages.
+	Language string
+	// Dir is the directory of the catalogs, with a JSON file of the texts for every language, like de.json.
+	Dir string
+}
+
+func (c *LocaleConfig) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.Language, "lang", getenv("SITE_LANGUAGE"), "language tag of the default pages, whose texts are of its catalog in the -locales (default: en) (env: SITE_LANGUAGE)")
+	fs.StringVar(&c.Dir, "locales", getenv("LOCALES_DIR"), "directory of the catalogs of the page texts, with a JSON file per language, like de.json (env: LOCALES_DIR)")
+}
+
+// Catalogs reads the catalogs of the locales directory, which are none when it isn't set.
+func (c LocaleConfig) Catalogs() (map[string]vanity.Catalog, error) {
+	if c.Dir == "" {
+		return nil, nil
+	}
+	return vanity.ReadCatalogs(os.DirFS(c.Dir))
+}
+
+// bindSecurityHeaders binds the flags of the security headers of the pages, which generate and serve share.
+func bindSecurityHeaders(fs *flag.FlagSet, h *vanity.SecurityHeaders) {
+	fs.StringVar(&h.ContentSecurityPolicy, "csp", getenv("CONTENT_SECURITY_POLICY"), "Content-Security-Policy of the pages, off leaves it out (default: the site's own resources and the page's inline scripts by their hashes) (env: CONTENT_SECURITY_POLICY)")
+	fs.StringVar(&h.ContentTypeOptions, "content-type-options", getenv("CONTENT_TYPE_OPTIONS"), "X-Content-Type-Options header, off leaves it out (default: nosniff) (env: CONTENT_TYPE_OPTIONS)")
+	fs.StringVar(&h.ReferrerPolicy, "referrer-policy", getenv("REFERRER_POLICY"), "Referrer-Policy header, off leaves it out (default: strict-origin-when-cross-origin) (env: REFERRER_POLICY)")
+}
+
+// bindPurge binds the flag of the CDN whose cache is purged of the changed files, which generate and deploy share.
+func bindPurge(fs *flag.FlagSet, cdn *string) {
+	fs.StringVar(cdn, "purge", getenv("PURGE_CDN"), "CDN whose cache is purged of the changed files after the site is written: cloudflare, fastly or cloudfront (env: PURGE_CDN)")
+}
+
+func defaultBranchCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "generate-go-redirect", "branches.json")
+}
+
+// remoteImports configures the fetching of an imports file from a URL, by the IMPORTS_URL_ env variables.
+var remoteImports vanity.RemoteImportsConfig
+
+// loadRemoteImports reads the remoteImports from the env variables, like the rest of the settings.
+func loadRemoteImports() error {
+	if timeout, ok := lookupEnv("IMPORTS_URL_TIMEOUT"); ok {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("invalid IMPORTS_URL_TIMEOUT: %w", err)
+		}
+		remoteImports.Timeout = d
+	}
+	if retries, ok := lookupEnv("IMPORTS_URL_RETRIES"); ok {
+		n, err := strconv.Atoi(retries)
+		if err != nil {
+			return fmt.Errorf("invalid IMPORTS_URL_RETRIES: %w", err)
+		}
+		if n == 0 {
+			// zero is the default of the retries, which a negative number turns off
+			n = -1
+		}
+		remoteImports.Retries = n
+	}
+	remoteImports.SHA256 = getenv("IMPORTS_URL_SHA256")
+	return nil
+}
+
+func getImportsLocation() string {
+	if location, ok := lookupEnv("IMPORTS_URL"); ok {
+		return location
+	}
+	return getenv("IMPORTS_FILE_PATH")
+}
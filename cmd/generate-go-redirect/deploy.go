@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
+)
+
+func deployCommand(ctx context.Context, args []string) error {
+	var (
+		conf      Config
+		bucketURL string
+		endpoint  string
+		cdn       string
+		opts      vanity.DeployOptions
+	)
+	fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&bucketURL, "bucket", getenv("DEPLOY_BUCKET"), "bucket URL of the site, like s3://bucket/prefix, gs://bucket, azblob://account/container or ssh://user@host/var/www/site (env: DEPLOY_BUCKET)")
+	fs.StringVar(&endpoint, "endpoint", getenv("AWS_ENDPOINT_URL"), "endpoint of an S3 compatible storage, like Cloudflare R2 or MinIO, or of an Azure Blob service emulator (env: AWS_ENDPOINT_URL)")
+	fs.DurationVar(&opts.MaxAge, "max-age", 5*time.Minute, "how long the clients may cache the objects")
+	fs.BoolVar(&opts.Delete, "delete", true, "delete the objects that are no longer part of the site")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "print the plan of object changes without touching the bucket")
+	bindPurge(fs, &cdn)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	required := []string{flagOut}
+	if cdn != "" {
+		// the purged URLs are of the domain
+		required = append(required, flagDomain)
+	}
+	if err := conf.Require(required...); err != nil {
+		return err
+	}
+	if bucketURL == "" {
+		return fmt.Errorf("missing -bucket flag or DEPLOY_BUCKET env variable")
+	}
+	bucket, err := openBucket(bucketURL, endpoint)
+	if err != nil {
+		return err
+	}
+	var purger vanity.CachePurger
+	if cdn != "" && !opts.DryRun {
+		if purger, err = openPurger(cdn); err != nil {
+			return err
+		}
+	}
+
+	changes, err := vanity.Deploy(ctx, os.DirFS(conf.WebDirPath), bucket, opts)
+	if err != nil {
+		return fmt.Errorf("deploy failed: %w", err)
+	}
+	if opts.DryRun {
+		// the objects are compared by their hashes, so there is no old content to diff against
+		return printPlan(os.Stdout, changes, false)
+	}
+	var counts = map[vanity.ChangeKind]int{}
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	logger.Info(ctx, "site deployed",
+		logging.Field("uploaded", counts[vanity.ChangeCreate]+counts[vanity.ChangeUpdate]),
+		logging.Field("unchanged", counts[vanity.ChangeUnchanged]),
+		logging.Field("deleted", counts[vanity.ChangeDelete]))
+	outputs := changeOutputs(changes)
+	// the objects of a bucket don't tell their modules
+	delete(outputs, "changed-modules")
+	if err := actions.setOutputs(outputs); err != nil {
+		return err
+	}
+	if purger != nil {
+		return purgeCache(ctx, purger, deployedURLs(conf.Domains(), changes))
+	}
+	return nil
+}
+
+// deployedURLs are the URLs of the changed objects of a deployment.
+// The site of a multi-domain configuration has a directory per domain, like go.llib.dev/testcase/index.html.
+func deployedURLs(domains []string, changes []vanity.Change) []string {
+	if len(domains) < 2 {
+		return vanity.PurgeURLs(strings.Join(domains, ""), changes)
+	}
+	var (
+		sites = map[string][]vanity.Change{}
+		urls  []string
+	)
+	for _, change := range changes {
+		domain, name, ok := strings.Cut(change.File.Path, "/")
+		if !ok {
+			continue
+		}
+		change.File.Path = name
+		sites[domain] = append(sites[domain], change)
+	}
+	for domain, changes := range sites {
+		urls = append(urls, vanity.PurgeURLs(domain, changes)...)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// openPurger opens the cache purger of a CDN, with the credentials of the environment:
+//
+//	cloudflare   CLOUDFLARE_ZONE_ID and CLOUDFLARE_API_TOKEN
+//	fastly       FASTLY_API_TOKEN
+//	cloudfront   CLOUDFRONT_DISTRIBUTION_ID, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func openPurger(cdn string) (vanity.CachePurger, error) {
+	var missing []string
+	require := func(key string) string {
+		value := getenv(key)
+		if value == "" {
+			missing = append(missing, key)
+		}
+		return value
+	}
+	var purger vanity.CachePurger
+	switch cdn {
+	case "cloudflare":
+		purger = vanity.CloudflarePurger{ZoneID: require("CLOUDFLARE_ZONE_ID"), APIToken: require("CLOUDFLARE_API_TOKEN")}
+	case "fastly":
+		purger = vanity.FastlyPurger{APIToken: require("FASTLY_API_TOKEN")}
+	case "cloudfront":
+		purger = vanity.CloudFrontPurger{
+			DistributionID:  require("CLOUDFRONT_DISTRIBUTION_ID"),
+			AccessKeyID:     require("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: require("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		}
+	default:
+		return nil, fmt.Errorf("unknown CDN to purge: %s, use cloudflare, fastly or cloudfront", cdn)
+	}
+	if 0 < len(missing) {
+		return nil, fmt.Errorf("the %s purge needs the %s env variables", cdn, strings.Join(missing, ", "))
+	}
+	return purger, nil
+}
+
+// purgeCache purges the URLs from the cache of the CDN, and logs the outcome.
+func purgeCache(ctx context.Context, purger vanity.CachePurger, urls []string) error {
+	if len(urls) == 0 {
+		logger.Info(ctx, "nothing changed, nothing to purge")
+		return nil
+	}
+	if err := purger.Purge(ctx, urls); err != nil {
+		return fmt.Errorf("cache purge failed: %w", err)
+	}
+	logger.Info(ctx, "cache purged", logging.Field("urls", len(urls)))
+	return nil
+}
+
+// openBucket opens the bucket of a bucket URL, with the credentials of the environment:
+//
+//	s3://bucket/prefix           AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION
+//	gs://bucket/prefix           GCS_HMAC_ACCESS_KEY_ID and GCS_HMAC_SECRET, the HMAC key of the XML API
+//	azblob://account/container   AZURE_STORAGE_SAS_TOKEN
+//	ssh://user@host:port/dir     the keys and the known hosts of the ssh command
+//
+// The directory of an ssh:// URL is absolute, or relative to the home directory of the user with a ~/ prefix.
+func openBucket(rawURL, endpoint string) (vanity.Bucket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bucket URL: %w", err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		return vanity.S3Bucket{
+			Name:            u.Host,
+			Prefix:          prefix,
+			Region:          zerokit.Coalesce(getenv("AWS_REGION"), getenv("AWS_DEFAULT_REGION")),
+			Endpoint:        endpoint,
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	case "gs":
+		// Google Cloud Storage answers the S3 requests signed with an HMAC key on its XML API
+		return vanity.S3Bucket{
+			Name:            u.Host,
+			Prefix:          prefix,
+			Region:          "auto",
+			Endpoint:        zerokit.Coalesce(endpoint, "https://storage.googleapis.com"),
+			AccessKeyID:     getenv("GCS_HMAC_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("GCS_HMAC_SECRET"),
+		}, nil
+	case "azblob":
+		container, prefix, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			return nil, fmt.Errorf("missing container in the bucket URL: %s", rawURL)
+		}
+		return vanity.AzureBucket{
+			Account:   u.Host,
+			Container: container,
+			Prefix:    prefix,
+			SASToken:  getenv("AZURE_STORAGE_SAS_TOKEN"),
+			Endpoint:  endpoint,
+		}, nil
+	case "ssh":
+		host := u.Hostname()
+		if u.User != nil {
+			host = u.User.Username() + "@" + host
+		}
+		var port int
+		if u.Port() != "" {
+			if port, err = strconv.Atoi(u.Port()); err != nil {
+				return nil, fmt.Errorf("invalid port in the bucket URL: %s", rawURL)
+			}
+		}
+		dir := u.Path
+		if rel, ok := strings.CutPrefix(dir, "/~/"); ok {
+			dir = rel
+		}
+		return vanity.SSHBucket{Host: host, Port: port, Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown bucket URL scheme: %s, use s3://, gs://, azblob:// or ssh://", u.Scheme)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
+)
+
+func discoverCommand(ctx context.Context, args []string) error {
+	var (
+		conf     Config
+		github   vanity.GitHubDiscovery
+		gitlab   vanity.GitLabDiscovery
+		gitea    vanity.GiteaDiscovery
+		forks    bool
+		archived bool
+		write    bool
+	)
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&github.Owner, "github", "", "GitHub organization or user whose repositories are discovered")
+	fs.StringVar(&github.Token, "github-token", getenv("GITHUB_TOKEN"), "GitHub access token (env: GITHUB_TOKEN)")
+	fs.StringVar(&github.BaseURL, "github-api", getenv("GITHUB_API_URL"), "GitHub API URL of a GitHub Enterprise Server (env: GITHUB_API_URL)")
+	fs.StringVar(&gitlab.Group, "gitlab", "", "GitLab group, including its subgroups, or user whose projects are discovered")
+	fs.StringVar(&gitlab.Token, "gitlab-token", getenv("GITLAB_TOKEN"), "GitLab access token (env: GITLAB_TOKEN)")
+	fs.StringVar(&gitlab.BaseURL, "gitlab-url", getenv("GITLAB_URL"), "URL of a self-hosted GitLab instance (env: GITLAB_URL)")
+	fs.StringVar(&gitea.Owner, "gitea", "", "Gitea, Forgejo or Codeberg organization or user whose repositories are discovered")
+	fs.StringVar(&gitea.Token, "gitea-token", getenv("GITEA_TOKEN"), "Gitea access token (env: GITEA_TOKEN)")
+	fs.StringVar(&gitea.BaseURL, "gitea-url", zerokit.Coalesce(getenv("GITEA_URL"), "https://codeberg.org"), "URL of the Gitea or Forgejo instance (env: GITEA_URL)")
+	fs.BoolVar(&forks, "forks", false, "include the forked repositories")
+	fs.BoolVar(&archived, "archived", false, "include the archived repositories")
+	fs.BoolVar(&write, "write", false, "append the discovered modules to the imports file, rather than printing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain); err != nil {
+		return err
+	}
+	var discoverer vanity.Discoverer
+	switch {
+	case 1 < countSet(github.Owner, gitlab.Group, gitea.Owner):
+		return fmt.Errorf("only one of -github, -gitlab and -gitea can be used at a time")
+	case github.Owner != "":
+		github.IncludeForks, github.IncludeArchived = forks, archived
+		discoverer = github
+	case gitlab.Group != "":
+		gitlab.IncludeForks, gitlab.IncludeArchived = forks, archived
+		discoverer = gitlab
+	case gitea.Owner != "":
+		gitea.IncludeForks, gitea.IncludeArchived = forks, archived
+		discoverer = gitea
+	default:
+		return fmt.Errorf("missing -github, -gitlab or -gitea flag")
+	}
+	if write {
+		if err := conf.Require(flagImports); err != nil {
+			return err
+		}
+		if vanity.IsRemoteImports(conf.Imports) {
+			return fmt.Errorf("modules can't be added to a remote imports file: %s", conf.Imports)
+		}
+	}
+
+	discovered, err := vanity.DiscoverImports(ctx, discoverer, conf.primaryDomain())
+	if err != nil {
+		return err
+	}
+	configured, err := readConfiguredImports(ctx, conf)
+	if err != nil {
+		return err
+	}
+	entries, err := newImports(ctx, conf, configured, discovered)
+	if err != nil {
+		return err
+	}
+	if err := vanity.ValidateImports(append(configured, entries...), conf.Domains()...); err != nil {
+		return fmt.Errorf("invalid discovered modules:\n%w", err)
+	}
+
+	if !write {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(zeroToEmpty(entries))
+	}
+	for _, entry := range entries {
+		location, err := appendImport(conf, entry)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is added to %s\n", entry.ImportPrefix, location)
+	}
+	fmt.Printf("%d modules discovered, %d added, %d already configured\n",
+		len(discovered), len(entries), len(discovered)-len(entries))
+	return nil
+}
+
+func countSet(values ...string) int {
+	var n int
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// readConfiguredImports reads the entries of the imports file, if there is one yet.
+func readConfiguredImports(ctx context.Context, conf Config) ([]vanity.ImportDTO, error) {
+	if conf.Imports == "" {
+		return nil, nil
+	}
+	dtos, err := vanity.ReadImports(ctx, conf.Imports, conf.ImportsOptions)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return dtos, err
+}
+
+// newImports reconciles the discovered modules with the configured ones.
+// A module is already configured when either its import prefix or its repository is in the imports file,
+// so a hand-picked prefix of a repository is kept.
+func newImports(ctx context.Context, conf Config, configured, discovered []vanity.ImportDTO) ([]vanity.ImportDTO, error) {
+	known := map[string]struct{}{}
+	for _, dto := range configured {
+		known[dto.ImportPrefix] = struct{}{}
+		known[dto.RootRepo] = struct{}{}
+	}
+	var entries []vanity.ImportDTO
+	for _, dto := range discovered {
+		_, prefixKnown := known[dto.ImportPrefix]
+		_, repoKnown := known[dto.RootRepo]
+		if prefixKnown || repoKnown {
+			continue
+		}
+		entry, err := pinSource(ctx, conf, dto)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// zeroToEmpty makes a nil list print as an empty JSON array, so the output is always a valid imports file.
+func zeroToEmpty(dtos []vanity.ImportDTO) []vanity.ImportDTO {
+	if dtos == nil {
+		return []vanity.ImportDTO{}
+	}
+	return dtos
+}
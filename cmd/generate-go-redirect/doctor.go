@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func doctorCommand(ctx context.Context, args []string) error {
+	var (
+		conf      Config
+		opts      vanity.ReachabilityOptions
+		dns       bool
+		dnsTarget string
+		tlsCheck  bool
+		tlsOpts   vanity.TLSOptions
+		license   bool
+	)
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.IntVar(&opts.Workers, "workers", 8, "number of repositories probed concurrently")
+	fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "how long a single repository probe may take")
+	fs.BoolVar(&dns, "dns", false, "also check that the domain resolves to the -dns-target, and that the CNAME file of the output directory holds it")
+	fs.StringVar(&dnsTarget, "dns-target", getenv("DNS_TARGET"), "comma separated host names or IP addresses that the domain has to point to (default: GitHub Pages) (env: DNS_TARGET)")
+	fs.BoolVar(&tlsCheck, "tls", false, "also check the TLS certificate of the domain, like go get verifies it")
+	fs.DurationVar(&tlsOpts.ExpiryWarning, "tls-expiry", 21*24*time.Hour, "warn about a certificate that expires within this duration")
+	fs.BoolVar(&license, "license", false, "also detect the licenses of the modules, and warn about the ones without a recognized license")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	// the detection of the default branches would probe every repository a second time
+	conf.DetectBranch = false
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return err
+	}
+
+	results := vanity.CheckReachability(ctx, gen.Modules, opts)
+	var reachable, unreachable, skipped int
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tPREFIX\tVCS\tREPOSITORY\tTIME\tERROR")
+	for _, r := range results {
+		status, msg := "ok", ""
+		switch {
+		case r.Skipped:
+			status, msg = "skipped", "no probe for "+r.VCS+" repositories"
+			skipped++
+		case r.Err != nil:
+			// the error of a command can span lines, which would break the table
+			status, msg = "FAIL", strings.Join(strings.Fields(r.Err.Error()), " ")
+			unreachable++
+		default:
+			reachable++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", status, r.Prefix, r.VCS, r.RepoRoot, r.Duration.Round(time.Millisecond), msg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d repositories reachable, %d unreachable, %d skipped\n", reachable, unreachable, skipped)
+
+	var dnsFailures, tlsFailures int
+	if dns {
+		failures, err := checkDomains(ctx, gen, conf.WebDirPath, dnsTarget)
+		if err != nil {
+			return err
+		}
+		dnsFailures = failures
+	}
+	if tlsCheck {
+		tlsOpts.Timeout = opts.Timeout
+		failures, err := checkCertificates(ctx, gen, tlsOpts)
+		if err != nil {
+			return err
+		}
+		tlsFailures = failures
+	}
+	if license {
+		if err := checkLicenses(ctx, gen); err != nil {
+			return err
+		}
+	}
+	if 0 < unreachable {
+		return fmt.Errorf("%d repositories are unreachable", unreachable)
+	}
+	if 0 < dnsFailures {
+		return fmt.Errorf("%d domains don't point to their site", dnsFailures)
+	}
+	if 0 < tlsFailures {
+		return fmt.Errorf("%d domains fail the TLS verification", tlsFailures)
+	}
+	return nil
+}
+
+// checkDomains checks the DNS records of the domains of the sites, and the CNAME files of the output directory,
+// and prints a report of them. It returns the number of the domains that failed the check.
+func checkDomains(ctx context.Context, gen vanity.Generator, outDir, targets string) (int, error) {
+	var opts vanity.DNSOptions
+	for _, target := range strings.Split(targets, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			opts.Targets = append(opts.Targets, target)
+		}
+	}
+	sites := siteDomains(gen)
+	var failures int
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSTATUS\tDOMAIN\tRECORDS\tERROR")
+	for _, domain := range sites {
+		var errs []string
+		records, err := vanity.CheckDNS(ctx, domain, opts)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if outDir != "" {
+			dir := outDir
+			if 1 < len(sites) || domain != gen.Domain {
+				dir = filepath.Join(dir, domain)
+			}
+			if err := vanity.CheckCNAMEFile(os.DirFS(dir), domain); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		status := "ok"
+		if 0 < len(errs) {
+			status = "FAIL"
+			failures++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status, domain, records, strings.Join(errs, "; "))
+	}
+	return failures, w.Flush()
+}
+
+// checkCertificates checks the TLS certificates of the domains of the sites, and prints a report of them.
+// It returns the number of the domains that failed the check, while a warning, like a near expiry, doesn't fail it.
+func checkCertificates(ctx context.Context, gen vanity.Generator, opts vanity.TLSOptions) (int, error) {
+	var failures, warnings int
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSTATUS\tDOMAIN\tISSUER\tEXPIRES\tERROR")
+	for _, domain := range siteDomains(gen) {
+		r := vanity.CheckTLS(ctx, domain, opts)
+		status, msg := "ok", strings.Join(r.Warnings, "; ")
+		switch {
+		case r.Err != nil:
+			status, msg = "FAIL", strings.Join(strings.Fields(r.Err.Error()), " ")
+			failures++
+		case 0 < len(r.Warnings):
+			status = "WARN"
+			warnings++
+		}
+		var expires string
+		if !r.NotAfter.IsZero() {
+			expires = r.NotAfter.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status, r.Host, r.Issuer, expires, msg)
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	if 0 < warnings {
+		fmt.Printf("%d certificates expire soon\n", warnings)
+	}
+	return failures, nil
+}
+
+// checkLicenses detects the licenses of the modules and their nested modules, and prints a report of them.
+// A module without a recognized license is only warned about, since the go command doesn't need one,
+// but pkg.go.dev doesn't show the documentation of a module without a redistributable license.
+func checkLicenses(ctx context.Context, gen vanity.Generator) error {
+	var warnings int
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSTATUS\tMODULE\tLICENSE")
+	for _, meta := range vanity.DetectLicenses(ctx, gen.Modules) {
+		if meta.Private || meta.Tombstone.Kind != "" {
+			continue
+		}
+		importPaths := []string{meta.Import.Prefix}
+		for _, nested := range meta.Nested {
+			importPaths = append(importPaths, nested.ImportPath)
+		}
+		// only the git and Mercurial repositories are cloned for a look into their files
+		vcs := meta.Import.VCS.SourceRepo().Name
+		for _, importPath := range importPaths {
+			status, license := "ok", meta.Licenses[importPath]
+			switch {
+			case vcs != vanity.VCSGit && vcs != vanity.VCSMercurial:
+				status, license = "skipped", "no license detection for "+vcs+" repositories"
+			case license == "":
+				status, license = "WARN", "no detectable license"
+				warnings++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status, importPath, license)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if 0 < warnings {
+		fmt.Printf("%d modules have no detectable license\n", warnings)
+	}
+	return nil
+}
+
+// siteDomains are the domains of the sites that the generator renders.
+func siteDomains(gen vanity.Generator) []string {
+	if sites := gen.Sites(); 0 < len(sites) {
+		return sites
+	}
+	return []string{gen.Domain}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is the namespace of the env variables of the commands, like GOVANITY_DOMAIN,
+// which keeps them apart from the variables of other tools in a shared environment, like a CI job.
+const envPrefix = "GOVANITY_"
+
+// envFileVar points to the .env file of the settings, which is the .env file of the working directory by default.
+const envFileVar = envPrefix + "ENV_FILE"
+
+// dotenv are the variables of the .env file.
+var dotenv = map[string]string{}
+
+// loadEnvFile reads the variables of the .env file, which the env variables of the process override.
+// A missing .env file in the working directory is fine, unlike a missing file that GOVANITY_ENV_FILE points to.
+func loadEnvFile() error {
+	name, explicit := os.LookupEnv(envFileVar)
+	if !explicit {
+		name = ".env"
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the env file: %w", err)
+	}
+	vars, err := parseEnvFile(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	dotenv = vars
+	return nil
+}
+
+// parseEnvFile parses the KEY=value lines of a .env file, with an optional export in front of them.
+// The blank lines and the # comments are skipped.
+// A value can be double-quoted, with the escapes of a Go string, like \n, or single-quoted, which is taken as it is.
+// An unquoted value ends at a # comment, and the variables are not expanded in any of them.
+func parseEnvFile(data string) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated double-quoted value of %s", n, key)
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid double-quoted value of %s: %w", n, key, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single-quoted value of %s", n, key)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); 0 <= i {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+// closingQuote is the index of the double quote that closes the double-quoted value, which is -1 without one.
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// lookupEnv looks up the setting of an env variable, in the order of precedence:
+//
+//  1. the namespaced variable of the process, like GOVANITY_DOMAIN
+//  2. the variable of the process, like DOMAIN
+//  3. the namespaced variable of the .env file
+//  4. the variable of the .env file
+//
+// The flags of the commands override them all.
+func lookupEnv(key string) (string, bool) {
+	if value, ok := os.LookupEnv(envPrefix + key); ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	if value, ok := dotenv[envPrefix+key]; ok {
+		return value, true
+	}
+	value, ok := dotenv[key]
+	return value, ok
+}
+
+// getenv is the setting of an env variable by lookupEnv, which is empty when it isn't set.
+func getenv(key string) string {
+	value, _ := lookupEnv(key)
+	return value
+}
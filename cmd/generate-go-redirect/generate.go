@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/adapter/localfs"
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/pkg/vanity"
+)
+
+func generateCommand(ctx context.Context, args []string) error {
+	var (
+		conf   Config
+		gen    vanity.Generator
+		dryRun bool
+		diff   bool
+
+		indexTemplate string
+		assetsDir     string
+		templatesDir  string
+		host          string
+		worker        bool
+		archive       string
+		publish       bool
+		pages         vanity.GitHubPages
+		author        string
+		watch         bool
+		watchInterval time.Duration
+		report        string
+		reportSummary string
+		verifyGolden  bool
+		updateGolden  bool
+		goldenDir     string
+		locales       LocaleConfig
+		theme         string
+		warmUp        bool
+		warmUpOpts    vanity.WarmUpOptions
+		cdn           string
+	)
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.BoolVar(&dryRun, "dry-run", false, "print the plan of file changes without writing the output directory")
+	fs.BoolVar(&diff, "diff", false, "include a unified diff of the updated files in the dry-run plan")
+	fs.BoolVar(&gen.Options.Prune, "prune", false, "delete the generated pages of modules that are no longer configured")
+	fs.IntVar(&gen.Options.Workers, "workers", runtime.NumCPU(), "number of modules rendered concurrently")
+	fs.BoolVar(&gen.Options.HeadersFile, "headers", false, "add a _headers file with the Cache-Control of the pages for hosts like Netlify or Cloudflare Pages")
+	fs.DurationVar(&gen.Options.MaxAge, "max-age", 5*time.Minute, "how long the clients may cache a page")
+	bindSecurityHeaders(fs, &gen.Options.SecurityHeaders)
+	locales.Bind(fs)
+	fs.StringVar(&theme, "theme", getenv("THEME"), "built-in theme of the pages (default, gopher, paper or midnight), or a JSON theme file (env: THEME)")
+	fs.BoolVar(&gen.Options.Index, "index", false, "add an index.html to the domain root, which lists the modules")
+	fs.BoolVar(&gen.Options.Search, "search", false, "add a search-index.json of the modules, and a search box to the index page")
+	fs.StringVar(&indexTemplate, "index-template", "", "html/template file of the index page, which replaces the default table of the modules")
+	fs.BoolVar(&gen.Options.Sitemap, "sitemap", false, "add a sitemap.xml of the pages for the search engines")
+	fs.BoolVar(&gen.Options.Robots, "robots", false, "add a robots.txt, which keeps the crawlers away from the no-crawl modules, and points to the sitemap")
+	fs.BoolVar(&gen.Options.Badges, "badges", false, "add a badge.svg with the latest release next to the page of every module")
+	fs.BoolVar(&gen.Options.Shields, "shields", false, "add shields.io endpoint files with the latest release and its Go version next to the page of every module")
+	fs.BoolVar(&gen.Options.Landing, "landing", false, "make the pages of the modules landing pages with their rendered README, rather than redirects")
+	fs.BoolVar(&gen.Options.PrivateGuides, "private-guides", false, "add guide pages of the private modules with the GOPRIVATE settings and the credentials they need, and a goprivate.json of the settings")
+	fs.BoolVar(&gen.Options.APIDocs, "api-docs", false, "add the API documentation of the modules' packages under the docs directory of their pages")
+	fs.BoolVar(&gen.Options.Licenses, "licenses", false, "detect the licenses of the modules, and show them on their landing pages and on the index page")
+	fs.BoolVar(&gen.Options.Changelog, "changelog", false, "show the latest entries of the modules' CHANGELOG.md on their landing pages")
+	fs.IntVar(&gen.Options.ChangelogEntries, "changelog-entries", 3, "number of the latest changelog entries on a landing page")
+	fs.BoolVar(&gen.Options.Versions, "versions", false, "add a listing of the tagged versions under the versions directory of the modules' pages")
+	fs.StringVar(&assetsDir, "assets", "", "directory of static files, like a favicon and stylesheets, copied into the output directory with fingerprinted copies")
+	fs.StringVar(&templatesDir, "templates", "", "directory of html/template files, which override the head, body and footer of the module pages, or the whole go-import.html page")
+	fs.StringVar(&host, "host", "", "static host whose configuration files are added to the site: netlify, cloudflare or nginx")
+	fs.BoolVar(&worker, "worker", false, "add the _worker.js of Cloudflare Pages, which answers the go-get requests dynamically (with -host cloudflare)")
+	fs.BoolVar(&gen.Options.Atomic, "atomic", false, "write into a staging directory, and swap it with the output directory when every file is written")
+	fs.StringVar(&archive, "archive", "", "also package the output directory into a deterministic .tar.gz or .zip file, like a build artifact")
+	fs.BoolVar(&publish, "publish", false, "commit the output directory to the -publish-branch of the current git repository, and push it")
+	fs.StringVar(&pages.Branch, "publish-branch", "gh-pages", "branch that the site is published to")
+	fs.StringVar(&pages.Remote, "publish-remote", "origin", "remote that the published branch is pushed to")
+	fs.BoolVar(&pages.NoPush, "publish-no-push", false, "only commit the published branch locally, without pushing it")
+	fs.StringVar(&pages.Message, "publish-message", "Publish the vanity import pages", "commit message of the published site")
+	fs.BoolVar(&warmUp, "warm-up", false, "after writing and publishing the site, ask the module proxy and pkg.go.dev for the latest version of every public module, so they are indexed right away")
+	fs.StringVar(&warmUpOpts.ProxyURL, "warm-up-proxy", "https://proxy.golang.org", "module proxy of the warm-up")
+	bindPurge(fs, &cdn)
+	fs.StringVar(&author, "publish-author", "", "author of the published commit, like \"Name <email>\" (default: the user of the git config)")
+	fs.BoolVar(&watch, "watch", false, "keep running, and generate the site again when the imports file, the templates or the assets change")
+	fs.DurationVar(&watchInterval, "watch-interval", 500*time.Millisecond, "interval of checking the watched files for changes, which have to settle for an interval before a generation")
+	fs.StringVar(&report, "report", "", "write a JSON report of the generation to the file, with the status and the files of every module, and the warnings")
+	fs.StringVar(&reportSummary, "report-summary", "", "append a Markdown summary of the generation to the file, like $GITHUB_STEP_SUMMARY")
+	fs.BoolVar(&verifyGolden, "verify-golden", false, "compare the rendered site against its snapshot in the -golden-dir, print the differences, and fail when it differs")
+	fs.BoolVar(&updateGolden, "update-golden", false, "render the site into its snapshot in the -golden-dir, rather than into the output directory")
+	fs.StringVar(&goldenDir, "golden-dir", "testdata/golden", "directory of the golden snapshot of the site, committed to make the changes of the pages visible in code review")
+	fs.BoolVar(&gen.Options.ContinueOnError, "continue-on-error", false, "generate the rest of the site when a module fails, keep the previous pages of the failed modules, and exit with code 3")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	golden := verifyGolden || updateGolden
+	required := []string{flagDomain, flagImports}
+	if !golden {
+		// the golden snapshot is written instead of the output directory
+		required = append(required, flagOut)
+	}
+	if err := conf.Require(required...); err != nil {
+		return err
+	}
+	if dryRun && (report != "" || reportSummary != "") {
+		return fmt.Errorf("the -report and -report-summary flags can't be used with -dry-run, which writes no files")
+	}
+	if verifyGolden && updateGolden {
+		return fmt.Errorf("the -verify-golden and -update-golden flags can't be used together")
+	}
+	if golden && (dryRun || archive != "" || publish || warmUp || cdn != "" || report != "" || reportSummary != "") {
+		return fmt.Errorf("the -verify-golden and -update-golden flags only render the golden snapshot, so they can't be used with -dry-run, -archive, -publish, -warm-up, -purge or -report")
+	}
+	if dryRun && (warmUp || cdn != "") {
+		return fmt.Errorf("the -warm-up and -purge flags can't be used with -dry-run, which leaves the site unpublished")
+	}
+	if watch && vanity.IsRemoteImports(conf.Imports) {
+		return fmt.Errorf("the -watch flag needs a local imports file")
+	}
+	if assetsDir != "" {
+		gen.Options.Assets = os.DirFS(assetsDir)
+	}
+	if templatesDir != "" {
+		gen.Options.Templates = os.DirFS(templatesDir)
+	}
+	catalogs, err := locales.Catalogs()
+	if err != nil {
+		return err
+	}
+	gen.Options.Language, gen.Options.Catalogs = locales.Language, catalogs
+	// the templates read the settings like the flags do, with the GOVANITY_ prefix and the .env file
+	gen.Options.Getenv = getenv
+	if theme != "" {
+		if gen.Options.Theme, err = vanity.LoadTheme(theme); err != nil {
+			return err
+		}
+	}
+	if author != "" {
+		addr, err := mail.ParseAddress(author)
+		if err != nil {
+			return fmt.Errorf("invalid -publish-author: %w", err)
+		}
+		pages.AuthorName, pages.AuthorEmail = addr.Name, addr.Address
+	}
+	var archiveFormat string
+	if archive != "" {
+		format, err := vanity.ArchiveFormat(archive)
+		if err != nil {
+			return err
+		}
+		archiveFormat = format
+	}
+	if worker && host != "cloudflare" {
+		return fmt.Errorf("the -worker flag is only for -host cloudflare")
+	}
+	if host != "" {
+		if gen.Options.HeadersFile && host != "nginx" {
+			return fmt.Errorf("the -headers flag can't be used with -host, whose _headers file sets the Cache-Control of the pages too")
+		}
+		plugin, err := hostPlugin(host, worker)
+		if err != nil {
+			return err
+		}
+		gen.Options.Plugins = append(gen.Options.Plugins, plugin)
+	}
+	out := generateOutputs{
+		dryRun: dryRun, diff: diff, archive: archive, archiveFormat: archiveFormat, publish: publish, pages: pages,
+		report: report, reportSummary: reportSummary,
+	}
+	if warmUp {
+		out.warmUp = &warmUpOpts
+	}
+	if cdn != "" {
+		if out.purger, err = openPurger(cdn); err != nil {
+			return err
+		}
+	}
+	if golden {
+		out.golden, out.updateGolden = goldenDir, updateGolden
+	}
+	if !watch {
+		return generateSite(ctx, conf, gen, indexTemplate, out)
+	}
+	return watchGenerate(ctx, []string{conf.Imports, indexTemplate, assetsDir, templatesDir}, watchInterval, func(ctx context.Context) error {
+		return generateSite(ctx, conf, gen, indexTemplate, out)
+	})
+}
+
+// generateOutputs are the outputs of a generation besides the output directory.
+type generateOutputs struct {
+	dryRun, diff  bool
+	archive       string
+	archiveFormat string
+	publish       bool
+	pages         vanity.GitHubPages
+	// warmUp is the configuration of the warm-up of the module proxy and pkg.go.dev, when it is enabled.
+	warmUp *vanity.WarmUpOptions
+	// purger purges the changed files from the cache of the CDN, when it is set.
+	purger        vanity.CachePurger
+	report        string
+	reportSummary string
+	// golden is the directory of the golden snapshot, which is verified, or updated, instead of writing the output directory.
+	golden       string
+	updateGolden bool
+}
+
+// generateSite loads the modules, and writes the site into the output directory, or prints the plan of it.
+// The index template is read on every generation, so the watch mode picks up its changes.
+// The report of the generation is written even when it fails, since that's when it's needed the most.
+func generateSite(ctx context.Context, conf Config, gen vanity.Generator, indexTemplate string, out generateOutputs) (rErr error) {
+	if out.report != "" || out.reportSummary != "" {
+		report := vanity.NewReport()
+		gen.Options.Report = report
+		defer recordWarnings(report)()
+		defer func() {
+			report.Finish(gen.Modules, rErr)
+			rErr = errorkit.Merge(rErr, writeReport(report, out.report, out.reportSummary))
+		}()
+	}
+	if indexTemplate != "" {
+		data, err := os.ReadFile(indexTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to read the index template: %w", err)
+		}
+		gen.Options.IndexTemplate = string(data)
+	}
+	endGroup := actions.group("Load the modules")
+	err := loadModules(ctx, conf, &gen)
+	endGroup()
+	if err != nil {
+		return err
+	}
+	if out.golden != "" {
+		return generateGolden(ctx, gen, out.golden, out.updateGolden)
+	}
+	if out.dryRun {
+		changes, err := planDomains(ctx, gen, conf.WebDirPath)
+		var failures vanity.ModuleErrors
+		if err != nil && !errors.As(err, &failures) {
+			return err
+		}
+		if err := printPlan(os.Stdout, changes, out.diff); err != nil {
+			return err
+		}
+		if err := actions.setOutputs(generateOutputsOf(gen.Modules, changes, out.report)); err != nil {
+			return err
+		}
+		if 0 < len(failures) {
+			printModuleErrors(os.Stderr, failures, len(gen.Modules))
+			return failures
+		}
+		return nil
+	}
+	var (
+		written []vanity.Change
+		purged  []string
+	)
+	gen.Options.OnWrite = func(domain string, changes []vanity.Change) {
+		written = append(written, changes...)
+		if out.purger != nil {
+			purged = append(purged, vanity.PurgeURLs(domain, changes)...)
+		}
+	}
+	endGroup = actions.group("Write the site")
+	err = gen.WriteDir(ctx, conf.WebDirPath)
+	endGroup()
+	var failures vanity.ModuleErrors
+	if err != nil && !errors.As(err, &failures) {
+		return fmt.Errorf("generate project redirects have failed: %w", err)
+	}
+	if 0 < len(failures) {
+		// the failures are printed at the very end, so the logs of the other outputs don't bury them
+		defer printModuleErrors(os.Stderr, failures, len(gen.Modules))
+	}
+	if out.archive != "" {
+		if err := writeArchive(out.archive, out.archiveFormat, conf.WebDirPath); err != nil {
+			return fmt.Errorf("archive failed: %w", err)
+		}
+	}
+	if out.publish {
+		endGroup := actions.group("Publish the site")
+		commit, err := out.pages.Publish(ctx, conf.WebDirPath)
+		endGroup()
+		if err != nil {
+			return fmt.Errorf("publish failed: %w", err)
+		}
+		if commit == "" {
+			logger.Info(ctx, "the branch is up to date, nothing to publish", logging.Field("branch", out.pages.Branch))
+		} else {
+			logger.Info(ctx, "site published", logging.Field("branch", out.pages.Branch), logging.Field("commit", commit))
+		}
+	}
+	if out.warmUp != nil {
+		endGroup := actions.group("Warm up the modules")
+		warmUpModules(ctx, gen.Modules, *out.warmUp)
+		endGroup()
+	}
+	if out.purger != nil {
+		endGroup := actions.group("Purge the cache")
+		err := purgeCache(ctx, out.purger, purged)
+		endGroup()
+		if err != nil {
+			return err
+		}
+	}
+	if err := actions.setOutputs(generateOutputsOf(gen.Modules, written, out.report)); err != nil {
+		return err
+	}
+	if 0 < len(failures) {
+		return failures
+	}
+	return nil
+}
+
+// loadModules loads the modules of the imports file, with the data of their repositories that the options of the site need.
+func loadModules(ctx context.Context, conf Config, gen *vanity.Generator) error {
+	if err := conf.Load(ctx, gen); err != nil {
+		return fmt.Errorf("get import meta data failed: %w", err)
+	}
+	if gen.Options.Badges || gen.Options.Shields {
+		gen.Modules = vanity.DetectReleases(ctx, gen.Modules)
+	}
+	if gen.Options.Landing {
+		gen.Modules = vanity.FetchReadmes(ctx, gen.Modules)
+	} else {
+		fetchTemplateReadmes(ctx, gen.Modules)
+	}
+	if gen.Options.APIDocs {
+		gen.Modules = vanity.FetchAPIDocs(ctx, gen.Modules)
+	}
+	if gen.Options.Licenses {
+		gen.Modules = vanity.DetectLicenses(ctx, gen.Modules)
+	}
+	if gen.Options.Changelog {
+		gen.Modules = vanity.FetchChangelogs(ctx, gen.Modules, gen.Options.ChangelogEntries)
+	}
+	if gen.Options.Versions {
+		gen.Modules = vanity.DetectVersions(ctx, gen.Modules)
+	}
+	return nil
+}
+
+// generateOutputsOf are the step outputs of a generation in a GitHub Actions workflow:
+// the changes of the site, the import prefixes of the modules as a JSON array, and the path of the report, when it is written.
+func generateOutputsOf(metas []vanity.Meta, changes []vanity.Change, report string) map[string]string {
+	outputs := changeOutputs(changes)
+	prefixes := make([]string, 0, len(metas))
+	for _, meta := range metas {
+		prefixes = append(prefixes, meta.Import.Prefix)
+	}
+	outputs["modules"] = jsonOutput(prefixes)
+	if report != "" {
+		outputs["report"] = report
+	}
+	return outputs
+}
+
+// warmUpModules asks the module proxy and pkg.go.dev for the latest versions of the modules, and logs the outcome.
+// A failed warm-up is only warned about, since the site is written already, and the services catch up on their own.
+func warmUpModules(ctx context.Context, metas []vanity.Meta, opts vanity.WarmUpOptions) {
+	var warmed, documented int
+	for _, r := range vanity.WarmUpModules(ctx, metas, opts) {
+		if r.Err != nil {
+			logger.Warn(ctx, "module warm-up failed", logging.Field("module", r.ImportPath), logging.ErrField(r.Err))
+			continue
+		}
+		warmed++
+		if r.Documented {
+			documented++
+		}
+		logger.Info(ctx, "module warmed up",
+			logging.Field("module", r.ImportPath),
+			logging.Field("version", r.Version),
+			logging.Field("documented", r.Documented))
+	}
+	logger.Info(ctx, "warm-up finished", logging.Field("warmed", warmed), logging.Field("documented", documented))
+}
+
+// generateGolden updates the golden snapshot of the site, or verifies the site against it,
+// and prints the plan of the differences with their diffs.
+func generateGolden(ctx context.Context, gen vanity.Generator, dir string, update bool) error {
+	if update {
+		_, err := gen.UpdateGolden(ctx, dir)
+		return err
+	}
+	changes, err := gen.DiffGolden(ctx, dir)
+	if err != nil {
+		return err
+	}
+	var differ bool
+	for _, change := range changes {
+		differ = differ || change.Kind != vanity.ChangeUnchanged
+	}
+	if !differ {
+		logger.Info(ctx, "the site matches its golden snapshot", logging.Field("dir", dir))
+		return nil
+	}
+	if err := printPlan(os.Stdout, changes, true); err != nil {
+		return err
+	}
+	return fmt.Errorf("the site differs from its golden snapshot in %s, update it with -update-golden", dir)
+}
+
+// printModuleErrors prints the failures of a generation with -continue-on-error, grouped by module.
+func printModuleErrors(w io.Writer, failures vanity.ModuleErrors, modules int) {
+	fmt.Fprintf(w, "\n%d of %d modules failed:\n", len(failures), modules)
+	for _, failure := range failures {
+		fmt.Fprintf(w, "\n%s\n", failure.Prefix)
+		for _, line := range strings.Split(failure.Err.Error(), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// recordWarnings records the warnings of the logger into the report, while they are logged as usual.
+// The returned function stops the recording.
+func recordWarnings(report *vanity.Report) (restore func()) {
+	var original *logging.Logger
+	logger.Configure(func(l *logging.Logger) { original = l.Clone() })
+	logger.Hijack(func(level logging.Level, msg string, fields logging.Fields) {
+		ctx := context.Background()
+		switch level {
+		case logging.LevelDebug:
+			original.Debug(ctx, msg, fields)
+		case logging.LevelInfo:
+			original.Info(ctx, msg, fields)
+		case logging.LevelWarn:
+			report.AddWarning(msg, fields)
+			original.Warn(ctx, msg, fields)
+		default:
+			original.Error(ctx, msg, fields)
+		}
+	})
+	return func() { logger.Hijack(original.Hijack) }
+}
+
+// writeReport writes the JSON report, and appends the Markdown summary, to the files that are set.
+func writeReport(report *vanity.Report, jsonPath, summaryPath string) error {
+	if jsonPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(jsonPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write the report: %w", err)
+		}
+	}
+	if summaryPath != "" {
+		f, err := os.OpenFile(summaryPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open the report summary: %w", err)
+		}
+		return errorkit.Merge(report.WriteSummary(f), f.Close())
+	}
+	return nil
+}
+
+// writeArchive packages the output directory into the archive file.
+func writeArchive(name, format, outDir string) (rErr error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, f.Close()) }()
+	return vanity.WriteArchive(f, os.DirFS(outDir), format)
+}
+
+// hostPlugin is the output plugin of a static host, by the name of the host.
+func hostPlugin(name string, worker bool) (vanity.OutputPlugin, error) {
+	switch name {
+	case "netlify":
+		return vanity.Netlify{}, nil
+	case "cloudflare":
+		return vanity.Cloudflare{Worker: worker}, nil
+	case "nginx":
+		return vanity.Nginx{}, nil
+	default:
+		return nil, fmt.Errorf("unknown static host: %s", name)
+	}
+}
+
+// fetchTemplateReadmes fetches the READMEs of the modules with a page template of their own,
+// since their pages show them, unlike the redirects of the rest.
+func fetchTemplateReadmes(ctx context.Context, metas []vanity.Meta) {
+	for i, meta := range metas {
+		if meta.Template != "" {
+			metas[i] = vanity.FetchReadmes(ctx, []vanity.Meta{meta})[0]
+		}
+	}
+}
+
+// planDomains plans the changes of the output directory.
+// The changes of a multi-domain or wildcard site are planned in the subdirectory of each domain,
+// and their paths are prefixed with the subdirectory.
+func planDomains(ctx context.Context, gen vanity.Generator, outDir string) ([]vanity.Change, error) {
+	sites := gen.Sites()
+	if len(sites) == 0 {
+		return gen.Plan(ctx, localfs.FileSystem{RootPath: outDir})
+	}
+	var (
+		all      []vanity.Change
+		failures vanity.ModuleErrors
+	)
+	for _, domain := range sites {
+		sub := gen.ForDomain(domain)
+		changes, err := sub.Plan(ctx, localfs.FileSystem{RootPath: filepath.Join(outDir, domain)})
+		var siteFailures vanity.ModuleErrors
+		if errors.As(err, &siteFailures) {
+			failures = append(failures, siteFailures...)
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", domain, err)
+		}
+		for _, change := range changes {
+			change.File.Path = path.Join(domain, change.File.Path)
+			all = append(all, change)
+		}
+	}
+	if 0 < len(failures) {
+		return all, failures
+	}
+	return all, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
+	"golang.org/x/term"
+)
+
+func initCommand(ctx context.Context, args []string) error {
+	var (
+		conf   Config
+		repo   string
+		prefix string
+		force  bool
+	)
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&prefix, "prefix", "", "import prefix of the first module")
+	fs.StringVar(&repo, "repo", "", "repository root URL of the first module")
+	fs.BoolVar(&force, "force", false, "overwrite an existing imports file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if isInteractive() {
+		p := prompter{in: bufio.NewScanner(os.Stdin), out: os.Stdout}
+		conf.Domain = p.Ask("vanity domain", conf.Domain)
+		conf.Imports = p.Ask("imports file", zerokit.Coalesce(conf.Imports, "imports.json"))
+		conf.WebDirPath = p.Ask("output directory", zerokit.Coalesce(conf.WebDirPath, "docs"))
+		if repo == "" {
+			repo = p.Ask("repository of the first module (leave empty to skip)", "")
+		}
+		if repo != "" && prefix == "" {
+			prefix = p.Ask("import prefix of the first module", defaultPrefix(conf.primaryDomain(), repo))
+		}
+	}
+	conf.Imports = zerokit.Coalesce(conf.Imports, "imports.json")
+	conf.WebDirPath = zerokit.Coalesce(conf.WebDirPath, "docs")
+	if err := conf.Require(flagDomain); err != nil {
+		return err
+	}
+	if repo != "" && prefix == "" {
+		prefix = defaultPrefix(conf.primaryDomain(), repo)
+	}
+
+	return scaffold(ctx, conf, prefix, repo, force)
+}
+
+// scaffold creates a starter imports file, and the output directory with its CNAME file.
+// The imports file is read back, so a starter that the generation couldn't read is not left behind.
+func scaffold(ctx context.Context, conf Config, prefix, repo string, force bool) error {
+	format, err := vanity.ImportsFormat(conf.Imports, conf.ImportsOptions.Format)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(conf.Imports); err == nil && !force {
+		return fmt.Errorf("%s already exists, use -force to overwrite it", conf.Imports)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	var buf strings.Builder
+	if err := starterTemplates.ExecuteTemplate(&buf, format, starterData{
+		Domain: conf.primaryDomain(),
+		Module: vanity.ImportDTO{VCS: "git", ImportPrefix: prefix, RootRepo: repo},
+	}); err != nil {
+		return err
+	}
+	if dir := filepath.Dir(conf.Imports); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(conf.Imports, []byte(buf.String()), 0644); err != nil {
+		return err
+	}
+	if _, err := vanity.ReadImports(ctx, conf.Imports, conf.ImportsOptions); err != nil {
+		_ = os.Remove(conf.Imports)
+		return fmt.Errorf("the starter imports file can't be read, check the -prefix and -repo: %w", err)
+	}
+
+	created := []string{conf.Imports}
+	for _, cname := range starterCNAMEs(conf) {
+		name := filepath.Join(conf.WebDirPath, cname.Dir, "CNAME")
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, []byte(cname.Host), 0644); err != nil {
+			return err
+		}
+		created = append(created, name)
+	}
+	if err := os.MkdirAll(conf.WebDirPath, 0755); err != nil {
+		return err
+	}
+
+	fmt.Printf("created %s\n\n", strings.Join(created, ", "))
+	fmt.Printf("next steps:\n")
+	fmt.Printf("  export DOMAIN=%q IMPORTS_FILE_PATH=%q WEB_DIR_PATH=%q\n", conf.Domain, conf.Imports, conf.WebDirPath)
+	fmt.Printf("  generate-go-redirect generate\n")
+	return nil
+}
+
+type starterCNAME struct {
+	// Dir is the directory of the site in the output directory.
+	Dir string
+	// Host is the host of the site's domain, without its base path.
+	Host string
+}
+
+// starterCNAMEs are the CNAME files of the sites, like the generation writes them:
+// the one of a single domain's site is in the output directory, and the ones of a multi-domain site are in the subdirectories of the domains.
+// A wildcard domain has no CNAME file, since the sites of its subdomains are known only from the modules.
+func starterCNAMEs(conf Config) []starterCNAME {
+	gen := vanity.Generator{Domain: conf.primaryDomain()}
+	if domains := conf.Domains(); 1 < len(domains) {
+		gen.Domains = domains
+	}
+	host := func(domain string) string {
+		host, _, _ := strings.Cut(domain, "/")
+		return host
+	}
+	sites := gen.Sites()
+	if len(sites) == 0 {
+		if strings.HasPrefix(gen.Domain, "*.") {
+			return nil
+		}
+		return []starterCNAME{{Dir: ".", Host: host(gen.Domain)}}
+	}
+	var cnames []starterCNAME
+	for _, site := range sites {
+		cnames = append(cnames, starterCNAME{Dir: site, Host: host(site)})
+	}
+	return cnames
+}
+
+type starterData struct {
+	Domain string
+	Module vanity.ImportDTO
+}
+
+// starterTemplates are the starter imports files by their format.
+// The values are written with quote, whose JSON strings are valid YAML and TOML strings too.
+var starterTemplates = template.Must(template.New("").Funcs(template.FuncMap{"quote": quote}).Parse(`
+{{- define "json" -}}
+[
+{{- if .Module.RootRepo }}
+  {
+    "vcs": {{ quote .Module.VCS }},
+    "import-prefix": {{ quote .Module.ImportPrefix }},
+    "root-repo": {{ quote .Module.RootRepo }}
+  }
+{{- else }}
+  // {
+  //   "vcs": "git",
+  //   "import-prefix": "{{ .Domain }}/mymodule",
+  //   "root-repo": "https://github.com/me/mymodule"
+  // }
+{{- end }}
+]
+{{ end -}}
+
+{{- define "yaml" -}}
+# Each entry maps an import prefix under {{ .Domain }} to its repository.
+{{- if .Module.RootRepo }}
+- vcs: {{ quote .Module.VCS }}
+  import-prefix: {{ quote .Module.ImportPrefix }}
+  root-repo: {{ quote .Module.RootRepo }}
+{{- else }}
+# - vcs: git
+#   import-prefix: {{ .Domain }}/mymodule
+#   root-repo: https://github.com/me/mymodule
+[]
+{{- end }}
+{{ end -}}
+
+{{- define "toml" -}}
+# Each [[modules]] table maps an import prefix under {{ .Domain }} to its repository.
+{{- if .Module.RootRepo }}
+[[modules]]
+vcs = {{ quote .Module.VCS }}
+import-prefix = {{ quote .Module.ImportPrefix }}
+root-repo = {{ quote .Module.RootRepo }}
+{{- else }}
+# [[modules]]
+# vcs = "git"
+# import-prefix = "{{ .Domain }}/mymodule"
+# root-repo = "https://github.com/me/mymodule"
+{{- end }}
+{{ end -}}
+`))
+
+// quote writes a string as a double-quoted JSON string.
+func quote(s string) (string, error) {
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+// defaultPrefix suggests an import prefix from the repository's name.
+func defaultPrefix(domain, repo string) string {
+	name := strings.TrimSuffix(filepath.Base(strings.TrimSuffix(repo, "/")), ".git")
+	return vanity.ImportPrefix(domain, name)
+}
+
+// isInteractive tells whether the standard input is a terminal.
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// Ask prompts for a value, and returns the default value when the answer is empty.
+func (p prompter) Ask(label, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+	if !p.in.Scan() {
+		return def
+	}
+	if answer := strings.TrimSpace(p.in.Text()); answer != "" {
+		return answer
+	}
+	return def
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func lintCommand(ctx context.Context, args []string) error {
+	var (
+		conf   Config
+		failOn string
+	)
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&failOn, "fail-on", vanity.SeverityError, "lowest severity of the findings that fail the lint (error, warning)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	if failOn != vanity.SeverityError && failOn != vanity.SeverityWarning {
+		return fmt.Errorf("unknown severity: %s, use error or warning", failOn)
+	}
+	dtos, err := vanity.ReadImports(ctx, conf.Imports, conf.ImportsOptions)
+	if err != nil {
+		return err
+	}
+	var counts = map[string]int{}
+	for _, finding := range vanity.LintImports(dtos, conf.Domains()...) {
+		fmt.Printf("%s: %s\n", finding.Severity, finding.Error())
+		counts[finding.Severity]++
+		actions.annotate(finding.Severity, annotatedFile(conf.Imports), "lint: "+finding.Field, finding.Error())
+	}
+	fmt.Printf("%d entries, %d errors, %d warnings\n", len(dtos), counts[vanity.SeverityError], counts[vanity.SeverityWarning])
+	if err := actions.setOutputs(map[string]string{
+		"errors":   fmt.Sprint(counts[vanity.SeverityError]),
+		"warnings": fmt.Sprint(counts[vanity.SeverityWarning]),
+	}); err != nil {
+		return err
+	}
+	if 0 < counts[vanity.SeverityError] || (failOn == vanity.SeverityWarning && 0 < counts[vanity.SeverityWarning]) {
+		return fmt.Errorf("the imports file has lint findings")
+	}
+	return nil
+}
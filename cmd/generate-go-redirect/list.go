@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func listCommand(ctx context.Context, args []string) error {
+	var (
+		conf   Config
+		output string
+	)
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&output, "o", "table", "output format (table, json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return err
+	}
+	switch output {
+	case "table":
+		return printModuleTable(os.Stdout, gen.Modules)
+	case "json":
+		return printModuleJSON(os.Stdout, gen.Modules)
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+// ModuleDTO is the machine-readable representation of a configured module.
+type ModuleDTO struct {
+	ImportPrefix string   `json:"import-prefix"`
+	VCS          string   `json:"vcs"`
+	RootRepo     string   `json:"root-repo"`
+	Homepage     string   `json:"homepage,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Category     string   `json:"category,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Submodules   []string `json:"submodules,omitempty"`
+	Majors       []string `json:"majors,omitempty"`
+	Deprecated   string   `json:"deprecated,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	MovedTo      string   `json:"moved-to,omitempty"`
+	Template     string   `json:"template,omitempty"`
+	Private      bool     `json:"private,omitempty"`
+}
+
+func toModuleDTO(meta vanity.Meta) ModuleDTO {
+	dto := ModuleDTO{
+		ImportPrefix: meta.Import.Prefix,
+		VCS:          meta.Import.VCS.Name,
+		RootRepo:     meta.Import.VCS.RepoRoot.String(),
+		Homepage:     meta.Source.HomepageURL,
+		Title:        meta.Title,
+		Description:  meta.Description,
+		Category:     meta.Category,
+		Tags:         meta.Tags,
+		Private:      meta.Private,
+		Majors:       meta.Majors,
+		Deprecated:   meta.Deprecation.Message,
+		Replacement:  meta.Deprecation.Replacement,
+		Type:         meta.Tombstone.Kind,
+		MovedTo:      meta.Tombstone.MovedTo,
+		Template:     meta.Template,
+	}
+	for _, nested := range meta.Nested {
+		dto.Submodules = append(dto.Submodules, nested.ImportPath)
+	}
+	return dto
+}
+
+func printModuleJSON(w io.Writer, metas []vanity.Meta) error {
+	dtos := make([]ModuleDTO, 0, len(metas))
+	for _, meta := range metas {
+		dtos = append(dtos, toModuleDTO(meta))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dtos)
+}
+
+func printModuleTable(w io.Writer, metas []vanity.Meta) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMPORT PREFIX\tVCS\tREPOSITORY\tSUBMODULES\tSTATUS")
+	for _, meta := range metas {
+		var subs []string
+		for _, nested := range meta.Nested {
+			subs = append(subs, nested.Path)
+		}
+		var status string
+		switch {
+		case meta.Moved():
+			status = "moved to " + meta.Tombstone.MovedTo
+		case meta.Gone():
+			status = "gone"
+		case meta.Deprecation.Replacement != "":
+			status = "deprecated, use " + meta.Deprecation.Replacement
+		case meta.Deprecated():
+			status = "deprecated"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			meta.Import.Prefix,
+			meta.Import.VCS.Name,
+			meta.Import.VCS.RepoRoot.String(),
+			strings.Join(subs, ", "),
+			status)
+	}
+	return tw.Flush()
+}
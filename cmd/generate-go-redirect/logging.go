@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// The formats of the log output.
+const (
+	// logFormatText is a line of the time, the level, the message and the key=value fields, for interactive use.
+	logFormatText = "text"
+	// logFormatJSON is a JSON object per line, for the log aggregating systems.
+	logFormatJSON = "json"
+)
+
+// configureLogging sets up the logger from the LOG_LEVEL and LOG_FORMAT env variables.
+// The logs go to the standard error, so they don't mix with the output of the commands, like list -o json.
+// The logger reads the LOG_LEVEL of the process by itself, but not the namespaced one, or the one of the .env file.
+func configureLogging() error {
+	logger.Configure(func(l *logging.Logger) { l.Out = os.Stderr })
+	if level, ok := lookupEnv("LOG_LEVEL"); ok && level != os.Getenv("LOG_LEVEL") {
+		if err := (logLevelFlag{}).Set(level); err != nil {
+			return err
+		}
+	}
+	if format, ok := lookupEnv("LOG_FORMAT"); ok {
+		return setLogFormat(format)
+	}
+	return setLogFormat(logFormatText)
+}
+
+func setLogFormat(format string) error {
+	var marshal func(any) ([]byte, error)
+	switch format {
+	case logFormatText:
+		marshal = marshalLogText
+	case logFormatJSON:
+		marshal = json.Marshal
+	default:
+		return fmt.Errorf("unknown log format: %s (text or json)", format)
+	}
+	logger.Configure(func(l *logging.Logger) { l.MarshalFunc = marshal })
+	return nil
+}
+
+// logLevelFlag sets the level of the logger when the flag is parsed, so every command shares it.
+type logLevelFlag struct{}
+
+func (logLevelFlag) String() string {
+	var level logging.Level
+	logger.Configure(func(l *logging.Logger) { level = l.Level })
+	return level.String()
+}
+
+func (logLevelFlag) Set(value string) error {
+	level := logging.Level(strings.ToLower(value))
+	switch level {
+	case logging.LevelDebug, logging.LevelInfo, logging.LevelWarn, logging.LevelError:
+	default:
+		return fmt.Errorf("unknown log level: %s (debug, info, warn or error)", value)
+	}
+	logger.Configure(func(l *logging.Logger) { l.Level = level })
+	return nil
+}
+
+// logFormatFlag sets the format of the logger when the flag is parsed.
+type logFormatFlag struct{}
+
+func (logFormatFlag) String() string { return "" }
+
+func (logFormatFlag) Set(value string) error { return setLogFormat(strings.ToLower(value)) }
+
+// marshalLogText formats a log entry like "2006/01/02 15:04:05 INFO message key=value",
+// with the fields in the order of their keys, and the nested ones, like an error, flattened.
+func marshalLogText(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	if ts, ok := entry["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			b.WriteString(t.Local().Format("2006/01/02 15:04:05 "))
+		}
+	}
+	level, _ := entry["level"].(string)
+	b.WriteString(strings.ToUpper(zerokit.Coalesce(level, "info")))
+	if msg, ok := entry["message"].(string); ok {
+		b.WriteString(" " + msg)
+	}
+	delete(entry, "timestamp")
+	delete(entry, "level")
+	delete(entry, "message")
+
+	fields := map[string]string{}
+	flattenLogFields(fields, "", entry)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + key + "=" + fields[key])
+	}
+	return []byte(b.String()), nil
+}
+
+// flattenLogFields turns the nested fields into dotted keys, except for the message of an error, which is the error itself.
+func flattenLogFields(fields map[string]string, prefix string, value any) {
+	if obj, ok := value.(map[string]any); ok {
+		for key, v := range obj {
+			if key == "message" && prefix != "" && len(obj) == 1 {
+				flattenLogFields(fields, strings.TrimSuffix(prefix, "."), v)
+				continue
+			}
+			flattenLogFields(fields, prefix+key+".", v)
+		}
+		return
+	}
+	key := strings.TrimSuffix(prefix, ".")
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		fields[key] = v
+	default:
+		data, _ := json.Marshal(v)
+		fields[key] = string(data)
+	}
+}
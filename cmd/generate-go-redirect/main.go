@@ -2,229 +2,149 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	_ "embed"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
-	"io"
-	"log"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"go.llib.dev/frameless/pkg/env"
 	"go.llib.dev/frameless/pkg/logger"
 	"go.llib.dev/frameless/pkg/logging"
-	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
 )
 
+// exitPartialFailure is the exit code of a generation with -continue-on-error whose modules failed partly.
+const exitPartialFailure = 3
+
 func main() {
 	ctx := context.Background()
-	if err := Main(ctx); err != nil {
-		logger.Fatal(ctx, "error in main", logging.ErrField(err))
-		os.Exit(1)
-	}
-}
-
-func Main(ctx context.Context) error {
-	metas, err := getMetas()
-	if err != nil {
-		return fmt.Errorf("get import meta data failed: %w", err)
-	}
-	if err := generateProjectRedirects(metas); err != nil {
-		return fmt.Errorf("generate project redirects have failed: %w", err)
-	}
-	return nil
-}
-
-func generateProjectRedirects(metas []Meta) error {
-	const outDirEnvKey = "WEB_DIR_PATH"
-
-	domain, found, err := env.Lookup[string]("DOMAIN")
-	if err != nil {
-		return err
-	}
-	if !found {
-		return fmt.Errorf("missing DOMAIN env variable")
-	}
-
-	outDirPath, ok := os.LookupEnv(outDirEnvKey)
-	if !ok {
-		return fmt.Errorf("%s env variable not set", outDirEnvKey)
-	}
-
-	tmpl, err := getImportTemplate()
-	if err != nil {
-		return fmt.Errorf("getRedirectTemplate failed: %w", err)
-	}
-
-	if err := os.WriteFile(filepath.Join(outDirPath, "CNAME"), []byte(domain), 0666); err != nil {
-		return err
-	}
-
-	for _, meta := range metas {
-		if !strings.Contains(meta.Import.Prefix, domain) {
-			continue
-		}
-
-		var (
-			buf     bytes.Buffer
-			dirPath = filepath.Join(outDirPath, strings.TrimPrefix(meta.Import.Prefix, domain+"/"))
-			outPath = filepath.Join(dirPath, "index.html")
-		)
-
-		if err := tmpl.Execute(&buf, meta); err != nil {
-			return fmt.Errorf("redirect template execution failed: %w", err)
+	// the .env file is loaded first, since the logging and the defaults of the flags come from the env variables
+	if err := loadEnvFile(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := configureLogging(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadRemoteImports(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	actions.Enabled = getenv("GITHUB_ACTIONS") == "true"
+	if err := Main(ctx, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
 		}
-		if err := ensureDirectory(dirPath); err != nil {
-			return err
+		actions.annotateError(err)
+		if errors.As(err, new(vanity.ModuleErrors)) {
+			// the rest of the site was generated, which a pipeline may tell apart from a failed generation
+			logger.Error(ctx, "some modules failed", logging.ErrField(err))
+			os.Exit(exitPartialFailure)
 		}
-		if err := os.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
-			return fmt.Errorf("writing out html failed: %w", err)
-		}
-
-		log.Println("INFO", fmt.Sprintf("%s redirect is created", meta))
+		logger.Fatal(ctx, "error in main", logging.ErrField(err))
+		os.Exit(1)
 	}
-
-	return nil
 }
 
-//go:embed go-import.html
-var goImportHTML string
-
-// getImportTemplate is the Go import redirect template
-func getImportTemplate() (*template.Template, error) {
-	return template.New("go-redirect").Parse(goImportHTML)
-}
-
-type Meta struct {
-	Import MetaImport
-	Source MetaSource
-}
-
-type MetaImport struct {
-	Prefix string
-	VCS    MetaImportVCS
-}
-
-type MetaImportVCS struct {
-	Name     string `enum:"git,"`
-	RepoRoot *url.URL
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(ctx context.Context, args []string) error
 }
 
-// MetaSource
-//
-// {dir} - The import path with prefix and leading "/" trimmed.
-// {/dir} - If {dir} is not the empty string, then {/dir} is replaced by "/" +
-// {dir}. Otherwise, {/dir} is replaced with the empty string.
-//
-// {file} - The name of the file
-// {line} - The decimal line number.
-type MetaSource struct {
-	// HomepageURL is the home URL that the source uses
-	//
-	// default: _
-	HomepageURL string
-	// Directory is the directory pattern it should use
-	DirectoryPattern string
-	// File is the file pattern that the go import should use
-	FilePattern string
+// defaultCommand is used when no command is given, which keeps `go generate` working.
+const defaultCommand = "generate"
+
+var commands = []Command{
+	{
+		Name:    "init",
+		Summary: "scaffold the imports file and the output directory of a new vanity domain",
+		Run:     initCommand,
+	},
+	{
+		Name:    "generate",
+		Summary: "render the go-import redirect pages into the web directory",
+		Run:     generateCommand,
+	},
+	{
+		Name:    "add",
+		Summary: "append a module to the imports file",
+		Run:     addCommand,
+	},
+	{
+		Name:    "discover",
+		Summary: "find the Go module repositories of a GitHub, GitLab or Gitea account",
+		Run:     discoverCommand,
+	},
+	{
+		Name:    "list",
+		Summary: "print the configured modules as a table or JSON",
+		Run:     listCommand,
+	},
+	{
+		Name:    "deploy",
+		Summary: "sync the web directory to an S3, GCS or Azure Blob bucket, or to a web server over SSH",
+		Run:     deployCommand,
+	},
+	{
+		Name:    "serve",
+		Summary: "answer the go-get requests over HTTP, without a static-site host",
+		Run:     serveCommand,
+	},
+	{
+		Name:    "validate",
+		Summary: "check the imports file without generating anything, or the pages of the live site",
+		Run:     validateCommand,
+	},
+	{
+		Name:    "lint",
+		Summary: "find the conflicts and inconsistencies between the entries of the imports file",
+		Run:     lintCommand,
+	},
+	{
+		Name:    "doctor",
+		Summary: "probe the repositories of the modules, and optionally the DNS records and the TLS certificate of the domain",
+		Run:     doctorCommand,
+	},
+	{
+		Name:    "check",
+		Summary: "resolve every import path from the web directory like go get, without network access",
+		Run:     checkCommand,
+	},
+	{
+		Name:    "selftest",
+		Summary: "download every import path with the go command, from the web directory served locally over HTTPS",
+		Run:     selftestCommand,
+	},
 }
 
-// var findURL = regexp.MustCompile(`https?://[^\s+]+`)
-
-func getMetas() ([]Meta, error) {
-	type JSONDTO struct {
-		VCS              string `json:"vcs"`
-		ImportPrefix     string `json:"import-prefix"`
-		RootRepo         string `json:"root-repo"`
-		HomepageURL      string `json:"homepage"`
-		DirectoryPattern string `json:"directory-pattern"`
-		FilePattern      string `json:"file-pattern"`
-	}
-
-	const envKey = "IMPORTS_FILE_PATH"
-	// Read environment variable
-	filePath, ok := os.LookupEnv(envKey)
-	if !ok {
-		return nil,
-			fmt.Errorf("%s environment variable is not set", envKey)
-	}
-
-	// Open the file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil,
-			fmt.Errorf("failed to open imports file: %w", err)
-	}
-	defer file.Close()
-
-	// Create a new scanner and read the file line by line
-
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, err
+func Main(ctx context.Context, args []string) error {
+	name := defaultCommand
+	if 0 < len(args) && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-
-	var metas []Meta
-
-	var dtos []JSONDTO
-	if err := json.Unmarshal(data, &dtos); err != nil {
-		return nil, err
+	if name == "help" {
+		usage()
+		return nil
 	}
-
-	for _, dto := range dtos {
-		vcsRepoRoot, err := url.Parse(dto.RootRepo)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse vcs repo root: %w", err)
-		}
-
-		imp := MetaImport{
-			Prefix: dto.ImportPrefix,
-			VCS: MetaImportVCS{
-				Name:     dto.VCS,
-				RepoRoot: vcsRepoRoot,
-			},
-		}
-
-		src := MetaSource{
-			HomepageURL:      dto.HomepageURL,
-			DirectoryPattern: dto.DirectoryPattern,
-			FilePattern:      dto.FilePattern,
-		}
-
-		if src.HomepageURL == "" {
-			src.HomepageURL = imp.VCS.RepoRoot.String()
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd.Run(ctx, args)
 		}
-
-		if strings.Contains(imp.VCS.RepoRoot.Host, "github.com") {
-			if zerokit.IsZero(src.DirectoryPattern) {
-				src.DirectoryPattern = fmt.Sprintf("%s/tree/master{/dir}", imp.VCS.RepoRoot.String())
-			}
-			if zerokit.IsZero(src.FilePattern) {
-				src.FilePattern = fmt.Sprintf("%s/{file}#L{line}", src.DirectoryPattern)
-			}
-		}
-
-		metas = append(metas, Meta{
-			Import: imp,
-			Source: src,
-		})
 	}
-
-	return metas, nil
+	usage()
+	return fmt.Errorf("unknown command: %s", name)
 }
 
-// ensureDirectory attempts to create a directory at the specified path.
-// It returns nil if the directory was created successfully or already exists,
-// and an error if any occurred.
-func ensureDirectory(path string) error {
-	err := os.MkdirAll(path, 0755)
-	if err != nil {
-		return err
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s <command> [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.Name, cmd.Summary)
 	}
-	return nil
+	fmt.Fprintf(out, "\nUse \"<command> -h\" to see the flags of a command.\n")
+	fmt.Fprintf(out, "The env variables of the flags can be namespaced with %s, like %sDOMAIN, and set in a .env file (env: %s).\n", envPrefix, envPrefix, envFileVar)
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func printPlan(w io.Writer, changes []vanity.Change, withDiff bool) error {
+	var counts = map[vanity.ChangeKind]int{}
+	for _, change := range changes {
+		counts[change.Kind]++
+		if _, err := fmt.Fprintf(w, "%-9s %s\n", change.Kind, change.File.Path); err != nil {
+			return err
+		}
+		if !withDiff || change.Kind == vanity.ChangeUnchanged {
+			continue
+		}
+		diff := unifiedDiff("a/"+change.File.Path, "b/"+change.File.Path, string(change.Old), string(change.File.Content))
+		if _, err := io.WriteString(w, diff); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d to create, %d to update, %d to delete, %d unchanged\n",
+		counts[vanity.ChangeCreate], counts[vanity.ChangeUpdate], counts[vanity.ChangeDelete], counts[vanity.ChangeUnchanged])
+	return err
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/pkg/tasker"
+	"go.llib.dev/pkg/vanity"
+)
+
+// reloadTask loads the modules of the server from the imports file,
+// then it reloads them on SIGHUP, when the imports file changes on the disk,
+// and when the webhook tells about a push to the repository of the imports file, or of a module.
+// The new modules replace the server's module table in one step, so in-flight requests are not affected.
+// A broken imports file is reported on reload, and the server keeps the previous modules.
+//
+// With a refresh interval, the modules are also reloaded periodically, with the default branches detected again,
+// and the major versions and the nested modules too when their detection is on, so these parts of the pages don't go stale.
+func reloadTask(conf Config, srv *vanity.Server, watch, refresh time.Duration, hook webhookConfig) tasker.Task {
+	return func(ctx context.Context) error {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		var tick <-chan time.Time
+		if 0 < watch && !vanity.IsRemoteImports(conf.Imports) {
+			ticker := time.NewTicker(watch)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		var (
+			refreshTimer *time.Timer
+			refreshes    <-chan time.Time
+			failures     int
+		)
+		if 0 < refresh {
+			refreshTimer = time.NewTimer(refreshDelay(refresh, 0))
+			defer refreshTimer.Stop()
+			refreshes = refreshTimer.C
+		}
+
+		last, _ := importsFingerprint(conf.Imports)
+		modules, err := loadImports(ctx, conf, srv)
+		if err != nil {
+			return err
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-refreshes:
+				modules, err = refreshImports(ctx, conf, srv, modules)
+				if err != nil {
+					failures++
+				} else {
+					failures = 0
+				}
+				refreshTimer.Reset(refreshDelay(refresh, failures))
+			case <-hup:
+				modules = reloadImports(ctx, conf, srv, modules)
+			case <-tick:
+				fingerprint, err := importsFingerprint(conf.Imports)
+				if err != nil || fingerprint == last {
+					continue
+				}
+				last = fingerprint
+				modules = reloadImports(ctx, conf, srv, modules)
+			case event := <-hook.Pushes:
+				// a burst of pushes, like a branch and its tags, is a single reload
+				events := []vanity.PushEvent{event}
+				for pending := true; pending; {
+					select {
+					case event := <-hook.Pushes:
+						events = append(events, event)
+					default:
+						pending = false
+					}
+				}
+				modules = pushReload(ctx, conf, srv, modules, hook.ImportsRepo, events)
+			}
+		}
+	}
+}
+
+func loadImports(ctx context.Context, conf Config, srv *vanity.Server) ([]vanity.Meta, error) {
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return nil, err
+	}
+	srv.SetModules(gen.Modules)
+	for _, meta := range gen.Modules {
+		importPaths := []string{meta.Import.Prefix}
+		for _, nested := range meta.Nested {
+			importPaths = append(importPaths, nested.ImportPath)
+		}
+		for _, importPath := range importPaths {
+			if reserved, ok := vanity.ReservedPath(importPath); ok {
+				logger.Warn(ctx, "the module can't be served, its import path falls on an endpoint of the server",
+					logging.Field("import_path", importPath), logging.Field("endpoint", reserved))
+			}
+		}
+	}
+	logger.Info(ctx, "imports loaded", logging.Field("modules", len(gen.Modules)))
+	return gen.Modules, nil
+}
+
+// reloadImports loads the modules again, and returns them, or the previous ones when the reload fails.
+func reloadImports(ctx context.Context, conf Config, srv *vanity.Server, previous []vanity.Meta) []vanity.Meta {
+	modules, err := loadImports(ctx, conf, srv)
+	srv.Metrics.ObserveReload(err)
+	if err != nil {
+		logger.Error(ctx, "imports reload failed, the previous modules are kept", logging.ErrField(err))
+		return previous
+	}
+	return modules
+}
+
+// refreshImports reloads the modules, ignoring the cached default branches, so they are detected again.
+// The major versions and the nested modules are detected again only when -detect-majors and -scan-submodules are on,
+// like on any reload, and the archived status of the repositories is not detected at all.
+func refreshImports(ctx context.Context, conf Config, srv *vanity.Server, previous []vanity.Meta) ([]vanity.Meta, error) {
+	conf.Branches.TTL = 0
+	modules, err := loadImports(ctx, conf, srv)
+	srv.Metrics.ObserveReload(err)
+	if err != nil {
+		logger.Error(ctx, "imports refresh failed, the previous modules are kept", logging.ErrField(err))
+		return previous, err
+	}
+	return modules, nil
+}
+
+// refreshDelay is the time until the next periodic refresh.
+// It's the interval with a ±10% jitter, so the replicas of the server don't hit the code hosts at the same time.
+// After failed refreshes, it's a backoff from 30s, which doubles with every failure, up to the interval.
+func refreshDelay(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	if 0 < failures {
+		backoff := 30 * time.Second
+		for i := 1; i < failures && backoff < interval; i++ {
+			backoff *= 2
+		}
+		if backoff < delay {
+			delay = backoff
+		}
+	}
+	if jitter := int64(delay / 10); 0 < jitter {
+		delay += time.Duration(rand.Int63n(2*jitter+1) - jitter)
+	}
+	return delay
+}
+
+// pushReload reloads the modules after the pushes of the webhook, when a pushed repository is the repository of the imports file,
+// whose checkout is pulled first, or of a module, whose default branch is detected again, since the push could change it.
+// The pushes of other repositories are ignored.
+func pushReload(ctx context.Context, conf Config, srv *vanity.Server, modules []vanity.Meta, importsRepo string, events []vanity.PushEvent) []vanity.Meta {
+	var (
+		tracked bool
+		refresh []string
+	)
+	for _, event := range events {
+		if importsRepo != "" && event.Of(importsRepo) {
+			tracked = true
+			if err := vanity.PullImports(ctx, conf.Imports); err != nil {
+				logger.Error(ctx, "imports pull failed", logging.ErrField(err))
+			}
+			continue
+		}
+		var matched bool
+		for _, meta := range modules {
+			for _, repoURL := range []string{meta.Source.HomepageURL, repoRootOf(meta)} {
+				if repoURL != "" && event.Of(repoURL) {
+					matched = true
+					refresh = append(refresh, repoURL)
+				}
+			}
+		}
+		if !matched {
+			logger.Info(ctx, "push of an untracked repository is ignored", logging.Field("repo", event.Repository))
+			continue
+		}
+		tracked = true
+	}
+	if !tracked {
+		return modules
+	}
+	logger.Info(ctx, "reloading the imports after a push", logging.Field("pushes", len(events)))
+	conf.Branches.Refresh = refresh
+	return reloadImports(ctx, conf, srv, modules)
+}
+
+func repoRootOf(meta vanity.Meta) string {
+	if meta.Import.VCS.RepoRoot == nil {
+		return ""
+	}
+	return meta.Import.VCS.RepoRoot.String()
+}
+
+// webhookConfig connects the webhook of the server to the reload task.
+type webhookConfig struct {
+	// Pushes are the verified pushes of the webhook.
+	Pushes <-chan vanity.PushEvent
+	// ImportsRepo is the repository of the imports file.
+	ImportsRepo string
+}
+
+// importsFingerprint summarizes the modification time and size of the imports file,
+// or of every module file when the imports location is a directory.
+func importsFingerprint(location string) (string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+	}
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(location, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.ModTime().UnixNano(), info.Size()))
+	}
+	return strings.Join(parts, ","), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func selftestCommand(ctx context.Context, args []string) error {
+	var (
+		conf Config
+		opts vanity.SelfTestOptions
+	)
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.IntVar(&opts.Workers, "workers", 4, "number of import paths downloaded concurrently")
+	fs.DurationVar(&opts.Timeout, "timeout", 2*time.Minute, "how long the download of a single import path may take")
+	fs.StringVar(&opts.Go, "go", "go", "go command that downloads the modules")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports, flagOut); err != nil {
+		return err
+	}
+	// the branch only matters for the go-source meta, which the go command doesn't need
+	conf.DetectBranch = false
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return err
+	}
+
+	results, err := vanity.SelfTest(ctx, siteDirs(gen, conf.WebDirPath), gen.Modules, opts)
+	if err != nil {
+		return err
+	}
+	var failures int
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tIMPORT PATH\tVERSION\tTIME\tERROR")
+	for _, r := range results {
+		status, msg := "ok", ""
+		if r.Err != nil {
+			// the output of the go command spans lines, which would break the table
+			status, msg = "FAIL", strings.Join(strings.Fields(r.Err.Error()), " ")
+			failures++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status, r.ImportPath, r.Version, r.Duration.Round(time.Millisecond), msg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d import paths downloaded, %d failed\n", len(results)-failures, failures)
+	if 0 < failures {
+		return fmt.Errorf("%d import paths failed to download with the go command", failures)
+	}
+	return nil
+}
+
+// siteDirs are the output directories of the sites by their domains,
+// which are the subdirectories of the domains for a multi-domain or wildcard site.
+func siteDirs(gen vanity.Generator, outDir string) map[string]fs.FS {
+	sites := gen.Sites()
+	if len(sites) == 0 {
+		return map[string]fs.FS{gen.Domain: os.DirFS(outDir)}
+	}
+	dirs := map[string]fs.FS{}
+	for _, domain := range sites {
+		dirs[domain] = os.DirFS(filepath.Join(outDir, domain))
+	}
+	return dirs
+}
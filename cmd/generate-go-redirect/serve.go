@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/contextkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/pkg/tasker"
+	"go.llib.dev/frameless/pkg/zerokit"
+	"go.llib.dev/pkg/vanity"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func serveCommand(ctx context.Context, args []string) error {
+	var (
+		conf    Config
+		addr    string
+		acme    ACMEConfig
+		auth    AuthConfig
+		watch   time.Duration
+		refresh time.Duration
+		metrics string
+		sample  float64
+		drain   time.Duration
+		maxAge  time.Duration
+		proxy   bool
+		gitDir  string
+		cache   vanity.CachedSource
+		cacheMB int64
+		sumdb   string
+		secret  string
+		hook    webhookConfig
+		headers vanity.SecurityHeaders
+		locales LocaleConfig
+		theme   string
+	)
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.StringVar(&addr, "addr", getServeAddr(), "listen address of the HTTP server (env: ADDR or PORT)")
+	fs.DurationVar(&watch, "watch", 2*time.Second, "interval of checking the imports file for changes, 0 turns it off (SIGHUP always reloads)")
+	fs.DurationVar(&refresh, "refresh", 0, "interval of reloading the modules with the default branches of the repositories detected again, 0 turns it off")
+	fs.StringVar(&metrics, "metrics-addr", getenv("METRICS_ADDR"), "listen address of the Prometheus metrics on /metrics, like 127.0.0.1:9090, empty turns them off (env: METRICS_ADDR)")
+	fs.Float64Var(&sample, "access-log-sample", 1, "ratio of the requests written to the access log, 0 turns it off")
+	fs.DurationVar(&maxAge, "max-age", 5*time.Minute, "how long the clients, module proxies and CDNs may cache a page")
+	bindSecurityHeaders(fs, &headers)
+	locales.Bind(fs)
+	fs.StringVar(&theme, "theme", getenv("THEME"), "built-in theme of the pages (default, gopher, paper or midnight), or a JSON theme file (env: THEME)")
+	fs.DurationVar(&drain, "shutdown-timeout", 25*time.Second, "time limit of draining the in-flight requests on SIGTERM or SIGINT")
+	fs.BoolVar(&proxy, "proxy", false, "act as a module proxy (GOPROXY) for the configured git modules")
+	fs.StringVar(&gitDir, "proxy-dir", defaultProxyDir("git"), "directory of the git mirrors that the module proxy serves from")
+	fs.StringVar(&cache.Dir, "proxy-cache", defaultProxyDir("cache"), "directory of the cached module proxy responses, empty turns the cache off")
+	fs.DurationVar(&cache.TTL, "proxy-cache-ttl", 24*time.Hour, "how long a cached module proxy response is served, 0 keeps them forever")
+	fs.Int64Var(&cacheMB, "proxy-cache-size", 1024, "size limit of the module proxy cache in MiB, 0 turns the limit off")
+	fs.StringVar(&sumdb, "sumdb", "", "URL of a checksum database to forward the /sumdb/ requests to, e.g. https://sum.golang.org")
+	fs.StringVar(&secret, "webhook-secret", getenv("WEBHOOK_SECRET"), "secret of the push webhooks of GitHub, GitLab and Gitea on /webhook, empty turns the webhook off (env: WEBHOOK_SECRET)")
+	fs.StringVar(&hook.ImportsRepo, "imports-repo", getenv("IMPORTS_REPO"), "repository of the imports file, whose pushes pull the checkout of the imports file, and reload it (env: IMPORTS_REPO)")
+	acme.Bind(fs)
+	auth.Bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	if len(conf.Domains()) > 1 || strings.HasPrefix(conf.Domain, "*.") {
+		return fmt.Errorf("the server answers a single domain, run a server per domain instead")
+	}
+	srv, err := vanity.NewServer(conf.primaryDomain())
+	if err != nil {
+		return err
+	}
+	srv.MaxAge = maxAge
+	srv.SecurityHeaders = headers
+	srv.Language = locales.Language
+	if srv.Catalogs, err = locales.Catalogs(); err != nil {
+		return err
+	}
+	if theme != "" {
+		if srv.Theme, err = vanity.LoadTheme(theme); err != nil {
+			return err
+		}
+	}
+	if srv.Auth, err = auth.Credentials(); err != nil {
+		return err
+	}
+	if proxy {
+		srv.Proxy = &vanity.GitSource{Dir: gitDir}
+		if cache.Dir != "" {
+			cache.Source, cache.MaxSize = srv.Proxy, cacheMB<<20
+			srv.Proxy = &cache
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	mux.HandleFunc("/healthz", healthz)
+	mux.HandleFunc("/readyz", readyz(srv))
+	if sumdb != "" {
+		sumdbProxy, err := vanity.NewSumDBProxy(sumdb)
+		if err != nil {
+			return fmt.Errorf("invalid -sumdb URL: %w", err)
+		}
+		mux.Handle("/sumdb/", sumdbProxy)
+	}
+	if secret != "" {
+		pushes := make(chan vanity.PushEvent, 64)
+		hook.Pushes = pushes
+		mux.Handle("/webhook", &vanity.Webhook{
+			Secret: secret,
+			OnPush: func(ctx context.Context, event vanity.PushEvent) {
+				select {
+				case pushes <- event:
+				default:
+					logger.Warn(ctx, "too many pending pushes, the push is dropped", logging.Field("repo", event.Repository))
+				}
+			},
+		})
+	}
+	var handler http.Handler = mux
+	if 0 < sample {
+		handler = vanity.AccessLog{Handler: mux, SampleRate: sample}
+	}
+
+	servers := []*http.Server{{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}}
+	if acme.Enabled {
+		// the certificate is of the host, without the base path of the domain
+		host, _, _ := strings.Cut(conf.Domain, "/")
+		servers = acmeServers(host, addr, acme, handler)
+	}
+
+	tasks := []tasker.Task{reloadTask(conf, srv, watch, refresh, hook)}
+	for _, server := range servers {
+		logger.Info(ctx, "serving go-get requests",
+			logging.Field("addr", server.Addr),
+			logging.Field("tls", server.TLSConfig != nil))
+		tasks = append(tasks, serverTask(server, drain))
+	}
+	if metrics != "" {
+		// the metrics have a listener of their own, which can be kept away from the public traffic
+		srv.Metrics = &vanity.Metrics{}
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", srv.Metrics)
+		logger.Info(ctx, "serving metrics", logging.Field("addr", metrics))
+		tasks = append(tasks, serverTask(&http.Server{Addr: metrics, Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second}, drain))
+	}
+	return tasker.Main(ctx, tasks...)
+}
+
+// serverTask runs the HTTP server until a shutdown signal.
+// Then the server stops accepting new connections, and drains the in-flight requests,
+// so a rolling deploy doesn't break a `go get` mid-request.
+// The connections that are still active after the shutdown timeout are closed.
+func serverTask(srv *http.Server, shutdownTimeout time.Duration) tasker.Task {
+	return tasker.WithShutdown(
+		tasker.IgnoreError(func(context.Context) error {
+			if srv.TLSConfig != nil {
+				return srv.ListenAndServeTLS("", "")
+			}
+			return srv.ListenAndServe()
+		}, http.ErrServerClosed),
+		func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(contextkit.Detach(ctx), shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Warn(ctx, "in-flight requests were not drained in time", logging.ErrField(err))
+				return srv.Close()
+			}
+			return nil
+		},
+	)
+}
+
+// healthz tells that the server is alive.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// readyz tells whether the server has loaded its modules, and so it can take traffic.
+func readyz(srv *vanity.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !srv.Ready() {
+			http.Error(w, "imports are not loaded yet", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok\n")
+	}
+}
+
+// ACMEConfig configures the automatic TLS certificates of the serve command.
+type ACMEConfig struct {
+	// Enabled turns on HTTPS with a Let's Encrypt certificate for the domain.
+	Enabled bool
+	// TLSAddr is the listen address of the HTTPS server.
+	TLSAddr string
+	// CacheDir is where the certificates are kept between restarts,
+	// so they are not requested again on every start.
+	CacheDir string
+	// Email is the optional contact address of the ACME account.
+	Email string
+}
+
+func (c *ACMEConfig) Bind(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Enabled, "acme", getenv("ACME") == "true", "serve HTTPS with a Let's Encrypt certificate for the domain (env: ACME)")
+	fs.StringVar(&c.TLSAddr, "tls-addr", zerokit.Coalesce(getenv("TLS_ADDR"), ":443"), "listen address of the HTTPS server (env: TLS_ADDR)")
+	fs.StringVar(&c.CacheDir, "acme-cache", zerokit.Coalesce(getenv("ACME_CACHE_DIR"), defaultACMECacheDir()), "directory of the cached certificates (env: ACME_CACHE_DIR)")
+	fs.StringVar(&c.Email, "acme-email", getenv("ACME_EMAIL"), "contact email of the ACME account (env: ACME_EMAIL)")
+}
+
+// AuthConfig configures the credential sources of the private modules.
+type AuthConfig struct {
+	// File is the path of a credentials file, with a "user:password" pair or a bearer token on each line.
+	File string
+	// Tokens is a comma separated list of bearer tokens.
+	Tokens string
+}
+
+func (c *AuthConfig) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.File, "auth-file", getenv("AUTH_FILE"), "credentials file of the private modules, with a user:password pair or a bearer token per line (env: AUTH_FILE)")
+	fs.StringVar(&c.Tokens, "auth-tokens", getenv("AUTH_TOKENS"), "comma separated bearer tokens of the private modules (env: AUTH_TOKENS)")
+}
+
+// Credentials merges the credentials of every configured source.
+// Without any source, the private modules are not served at all.
+func (c AuthConfig) Credentials() (*vanity.Credentials, error) {
+	if c.File == "" && c.Tokens == "" {
+		return nil, nil
+	}
+	var creds vanity.Credentials
+	if c.File != "" {
+		var err error
+		if creds, err = vanity.ReadCredentials(c.File); err != nil {
+			return nil, err
+		}
+	}
+	for _, token := range strings.Split(c.Tokens, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			creds.Tokens = append(creds.Tokens, token)
+		}
+	}
+	return &creds, nil
+}
+
+// acmeServers serve HTTPS on the TLS address with an automatically obtained certificate.
+// The HTTP address answers the ACME challenges, and redirects everything else to HTTPS.
+func acmeServers(domain, addr string, acme ACMEConfig, handler http.Handler) []*http.Server {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domain),
+		Cache:      autocert.DirCache(acme.CacheDir),
+		Email:      acme.Email,
+	}
+	return []*http.Server{
+		{
+			Addr:              addr,
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		{
+			Addr:              acme.TLSAddr,
+			Handler:           handler,
+			TLSConfig:         m.TLSConfig(),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func defaultACMECacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "acme-cache"
+	}
+	return filepath.Join(dir, "generate-go-redirect", "acme")
+}
+
+func defaultProxyDir(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join("proxy-cache", name)
+	}
+	return filepath.Join(dir, "generate-go-redirect", name)
+}
+
+func getServeAddr() string {
+	if addr, ok := lookupEnv("ADDR"); ok {
+		return addr
+	}
+	if port, ok := lookupEnv("PORT"); ok {
+		return fmt.Sprintf(":%s", port)
+	}
+	return ":8080"
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go.llib.dev/pkg/vanity"
+)
+
+func validateCommand(ctx context.Context, args []string) error {
+	var (
+		conf         Config
+		checkModules bool
+		live         bool
+	)
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	conf.Bind(fs)
+	fs.BoolVar(&checkModules, "check-modules", false, "check that the repositories can be cloned, and their go.mod files declare the configured import paths")
+	fs.BoolVar(&live, "live", false, "request the pages of every import path from the live site with ?go-get=1, and check their go-import meta tags")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := conf.Require(flagDomain, flagImports); err != nil {
+		return err
+	}
+	var gen vanity.Generator
+	if err := conf.Load(ctx, &gen); err != nil {
+		return err
+	}
+	if checkModules {
+		if err := vanity.CheckModulePaths(ctx, gen.Modules); err != nil {
+			return fmt.Errorf("module check failed:\n%w", err)
+		}
+	}
+	if live {
+		if err := vanity.CheckLiveSite(ctx, nil, gen.Modules); err != nil {
+			return fmt.Errorf("live site check failed:\n%w", err)
+		}
+	}
+	fmt.Printf("%d modules are valid\n", len(gen.Modules))
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// watchGenerate runs the generation, then runs it again whenever a watched file changes, until an interrupt.
+// A change has to settle for an interval first, so the burst of writes of an editor, or of a git checkout, is a single generation.
+// A failed generation is logged, and the watch goes on, so the next fix of the files can be picked up.
+func watchGenerate(ctx context.Context, paths []string, interval time.Duration, generate func(context.Context) error) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	run := func() {
+		start := time.Now()
+		if err := generate(ctx); err != nil {
+			logger.Error(ctx, "generation failed, watching for changes", logging.ErrField(err))
+			return
+		}
+		logger.Info(ctx, "site generated, watching for changes", logging.Field("duration", time.Since(start).Round(time.Millisecond).String()))
+	}
+
+	last := watchFingerprint(paths)
+	run()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current := watchFingerprint(paths)
+		if current == last {
+			continue
+		}
+		// the debounce: the files have to stay the same for a whole interval
+		for settled := false; !settled; {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			next := watchFingerprint(paths)
+			settled, current = next == current, next
+		}
+		last = current
+		logger.Info(ctx, "change detected, generating the site again")
+		run()
+	}
+}
+
+// watchFingerprint summarizes the modification time and size of the files,
+// and of every file under the directories, like importsFingerprint does for the imports file.
+// A missing file is a part of the summary too, so its removal, or its creation, is a change.
+func watchFingerprint(paths []string) string {
+	var parts []string
+	for _, root := range paths {
+		if root == "" {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.ModTime().UnixNano(), info.Size()))
+			}
+			return nil
+		})
+		if err != nil {
+			parts = append(parts, fmt.Sprintf("%s:%v", root, err))
+		}
+	}
+	return strings.Join(parts, ",")
+}
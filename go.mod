@@ -3,6 +3,18 @@ module go.llib.dev
 go 1.20
 
 require (
-	go.llib.dev/frameless v0.235.0 // indirect
+	github.com/BurntSushi/toml v1.3.2
+	github.com/yuin/goldmark v1.7.8
+	go.llib.dev/frameless v0.235.0
+	golang.org/x/crypto v0.15.0
+	golang.org/x/mod v0.14.0
+	golang.org/x/sys v0.14.0
+	golang.org/x/term v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	go.llib.dev/testcase v0.160.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
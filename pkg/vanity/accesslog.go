@@ -0,0 +1,46 @@
+package vanity
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// AccessLog logs the requests of the wrapped handler through the logger,
+// so the traffic of the vanity domain can be analyzed downstream.
+type AccessLog struct {
+	Handler http.Handler
+	// SampleRate is the ratio of the logged requests, between 0 and 1.
+	// Server errors are logged regardless of the sampling.
+	SampleRate float64
+}
+
+func (al AccessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	al.Handler.ServeHTTP(rec, r)
+	if rec.status < 500 && rand.Float64() >= al.SampleRate {
+		return
+	}
+	logger.Info(r.Context(), "http request",
+		logging.Field("method", r.Method),
+		logging.Field("path", r.URL.Path),
+		logging.Field("user_agent", r.UserAgent()),
+		logging.Field("go_get", r.URL.Query().Get("go-get") == "1"),
+		logging.Field("status", rec.status),
+		logging.Field("duration_ms", float64(time.Since(start).Microseconds())/1000))
+}
+
+// statusRecorder remembers the status code that the handler responded with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
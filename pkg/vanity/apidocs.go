@@ -0,0 +1,300 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// apiDocsDir is the directory of the API documentation within the directory of a module's page.
+const apiDocsDir = "docs"
+
+// PackageDoc is the API documentation of a package, which is the data of its documentation page.
+type PackageDoc struct {
+	ImportPath string
+	// Name is the package name, and it is empty for a module root without Go files.
+	Name string
+	// Synopsis is the first sentence of the package comment.
+	Synopsis string
+	// Doc is the package comment, rendered to HTML.
+	Doc    template.HTML
+	Consts []DeclDoc
+	Vars   []DeclDoc
+	Funcs  []DeclDoc
+	Types  []TypeDoc
+}
+
+// DeclDoc is the documentation of an exported declaration.
+type DeclDoc struct {
+	// Name is the name of the function or type, and it is empty for a group of constants or variables.
+	Name string
+	// Decl is the source code of the declaration, without the function bodies and the unexported fields.
+	Decl string
+	// Doc is the doc comment of the declaration, rendered to HTML.
+	Doc template.HTML
+}
+
+// TypeDoc is the documentation of an exported type, with the declarations that belong to it.
+type TypeDoc struct {
+	DeclDoc
+	Consts []DeclDoc
+	Vars   []DeclDoc
+	// Funcs are the constructors of the type.
+	Funcs   []DeclDoc
+	Methods []DeclDoc
+}
+
+// FetchAPIDocs sets the API documentation of the modules and their nested modules,
+// by parsing the Go packages of the default branch of their git and Mercurial repositories with go/doc.
+// The files are selected by their build constraints for linux/amd64, like on pkg.go.dev.
+// A repository that can't be reached is only warned about, and its modules are left without documentation.
+func FetchAPIDocs(ctx context.Context, metas []Meta) []Meta {
+	for i, meta := range metas {
+		if meta.Private || meta.Tombstone.Kind != "" || !isClonable(meta.Import.VCS.SourceRepo().Name) {
+			continue
+		}
+		docs, err := fetchAPIDocs(ctx, meta)
+		if err != nil {
+			logger.Warn(ctx, "API documentation fetching failed",
+				logging.Field("module", meta.Import.Prefix),
+				logging.ErrField(err))
+			continue
+		}
+		metas[i].APIDocs = docs
+	}
+	return metas
+}
+
+func fetchAPIDocs(ctx context.Context, meta Meta) (_ map[string][]PackageDoc, rErr error) {
+	repo := meta.Import.VCS.SourceRepo()
+	clone, err := cloneRepo(ctx, repo.Name, repo.RepoRoot.String())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	files, err := clone.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modules := map[string]string{".": meta.Import.Prefix}
+	for _, nested := range meta.Nested {
+		modules[nested.Path] = nested.ImportPath
+	}
+	var (
+		// the directories of every go.mod file, since an unlisted module is not part of the configured ones either
+		moduleDirs = map[string]struct{}{".": {}}
+		pkgFiles   = map[string][]string{}
+	)
+	for _, name := range files {
+		dir := path.Dir(name)
+		if path.Base(name) == "go.mod" {
+			moduleDirs[dir] = struct{}{}
+		}
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") && (dir == "." || !isIgnoredPath(dir)) {
+			pkgFiles[dir] = append(pkgFiles[dir], name)
+		}
+	}
+
+	docs := map[string][]PackageDoc{}
+	for dir, names := range pkgFiles {
+		moduleDir := owningModuleDir(moduleDirs, dir)
+		modulePath, ok := modules[moduleDir]
+		if !ok {
+			continue
+		}
+		importPath := path.Join(modulePath, strings.TrimPrefix(strings.TrimPrefix(dir, moduleDir), "/"))
+		pkg, err := parsePackageDoc(ctx, clone, importPath, names)
+		if err != nil {
+			logger.Warn(ctx, "the package can't be documented",
+				logging.Field("package", importPath),
+				logging.ErrField(err))
+			continue
+		}
+		if pkg != nil {
+			docs[modulePath] = append(docs[modulePath], *pkg)
+		}
+	}
+	for _, pkgs := range docs {
+		sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+	}
+	return docs, nil
+}
+
+// owningModuleDir tells the directory of the innermost module that the directory belongs to.
+func owningModuleDir(moduleDirs map[string]struct{}, dir string) string {
+	for d := dir; d != "."; d = path.Dir(d) {
+		if _, ok := moduleDirs[d]; ok {
+			return d
+		}
+	}
+	return "."
+}
+
+// parsePackageDoc documents the package of the Go files.
+// It returns nil when none of the files are built for linux/amd64.
+func parsePackageDoc(ctx context.Context, clone repoClone, importPath string, names []string) (*PackageDoc, error) {
+	sources := map[string][]byte{}
+	for _, name := range names {
+		data, err := clone.ReadFile(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = data
+	}
+	bctx := build.Default
+	bctx.GOOS, bctx.GOARCH = "linux", "amd64"
+	bctx.JoinPath = path.Join
+	bctx.OpenFile = func(name string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(sources[name])), nil
+	}
+
+	var (
+		fset  = token.NewFileSet()
+		files []*ast.File
+	)
+	for _, name := range names {
+		if ok, err := bctx.MatchFile(path.Dir(name), path.Base(name)); err != nil || !ok {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, sources[name], parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	pkg, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		decl = func(node any) string {
+			var buf bytes.Buffer
+			_ = (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, node)
+			return buf.String()
+		}
+		// go/doc/comment escapes the text of the comments
+		html = func(text string) template.HTML {
+			return template.HTML(bytes.TrimSpace(pkg.HTML(text)))
+		}
+		values = func(vs []*doc.Value) []DeclDoc {
+			var dds []DeclDoc
+			for _, v := range vs {
+				dds = append(dds, DeclDoc{Decl: decl(v.Decl), Doc: html(v.Doc)})
+			}
+			return dds
+		}
+		funcs = func(recv string, fs []*doc.Func) []DeclDoc {
+			var dds []DeclDoc
+			for _, f := range fs {
+				name := f.Name
+				if recv != "" {
+					// the methods of the types can share their names
+					name = recv + "." + f.Name
+				}
+				dds = append(dds, DeclDoc{Name: name, Decl: decl(f.Decl), Doc: html(f.Doc)})
+			}
+			return dds
+		}
+	)
+	pd := PackageDoc{
+		ImportPath: importPath,
+		Name:       pkg.Name,
+		Synopsis:   pkg.Synopsis(pkg.Doc),
+		Doc:        html(pkg.Doc),
+		Consts:     values(pkg.Consts),
+		Vars:       values(pkg.Vars),
+		Funcs:      funcs("", pkg.Funcs),
+	}
+	for _, t := range pkg.Types {
+		pd.Types = append(pd.Types, TypeDoc{
+			DeclDoc: DeclDoc{Name: t.Name, Decl: decl(t.Decl), Doc: html(t.Doc)},
+			Consts:  values(t.Consts),
+			Vars:    values(t.Vars),
+			Funcs:   funcs("", t.Funcs),
+			Methods: funcs(t.Name, t.Methods),
+		})
+	}
+	return &pd, nil
+}
+
+// apiDocsPage is the data of an API documentation page.
+type apiDocsPage struct {
+	PackageDoc
+	// Packages are the other packages of the module, which are listed on the page of the module root.
+	Packages []apiDocsLink
+}
+
+// apiDocsLink is a package in the listing of a module's packages.
+type apiDocsLink struct {
+	ImportPath string
+	Synopsis   string
+	// URL is the path of the package's documentation page on the site.
+	URL string
+}
+
+// renderAPIDocs renders the documentation pages of a module's packages, under the docs directory of the module's page.
+// The page of the module root documents its root package, and it lists the other packages of the module.
+func renderAPIDocs(tmpl *template.Template, domain string, meta Meta, modulePath string) ([]File, error) {
+	pkgs, ok := meta.APIDocs[modulePath]
+	if !ok {
+		return nil, nil
+	}
+	_, basePath := splitDomain(domain)
+	var (
+		docsDir = path.Join(path.Dir(pagePath(domain, modulePath)), apiDocsDir)
+		root    = apiDocsPage{PackageDoc: PackageDoc{ImportPath: modulePath}}
+		pages   []apiDocsPage
+	)
+	for _, pkg := range pkgs {
+		if pkg.ImportPath == modulePath {
+			root.PackageDoc = pkg
+			continue
+		}
+		root.Packages = append(root.Packages, apiDocsLink{
+			ImportPath: pkg.ImportPath,
+			Synopsis:   pkg.Synopsis,
+			URL:        basePath + "/" + path.Join(docsDir, apiDocsRelPath(modulePath, pkg.ImportPath)) + "/",
+		})
+		pages = append(pages, apiDocsPage{PackageDoc: pkg})
+	}
+	pages = append([]apiDocsPage{root}, pages...)
+
+	var files []File
+	for _, page := range pages {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "api-docs", page); err != nil {
+			return nil, fmt.Errorf("API documentation template execution failed: %w", err)
+		}
+		files = append(files, File{
+			Path:    path.Join(docsDir, apiDocsRelPath(modulePath, page.ImportPath), "index.html"),
+			Content: buf.Bytes(),
+			Module:  meta.Import.Prefix,
+		})
+	}
+	return files, nil
+}
+
+// apiDocsRelPath is the path of a package's documentation page within the docs directory of its module.
+func apiDocsRelPath(modulePath, importPath string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(importPath, modulePath), "/")
+}
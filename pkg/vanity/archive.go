@@ -0,0 +1,110 @@
+package vanity
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// Archive formats of the site.
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// archiveTime is the modification time of every archived file.
+// It is the earliest time that a zip file can represent.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveFormat tells the archive format of a file by its extension, like site.tar.gz or site.zip.
+func ArchiveFormat(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	default:
+		return "", fmt.Errorf("unknown archive format of %s, use a .tar.gz, .tgz or .zip file", name)
+	}
+}
+
+// WriteArchive packages the site's directory into a tar.gz or zip archive.
+// The archive is deterministic, so the same site gives the same bytes:
+// the files are in lexical order, with the same modification time and permissions, and without an owner.
+// The .git directories are left out, like in a deploy.
+func WriteArchive(w io.Writer, site fs.FS, format string) error {
+	switch format {
+	case ArchiveTarGz:
+		// the zero header of gzip has neither a name nor a modification time
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		err := walkArchive(site, func(name string, isDir bool, content []byte) error {
+			hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: archiveTime, Typeflag: tar.TypeReg}
+			if isDir {
+				hdr = &tar.Header{Name: name + "/", Mode: 0o755, ModTime: archiveTime, Typeflag: tar.TypeDir}
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := tw.Write(content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	case ArchiveZip:
+		zw := zip.NewWriter(w)
+		err := walkArchive(site, func(name string, isDir bool, content []byte) error {
+			hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archiveTime}
+			hdr.SetMode(0o644)
+			if isDir {
+				hdr = &zip.FileHeader{Name: name + "/", Method: zip.Store, Modified: archiveTime}
+				hdr.SetMode(fs.ModeDir | 0o755)
+			}
+			fw, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return zw.Close()
+	default:
+		return fmt.Errorf("unknown archive format: %s", format)
+	}
+}
+
+// walkArchive calls fn with the directories and the files of the site in lexical order.
+func walkArchive(site fs.FS, fn func(name string, isDir bool, content []byte) error) error {
+	return fs.WalkDir(site, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return fn(name, true, nil)
+		}
+		content, err := fs.ReadFile(site, name)
+		if err != nil {
+			return err
+		}
+		return fn(name, false, content)
+	})
+}
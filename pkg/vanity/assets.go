@@ -0,0 +1,64 @@
+package vanity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// assets are the static files of the site, with the paths of their fingerprinted copies.
+type assets struct {
+	files []File
+	// fingerprints are the paths of the fingerprinted copies, by the path of the asset.
+	fingerprints map[string]string
+	// basePath is the path that the site is hosted under, which the URLs of the assets start with.
+	basePath string
+}
+
+// readAssets reads every file of the assets directory, which are copied into the site as they are,
+// and with a fingerprinted copy too, whose name carries the hash of its content, like css/site.1a2b3c4d.css.
+// The fingerprinted copies can be cached forever, since their URL changes with their content.
+func readAssets(fsys fs.FS, basePath string) (assets, error) {
+	as := assets{fingerprints: map[string]string{}, basePath: basePath}
+	if fsys == nil {
+		return as, nil
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		fingerprinted := fingerprintPath(name, content)
+		as.files = append(as.files,
+			File{Path: name, Content: content},
+			File{Path: fingerprinted, Content: content})
+		as.fingerprints[name] = fingerprinted
+		return nil
+	})
+	if err != nil {
+		return assets{}, fmt.Errorf("failed to read the assets: %w", err)
+	}
+	return as, nil
+}
+
+// URL tells the URL path of an asset's fingerprinted copy, by the asset's path within the assets directory.
+func (as assets) URL(name string) (string, error) {
+	fingerprinted, ok := as.fingerprints[strings.TrimPrefix(path.Clean(name), "/")]
+	if !ok {
+		return "", fmt.Errorf("%s is not in the assets directory", name)
+	}
+	return as.basePath + "/" + fingerprinted, nil
+}
+
+// fingerprintPath puts the hash of the content between the name and the extension of the file.
+func fingerprintPath(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+}
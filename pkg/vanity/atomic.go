@@ -0,0 +1,112 @@
+package vanity
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+)
+
+// writeDirAtomic writes a staging copy of the output directory,
+// and only when the write succeeded, it swaps the staging directory into the place of the output directory.
+// This way a failure in the middle of the run never leaves the published site half-updated.
+func writeDirAtomic(outDirPath string, write func(dirPath string) error) (rErr error) {
+	outDirPath = filepath.Clean(outDirPath)
+	staging, err := os.MkdirTemp(filepath.Dir(outDirPath), "."+filepath.Base(outDirPath)+".staging-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	// after the swap, the staging directory holds the previous content of the output directory
+	defer func() { rErr = errorkit.Merge(rErr, os.RemoveAll(staging)) }()
+
+	info, err := os.Stat(outDirPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.Chmod(staging, 0755); err != nil {
+			return err
+		}
+		if err := write(staging); err != nil {
+			return err
+		}
+		return os.Rename(staging, outDirPath)
+	case err != nil:
+		return err
+	}
+
+	if err := os.Chmod(staging, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := copyDir(outDirPath, staging); err != nil {
+		return fmt.Errorf("failed to prepare staging directory: %w", err)
+	}
+	if err := write(staging); err != nil {
+		return err
+	}
+	return swapDirs(staging, outDirPath)
+}
+
+// renameSwap swaps two directories with two renames.
+// It is the fallback when the platform can't exchange them in a single step.
+func renameSwap(a, b string) error {
+	tmp := a + ".swap"
+	if err := os.Rename(b, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(a, b); err != nil {
+		return errorkit.Merge(err, os.Rename(tmp, b))
+	}
+	return os.Rename(tmp, a)
+}
+
+// copyDir copies the content of a directory, while it keeps the file modes and modification times,
+// so the unchanged files look the same after the swap.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chtimes(target, info.ModTime(), info.ModTime())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) (rErr error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, out.Close()) }()
+	_, err = io.Copy(out, in)
+	return err
+}
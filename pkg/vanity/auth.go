@@ -0,0 +1,82 @@
+package vanity
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Credentials are the credentials that grant access to the private modules.
+// The go command sends them from the ~/.netrc file as basic auth,
+// while other clients, like a GOPROXY in front of the server, can use a bearer token.
+type Credentials struct {
+	// Tokens are the accepted bearer tokens.
+	Tokens []string
+	// Users are the accepted basic auth passwords by user name.
+	Users map[string]string
+}
+
+// Allow tells whether the request carries any of the accepted credentials.
+func (c *Credentials) Allow(r *http.Request) bool {
+	if c == nil {
+		return false
+	}
+	if user, password, ok := r.BasicAuth(); ok {
+		expected, ok := c.Users[user]
+		return ok && secureEqual(password, expected)
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	var allowed bool
+	for _, t := range c.Tokens {
+		// every token is compared, so the response time doesn't tell which one is close
+		allowed = secureEqual(token, t) || allowed
+	}
+	return allowed
+}
+
+// ReadCredentials reads a credentials file.
+// Every line is either a "user:password" pair for basic auth, or a bearer token.
+// Empty lines and lines starting with # are ignored.
+func ReadCredentials(path string) (Credentials, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to open credentials file: %w", err)
+	}
+	defer f.Close()
+
+	creds := Credentials{Users: map[string]string{}}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if user, password, ok := strings.Cut(line, ":"); ok {
+			creds.Users[user] = password
+			continue
+		}
+		creds.Tokens = append(creds.Tokens, line)
+	}
+	return creds, scanner.Err()
+}
+
+// authorize lets the request through when it is authenticated,
+// otherwise it asks the client for credentials.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.Auth.Allow(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", s.Domain))
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return false
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
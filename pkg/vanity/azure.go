@@ -0,0 +1,161 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// azureAPIVersion is the version of the Blob service REST API that the requests use.
+const azureAPIVersion = "2021-08-06"
+
+// AzureBucket is a container of an Azure Blob Storage account,
+// which is accessed with a shared access signature (SAS) token.
+type AzureBucket struct {
+	// Account is the name of the storage account.
+	Account string
+	// Container is the name of the container.
+	Container string
+	// Prefix is the name prefix of the site's blobs within the container, like a directory.
+	Prefix string
+	// SASToken is the shared access signature of the container,
+	// which allows listing, writing and deleting its blobs, like sv=...&sig=...
+	SASToken string
+	// Endpoint is the address of the Blob service, like the one of a sovereign cloud or a local emulator.
+	//
+	// default: https://<account>.blob.core.windows.net
+	Endpoint string
+	// Client is the HTTP client of the requests.
+	//
+	// default: a client that retries the temporary failures
+	Client *http.Client
+}
+
+type azureEnumerationResultsDTO struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentMD5 string `xml:"Content-MD5"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// List lists the blobs under the prefix, with the Content-MD5 that they were uploaded with.
+func (b AzureBucket) List(ctx context.Context) (map[string]string, error) {
+	var (
+		blobs  = map[string]string{}
+		prefix = b.namePrefix()
+		marker string
+	)
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result azureEnumerationResultsDTO
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("azure: failed to decode the blob listing: %w", err)
+		}
+		for _, blob := range result.Blobs {
+			sum, _ := base64.StdEncoding.DecodeString(blob.Properties.ContentMD5)
+			blobs[strings.TrimPrefix(blob.Name, prefix)] = hex.EncodeToString(sum)
+		}
+		if result.NextMarker == "" {
+			return blobs, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (b AzureBucket) Put(ctx context.Context, obj Object) error {
+	sum := md5.Sum(obj.Content)
+	header := http.Header{}
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	header.Set("X-Ms-Blob-Content-Type", obj.ContentType)
+	header.Set("X-Ms-Blob-Cache-Control", obj.CacheControl)
+	// the service stores the hash of the content, which the listing tells the unchanged blobs by
+	header.Set("X-Ms-Blob-Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.do(ctx, http.MethodPut, b.namePrefix()+obj.Key, nil, obj.Content, header)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (b AzureBucket) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, b.namePrefix()+key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (b AzureBucket) namePrefix() string {
+	if prefix := strings.Trim(b.Prefix, "/"); prefix != "" {
+		return prefix + "/"
+	}
+	return ""
+}
+
+// do sends a request to the container, or to a blob of it by its name, authorized by the SAS token.
+func (b AzureBucket) do(ctx context.Context, method, name string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	endpoint := strings.TrimSuffix(zerokit.Coalesce(b.Endpoint, "https://"+b.Account+".blob.core.windows.net"), "/")
+	rawURL := endpoint + "/" + url.PathEscape(b.Container)
+	if name != "" {
+		rawURL += "/" + (&url.URL{Path: name}).EscapedPath()
+	}
+	params := query.Encode()
+	if token := strings.TrimPrefix(b.SASToken, "?"); token != "" {
+		params = strings.TrimPrefix(params+"&"+token, "&")
+	}
+	if params != "" {
+		rawURL += "?" + params
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+
+	resp, err := zerokit.Coalesce(b.Client, discoveryClient()).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", redactSAS(err, b.SASToken))
+	}
+	if resp.StatusCode < 200 || 299 < resp.StatusCode {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("azure: %s %s/%s: unexpected status code: %s\n%s", method, b.Container, name, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// redactSAS keeps the SAS token out of the error of a failed request, since the error quotes the URL.
+func redactSAS(err error, token string) error {
+	if token == "" {
+		return err
+	}
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), strings.TrimPrefix(token, "?"), "REDACTED"))
+}
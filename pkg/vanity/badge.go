@@ -0,0 +1,104 @@
+package vanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"unicode/utf8"
+)
+
+// badgeFileName is the name of a module's badge, next to the module's page.
+const badgeFileName = "badge.svg"
+
+// renderBadge renders the badge of an import path, which shows its latest release,
+// or links to its reference documentation when it has no release yet.
+func renderBadge(domain string, meta Meta, importPath string) File {
+	label, message := "go", "reference"
+	if release, ok := meta.Releases[importPath]; ok {
+		message = release.Version
+	}
+	return File{
+		Path:    path.Join(path.Dir(pagePath(domain, importPath)), badgeFileName),
+		Content: []byte(badgeSVG(label, message, "#007d9c")),
+		Module:  meta.Import.Prefix,
+	}
+}
+
+// shieldsEndpointDTO is the response of a shields.io endpoint badge.
+// https://shields.io/badges/endpoint-badge
+type shieldsEndpointDTO struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// renderShields renders the shields.io endpoint files of an import path,
+// badge.json with its latest release, and go-version.json with the Go version that the release requires.
+// Before the first release, the files tell that the version is unknown.
+func renderShields(domain string, meta Meta, importPath string) ([]File, error) {
+	release := meta.Releases[importPath]
+	endpoints := []struct {
+		name string
+		dto  shieldsEndpointDTO
+	}{
+		{name: "badge.json", dto: shieldsEndpointDTO{Label: "version", Message: release.Version, Color: "blue"}},
+		{name: "go-version.json", dto: shieldsEndpointDTO{Label: "go", Message: release.GoVersion, Color: "00add8"}},
+	}
+	var files []File
+	for _, endpoint := range endpoints {
+		endpoint.dto.SchemaVersion = 1
+		if endpoint.dto.Message == "" {
+			endpoint.dto.Message, endpoint.dto.Color = "unknown", "lightgrey"
+		}
+		content, err := json.Marshal(endpoint.dto)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Path:    path.Join(path.Dir(pagePath(domain, importPath)), endpoint.name),
+			Content: append(content, '\n'),
+			Module:  meta.Import.Prefix,
+		})
+	}
+	return files, nil
+}
+
+// badgeSVG draws a flat badge, like the ones of shields.io.
+// The text widths are estimated, as there is no font at hand to measure them.
+func badgeSVG(label, message, color string) string {
+	var (
+		labelWidth   = textWidth(label)
+		messageWidth = textWidth(message)
+		width        = labelWidth + messageWidth
+		title        = template.HTMLEscapeString(label + ": " + message)
+	)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s">
+  <title>%[2]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r">
+    <rect width="%[1]d" height="20" rx="3" fill="#fff"/>
+  </clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[3]d" height="20" fill="#555"/>
+    <rect x="%[3]d" width="%[4]d" height="20" fill="%[5]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[6]d" y="14">%[7]s</text>
+    <text x="%[8]d" y="14">%[9]s</text>
+  </g>
+</svg>
+`, width, title, labelWidth, messageWidth, template.HTMLEscapeString(color),
+		labelWidth/2, template.HTMLEscapeString(label),
+		labelWidth+messageWidth/2, template.HTMLEscapeString(message))
+}
+
+// textWidth estimates the width of a badge text with its padding, in pixels.
+func textWidth(text string) int {
+	return utf8.RuneCountInString(text)*7 + 10
+}
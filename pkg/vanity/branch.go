@@ -0,0 +1,135 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// BranchDetector tells the default branch of the repositories,
+// so the go-source links point to the branch the repository actually uses, rather than to master.
+type BranchDetector struct {
+	// CacheFile keeps the detected branches between the runs.
+	// The cached branch is also the fallback when the repository can't be reached.
+	CacheFile string
+	// TTL is how long a cached branch is used without asking the repository again.
+	TTL time.Duration
+	// Refresh lists the repositories whose branch is detected again regardless of the TTL,
+	// like the ones that were just pushed to.
+	Refresh []string
+}
+
+type branchCacheEntryDTO struct {
+	Branch    string    `json:"branch"`
+	CheckedAt time.Time `json:"checked-at"`
+}
+
+// DetectBranches sets the branch of the entries whose go-source patterns are derived from it,
+// unless the entry configures its branch explicitly.
+func (d BranchDetector) DetectBranches(ctx context.Context, dtos []ImportDTO) []ImportDTO {
+	cache := d.readCache()
+	var changed bool
+	for i, dto := range dtos {
+		if dto.Branch != "" || (dto.DirectoryPattern != "" && dto.FilePattern != "") {
+			continue
+		}
+		repoURL := dto.RootRepo
+		switch {
+		case dto.VCS == VCSMod && dto.Origin != "":
+			repoURL = dto.Origin
+		case dto.VCS != VCSGit:
+			// the default branch of a Mercurial repository is always named default,
+			// and the others have no branches to detect
+			continue
+		}
+		cached, ok := cache[repoURL]
+		if ok && time.Since(cached.CheckedAt) < d.TTL && !d.refreshes(repoURL) {
+			dtos[i].Branch = cached.Branch
+			continue
+		}
+		branch, err := detectBranch(ctx, repoURL)
+		if err != nil {
+			// offline, the last known branch is better than none
+			logger.Warn(ctx, "default branch detection failed",
+				logging.Field("repo", repoURL),
+				logging.Field("fallback", cached.Branch),
+				logging.ErrField(err))
+			dtos[i].Branch = cached.Branch
+			continue
+		}
+		dtos[i].Branch = branch
+		cache[repoURL] = branchCacheEntryDTO{Branch: branch, CheckedAt: time.Now().UTC()}
+		changed = true
+	}
+	if changed {
+		if err := d.writeCache(cache); err != nil {
+			logger.Warn(ctx, "default branch cache write failed", logging.ErrField(err))
+		}
+	}
+	return dtos
+}
+
+func (d BranchDetector) refreshes(repoURL string) bool {
+	for _, u := range d.Refresh {
+		if repoKey(u) == repoKey(repoURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectBranch asks the repository where its HEAD points to.
+func detectBranch(ctx context.Context, repoURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	out, err := git(ctx, "", "ls-remote", "--symref", repoURL, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// ref: refs/heads/main	HEAD
+		if ref, ok := strings.CutPrefix(line, "ref: refs/heads/"); ok {
+			if branch, _, ok := strings.Cut(ref, "\t"); ok {
+				return branch, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("the HEAD of %s is not a branch", repoURL)
+}
+
+func (d BranchDetector) readCache() map[string]branchCacheEntryDTO {
+	cache := map[string]branchCacheEntryDTO{}
+	if d.CacheFile == "" {
+		return cache
+	}
+	data, err := os.ReadFile(d.CacheFile)
+	if err != nil {
+		return cache
+	}
+	// a broken cache is just detected again
+	_ = json.Unmarshal(data, &cache)
+	return cache
+}
+
+func (d BranchDetector) writeCache(cache map[string]branchCacheEntryDTO) error {
+	if d.CacheFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.CacheFile), 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return os.WriteFile(d.CacheFile, data, 0644)
+}
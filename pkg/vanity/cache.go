@@ -0,0 +1,36 @@
+package vanity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// headersFileName is the name of the custom headers file that static hosts like Netlify or Cloudflare Pages read.
+const headersFileName = "_headers"
+
+// cacheControl is the Cache-Control header value of the pages.
+// Without a max-age, the clients have to revalidate the pages on every use.
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// etag is a strong entity tag of the content, so it is stable across restarts and replicas.
+func etag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// renderHeaders renders the custom headers file of the static site, whose rules match the pages under the base path.
+// The file sets the Content-Security-Policy only when it's configured, since its single rule can't tell the pages apart.
+func renderHeaders(basePath string, maxAge time.Duration, security SecurityHeaders) File {
+	return File{
+		Path: headersFileName,
+		Content: []byte(fmt.Sprintf("%s/*\n  Cache-Control: %s\n%s",
+			basePath, cacheControl(maxAge), security.headerRules(security.sitePolicy()))),
+	}
+}
@@ -0,0 +1,131 @@
+package vanity
+
+import (
+	"context"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// changelogNames are the file names of a changelog, in the order of their preference.
+var changelogNames = []string{"CHANGELOG.md", "changelog.md", "CHANGES.md", "Changelog.md"}
+
+// FetchChangelogs sets the latest entries of the changelogs of the modules and their nested modules,
+// from the default branch of their git and Mercurial repositories, rendered like their READMEs.
+// An entry is a section of the changelog under a second level heading, like ## v1.2.0,
+// or under a first level heading when the changelog has no second level headings,
+// and the entry of the unreleased changes is left out.
+// The number of the entries defaults to 3.
+// A repository that can't be reached is only warned about, and its modules are left without a changelog.
+func FetchChangelogs(ctx context.Context, metas []Meta, entries int) []Meta {
+	if entries < 1 {
+		entries = 3
+	}
+	for i, meta := range metas {
+		if meta.Private || meta.Tombstone.Kind != "" || !isClonable(meta.Import.VCS.SourceRepo().Name) {
+			continue
+		}
+		changelogs, err := fetchChangelogs(ctx, meta, entries)
+		if err != nil {
+			logger.Warn(ctx, "changelog fetching failed",
+				logging.Field("module", meta.Import.Prefix),
+				logging.ErrField(err))
+			continue
+		}
+		metas[i].Changelogs = changelogs
+	}
+	return metas
+}
+
+func fetchChangelogs(ctx context.Context, meta Meta, entries int) (_ map[string]template.HTML, rErr error) {
+	repo := meta.Import.VCS.SourceRepo()
+	clone, err := cloneRepo(ctx, repo.Name, repo.RepoRoot.String())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	dirs := map[string]string{meta.Import.Prefix: ""}
+	for _, nested := range meta.Nested {
+		dirs[nested.ImportPath] = nested.Path
+	}
+	changelogs := map[string]template.HTML{}
+	for importPath, dir := range dirs {
+		for _, name := range changelogNames {
+			data, err := clone.ReadFile(ctx, path.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			latest := latestChangelogEntries(data, entries)
+			if len(latest) == 0 {
+				break
+			}
+			changelog, err := renderMarkdown(latest)
+			if err != nil {
+				return nil, err
+			}
+			changelogs[importPath] = changelog
+			break
+		}
+	}
+	return changelogs, nil
+}
+
+// changelogLinkRef matches a link reference definition of Markdown, like [1.2.0]: https://github.com/org/repo/compare/v1.1.0...v1.2.0
+var changelogLinkRef = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S+`)
+
+// latestChangelogEntries cuts the first entries of the Markdown changelog,
+// without its title and the entry of the unreleased changes.
+// The link reference definitions of the changelog are kept, since the headings of the entries often use them.
+func latestChangelogEntries(source []byte, n int) []byte {
+	var (
+		lines    = strings.Split(strings.ReplaceAll(string(source), "\r\n", "\n"), "\n")
+		headings = map[int]int{}
+		level    = 1
+		fenced   bool
+	)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+		}
+		switch {
+		case fenced:
+		case strings.HasPrefix(line, "# "):
+			headings[i] = 1
+		case strings.HasPrefix(line, "## "):
+			headings[i] = 2
+			// the entries are under the second level headings, unless the changelog has none
+			level = 2
+		}
+	}
+
+	var (
+		out, refs []string
+		taking    bool
+	)
+	for i, line := range lines {
+		if l, ok := headings[i]; ok && l <= level {
+			title := strings.ToLower(strings.Trim(line, "# []"))
+			taking = l == level && 0 < n && !strings.Contains(title, "unreleased") && title != "changelog" && title != "changes"
+			if taking {
+				n--
+			}
+		}
+		if _, ok := headings[i]; !ok && changelogLinkRef.MatchString(line) {
+			refs = append(refs, line)
+			continue
+		}
+		if taking {
+			out = append(out, line)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(append(append(out, ""), refs...), "\n"))
+}
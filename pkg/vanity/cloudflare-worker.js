@@ -0,0 +1,27 @@
+// The worker of the Cloudflare Pages site, generated from the imports file.
+// It answers the go-get requests of the packages under the modules with the page of their module,
+// and sends the browser visitors of the module pages to their redirect targets.
+const modules = {{ . }};
+
+export default {
+  async fetch(request, env) {
+    const url = new URL(request.url);
+    const path = url.pathname.replace(/\/+$/, "") || "/";
+    const module = modules.find((m) => path === m.path || path.startsWith(m.path.replace(/\/$/, "") + "/"));
+    if (!module) {
+      return env.ASSETS.fetch(request);
+    }
+    if (url.searchParams.get("go-get") === "1") {
+      return env.ASSETS.fetch(new URL(module.page, url));
+    }
+    if (path === module.path) {
+      return module.redirect ? Response.redirect(module.redirect, 302) : env.ASSETS.fetch(request);
+    }
+    // the files next to the page, like its badge and API documentation, are still served
+    const response = await env.ASSETS.fetch(request);
+    if (response.status === 404 && module.packageRedirect) {
+      return Response.redirect(module.packageRedirect.replace(":splat", path.slice(module.path.length + 1)), 302);
+    }
+    return response;
+  },
+};
@@ -0,0 +1,135 @@
+package vanity
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// cloudflareWorkerFileName is the worker of the Pages Functions advanced mode, which is read from the output directory.
+	cloudflareWorkerFileName = "_worker.js"
+	// cloudflareRoutesFileName tells which requests invoke the worker, and which are served as static files right away.
+	cloudflareRoutesFileName = "_routes.json"
+	// cloudflareMaxRoutes is the number of rules that Cloudflare Pages reads from the _routes.json file.
+	cloudflareMaxRoutes = 100
+)
+
+//go:embed cloudflare-worker.js
+var cloudflareWorkerJS string
+
+// Cloudflare is the output plugin of the sites deployed to Cloudflare Pages, an alternative to Netlify.
+//
+// Cloudflare Pages follows the rules of the _redirects file even when a file exists at their path,
+// and the rules can't match the go-get query parameter, so the _redirects file leaves the module pages as they are.
+// It answers the go-get requests of the packages known from the API documentation with the page of their module.
+// The _headers file sets the Cache-Control of the site by the MaxAge option, and the SecurityHeaders option, with a single rule,
+// since Cloudflare Pages joins the values of the rules that match the same path.
+// For the same reason, the Content-Security-Policy is only set when it's configured, rather than per page.
+//
+// With the Worker option, the _worker.js of the Pages Functions advanced mode handles the requests of the module pages dynamically.
+// It answers the go-get requests of every package under a module with the page of the module,
+// and sends the browser visitors of the module pages to their redirect targets,
+// with the same exceptions as the _redirects file of Netlify.
+type Cloudflare struct {
+	// Worker adds the _worker.js of the Pages Functions advanced mode, with a _routes.json, which limits it to the module pages.
+	Worker bool
+}
+
+func (c Cloudflare) Render(site Site) ([]File, error) {
+	_, basePath := splitDomain(site.Domain)
+	files := []File{{
+		Path: headersFileName,
+		Content: []byte(fmt.Sprintf("%s/*\n  Cache-Control: %s\n%s", basePath, cacheControl(site.Options.MaxAge),
+			site.Options.SecurityHeaders.headerRules(site.Options.SecurityHeaders.sitePolicy()))),
+	}}
+
+	var redirects strings.Builder
+	for _, p := range modulePages(site) {
+		urlPaths := pageURLPaths(basePath, pagePath(site.Domain, p.importPath))
+		for _, pkg := range p.meta.APIDocs[p.importPath] {
+			if pkg.ImportPath == p.importPath {
+				continue
+			}
+			// a rule per package, since a splat would serve the page of the module in place of the files next to it too
+			from := pageURLPaths(basePath, pagePath(site.Domain, pkg.ImportPath))[0]
+			fmt.Fprintln(&redirects, redirectRule{From: from, To: urlPaths[len(urlPaths)-1], Status: 200})
+		}
+	}
+	if redirects.Len() > 0 {
+		files = append(files, File{Path: redirectsFileName, Content: []byte(redirects.String())})
+	}
+
+	if c.Worker {
+		worker, routes, err := renderCloudflareWorker(site)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, worker, routes)
+	}
+	return files, nil
+}
+
+// cloudflareWorkerModule is a module page in the table of the worker.
+type cloudflareWorkerModule struct {
+	// Path is the URL path of the page, without the trailing slash.
+	Path string `json:"path"`
+	// Page is the URL path that the page's file is served at.
+	Page string `json:"page"`
+	// Redirect is where the browser visitors of the page are sent, if anywhere.
+	Redirect string `json:"redirect,omitempty"`
+	// PackageRedirect is where the browser visitors of the packages under the page are sent,
+	// with the :splat placeholder in place of the package's path within the module.
+	PackageRedirect string `json:"packageRedirect,omitempty"`
+}
+
+// renderCloudflareWorker renders the worker with the table of the module pages,
+// and the routes of the module pages, which invoke it.
+func renderCloudflareWorker(site Site) (File, File, error) {
+	_, basePath := splitDomain(site.Domain)
+	var (
+		pages   = modulePages(site)
+		modules []cloudflareWorkerModule
+		include []string
+	)
+	for _, p := range pages {
+		urlPaths := pageURLPaths(basePath, pagePath(site.Domain, p.importPath))
+		module := cloudflareWorkerModule{Path: urlPaths[0], Page: urlPaths[len(urlPaths)-1]}
+		if redirectsVisitors(p.meta, site.Options) {
+			module.Redirect = p.meta.RedirectURL(p.importPath)
+			if module.Redirect != "" && p.importPath != site.Domain {
+				module.PackageRedirect = p.meta.RedirectURL(p.importPath + "/:splat")
+			}
+		}
+		modules = append(modules, module)
+
+		// the pages under another module's page are covered by the route of the other page
+		nested := false
+		for _, parent := range pages {
+			nested = nested || strings.HasPrefix(p.importPath, parent.importPath+"/")
+		}
+		if !nested {
+			include = append(include, urlPaths[0], strings.TrimSuffix(urlPaths[0], "/")+"/*")
+		}
+	}
+	if len(include) > cloudflareMaxRoutes {
+		include = []string{"/*"}
+	}
+
+	table, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		return File{}, File{}, err
+	}
+	routes, err := json.MarshalIndent(struct {
+		Version int      `json:"version"`
+		Include []string `json:"include"`
+		Exclude []string `json:"exclude"`
+	}{Version: 1, Include: include, Exclude: []string{}}, "", "  ")
+	if err != nil {
+		return File{}, File{}, err
+	}
+	return File{Path: cloudflareWorkerFileName, Content: []byte(strings.Replace(cloudflareWorkerJS, "{{ . }}", string(table), 1))},
+		File{Path: cloudflareRoutesFileName, Content: append(routes, '\n')},
+		nil
+}
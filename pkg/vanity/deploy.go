@@ -0,0 +1,138 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"sort"
+	"time"
+)
+
+// Bucket is an object storage bucket that the site can be deployed to, like an S3, GCS or Azure Blob container.
+type Bucket interface {
+	// List tells the hex encoded MD5 hash of the objects' content, by their keys.
+	List(ctx context.Context) (map[string]string, error)
+	// Put uploads an object, or replaces it.
+	Put(ctx context.Context, obj Object) error
+	// Delete removes an object.
+	Delete(ctx context.Context, key string) error
+}
+
+// Object is a file of the site in a bucket.
+type Object struct {
+	Key          string
+	Content      []byte
+	ContentType  string
+	CacheControl string
+}
+
+// DeployOptions configure how the site is synced to a bucket.
+type DeployOptions struct {
+	// MaxAge is how long the clients may cache the objects.
+	MaxAge time.Duration
+	// Delete removes the objects that are no longer part of the site.
+	Delete bool
+	// DryRun only plans the changes, without touching the bucket.
+	DryRun bool
+}
+
+// Deploy syncs the site's directory to the bucket, without an intermediate git repository.
+// The new and changed files are uploaded with their Content-Type and Cache-Control,
+// the unchanged ones are skipped by the MD5 hash of their content,
+// and with the Delete option, the objects that are not in the directory anymore are removed.
+// It returns the changes of the bucket.
+func Deploy(ctx context.Context, site fs.FS, bucket Bucket, opts DeployOptions) ([]Change, error) {
+	remote, err := bucket.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the bucket: %w", err)
+	}
+
+	var changes []Change
+	err = fs.WalkDir(site, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		content, err := fs.ReadFile(site, name)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(content)
+		hash, ok := remote[name]
+		delete(remote, name)
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeCreate, File: File{Path: name, Content: content}})
+		case hash == hex.EncodeToString(sum[:]):
+			changes = append(changes, Change{Kind: ChangeUnchanged, File: File{Path: name, Content: content}})
+		default:
+			changes = append(changes, Change{Kind: ChangeUpdate, File: File{Path: name, Content: content}})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the site: %w", err)
+	}
+	if opts.Delete {
+		var orphans []string
+		for key := range remote {
+			orphans = append(orphans, key)
+		}
+		sort.Strings(orphans)
+		for _, key := range orphans {
+			changes = append(changes, Change{Kind: ChangeDelete, File: File{Path: key}})
+		}
+	}
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeCreate, ChangeUpdate:
+			err = bucket.Put(ctx, Object{
+				Key:          change.File.Path,
+				Content:      change.File.Content,
+				ContentType:  contentType(change.File.Path, change.File.Content),
+				CacheControl: cacheControl(opts.MaxAge),
+			})
+		case ChangeDelete:
+			err = bucket.Delete(ctx, change.File.Path)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", change.File.Path, err)
+		}
+	}
+	return changes, nil
+}
+
+// contentType tells the Content-Type of a file of the site by its extension.
+// The files without an extension, like the CNAME and the _headers file, are plain text.
+func contentType(name string, content []byte) string {
+	ext := path.Ext(name)
+	switch {
+	case ext == ".html":
+		return "text/html; charset=utf-8"
+	case ext == "":
+		if bytes.IndexByte(content, 0) < 0 {
+			return "text/plain; charset=utf-8"
+		}
+		return "application/octet-stream"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,124 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/httpkit"
+	"go.llib.dev/frameless/pkg/retry"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// Repository is a Go module repository found on a code host.
+type Repository struct {
+	// Name is the name of the repository, which the import prefix is derived from.
+	Name string
+	// URL is the repository root URL.
+	URL string
+	// VCS is the version control system of the repository.
+	//
+	// default: git
+	VCS string
+	// Homepage is the web page of the repository, when it differs from the URL.
+	Homepage string
+	// DirectoryPattern and FilePattern are the go-source patterns of the code host,
+	// when the code host knows them better than the defaults.
+	DirectoryPattern string
+	FilePattern      string
+}
+
+// Discoverer finds the repositories with a go.mod file on a code host.
+type Discoverer interface {
+	Discover(ctx context.Context) ([]Repository, error)
+}
+
+// ImportPrefix is the import prefix of a module named by its repository under the vanity domain.
+// Under a wildcard domain, like *.llib.dev, the module is served on its subdomain, like foo.llib.dev.
+func ImportPrefix(domain, name string) string {
+	if parent, ok := strings.CutPrefix(domain, wildcardPrefix); ok {
+		return strings.ToLower(name) + "." + parent
+	}
+	return domain + "/" + name
+}
+
+// DiscoverImports finds the module repositories, and turns them into imports file entries.
+// The import prefix of a module is the domain followed by the repository name, e.g. go.llib.dev/testcase,
+// or the repository's subdomain of a wildcard domain, e.g. testcase.llib.dev
+func DiscoverImports(ctx context.Context, d Discoverer, domain string) ([]ImportDTO, error) {
+	repos, err := d.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dtos []ImportDTO
+	for _, repo := range repos {
+		dtos = append(dtos, ImportDTO{
+			VCS:          zerokit.Coalesce(repo.VCS, VCSGit),
+			ImportPrefix: ImportPrefix(domain, repo.Name),
+			RootRepo:     repo.URL,
+			HomepageURL:  repo.Homepage,
+			// the patterns are only set when the code host knows them
+			DirectoryPattern: repo.DirectoryPattern,
+			FilePattern:      repo.FilePattern,
+		})
+	}
+	return dtos, nil
+}
+
+// discoveryClient is the HTTP client of the code host APIs,
+// which retries the temporary failures, like the fetching of a remote imports file does.
+func discoveryClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: httpkit.RetryRoundTripper{
+			RetryStrategy: retry.ExponentialBackoff{MaxRetries: 3},
+		},
+	}
+}
+
+// apiGet sends a GET request to a code host API.
+// A missing resource is reported as ErrNotFound, so the callers can tell it apart from a failure.
+func apiGet(ctx context.Context, client *http.Client, endpoint string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := zerokit.Coalesce(client, discoveryClient()).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status code: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextLink is the URL of the next page of a paginated API response, or empty on the last page.
+func nextLink(resp *http.Response) string {
+	if m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// drain reads the rest of the response body, so the connection can be reused.
+func drain(resp *http.Response) error {
+	defer resp.Body.Close()
+	_, err := io.Copy(io.Discard, resp.Body)
+	return err
+}
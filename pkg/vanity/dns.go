@@ -0,0 +1,122 @@
+package vanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// GitHubPagesIPs are the addresses of the GitHub Pages servers, which the A and AAAA records of an apex domain point to.
+var GitHubPagesIPs = []string{
+	"185.199.108.153",
+	"185.199.109.153",
+	"185.199.110.153",
+	"185.199.111.153",
+	"2606:50c0:8000::153",
+	"2606:50c0:8001::153",
+	"2606:50c0:8002::153",
+	"2606:50c0:8003::153",
+}
+
+// DNSOptions configure the DNS check of a domain.
+type DNSOptions struct {
+	// Targets are the host names that the domain is a CNAME of, like adamluzsi.github.io,
+	// or the IP addresses that it resolves to.
+	// A host name also matches the addresses that it resolves to, like the flattened CNAME of an apex domain.
+	//
+	// default: GitHub Pages, which is a CNAME of a github.io host, or the GitHubPagesIPs
+	Targets []string
+	// Resolver resolves the records of the domain.
+	//
+	// default: net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+// CheckDNS resolves the host of the domain, and checks that it points to one of the targets,
+// since a drifted DNS record breaks go get, along with everything else on the domain.
+// It returns the records of the host, like "CNAME adamluzsi.github.io, 185.199.108.153".
+func CheckDNS(ctx context.Context, domain string, opts DNSOptions) (string, error) {
+	var (
+		host, _  = splitDomain(domain)
+		resolver = zerokit.Coalesce(opts.Resolver, net.DefaultResolver)
+	)
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("%s doesn't resolve: %w", host, err)
+	}
+	// without a CNAME record, the canonical name is the host itself
+	cname, err := resolver.LookupCNAME(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", host, err)
+	}
+	cname = strings.TrimSuffix(cname, ".")
+	records := strings.Join(addrs, ", ")
+	if strings.EqualFold(cname, host) {
+		cname = ""
+	} else {
+		records = "CNAME " + cname + ", " + records
+	}
+
+	var (
+		allowed = map[string]struct{}{}
+		want    = "GitHub Pages"
+	)
+	allow := func(ips ...string) {
+		for _, ip := range ips {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				allowed[parsed.String()] = struct{}{}
+			}
+		}
+	}
+	if len(opts.Targets) == 0 {
+		if strings.HasSuffix(strings.ToLower(cname), ".github.io") {
+			return records, nil
+		}
+		allow(GitHubPagesIPs...)
+	} else {
+		want = strings.Join(opts.Targets, " or ")
+		for _, target := range opts.Targets {
+			target = strings.TrimSuffix(target, ".")
+			if net.ParseIP(target) != nil {
+				allow(target)
+				continue
+			}
+			if cname != "" && strings.EqualFold(cname, target) {
+				return records, nil
+			}
+			ips, err := resolver.LookupHost(ctx, target)
+			if err != nil {
+				return records, fmt.Errorf("the %s target doesn't resolve: %w", target, err)
+			}
+			allow(ips...)
+		}
+	}
+	for _, addr := range addrs {
+		if _, ok := allowed[net.ParseIP(addr).String()]; !ok {
+			return records, fmt.Errorf("%s points to %s, rather than to %s", host, records, want)
+		}
+	}
+	return records, nil
+}
+
+// CheckCNAMEFile checks that the CNAME file of the site holds the host of the domain,
+// since GitHub Pages serves the site on the host of the published CNAME file.
+func CheckCNAMEFile(site fs.FS, domain string) error {
+	host, _ := splitDomain(domain)
+	content, err := fs.ReadFile(site, "CNAME")
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("the site of %s has no CNAME file", host)
+	}
+	if err != nil {
+		return err
+	}
+	if got := strings.TrimSpace(string(content)); got != host {
+		return fmt.Errorf("the CNAME file holds %q, rather than %q", got, host)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package vanity
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// ModuleError is the failure of rendering the pages of a module.
+type ModuleError struct {
+	// Prefix is the import prefix of the failed module.
+	Prefix string
+	Err    error
+}
+
+func (e ModuleError) Error() string { return e.Prefix + ": " + e.Err.Error() }
+
+func (e ModuleError) Unwrap() error { return e.Err }
+
+// ModuleErrors are the failures of the modules of a generation with the ContinueOnError option,
+// whose other modules were generated regardless.
+type ModuleErrors []ModuleError
+
+func (errs ModuleErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	prefixes := make([]string, 0, len(errs))
+	for _, err := range errs {
+		prefixes = append(prefixes, err.Prefix)
+	}
+	return fmt.Sprintf("%d modules failed: %s", len(errs), strings.Join(prefixes, ", "))
+}
+
+func (errs ModuleErrors) Unwrap() []error {
+	unwrapped := make([]error, 0, len(errs))
+	for _, err := range errs {
+		unwrapped = append(unwrapped, err)
+	}
+	return unwrapped
+}
+
+// keepFailedModules reads the files of the failed modules, which the output directory has from a previous generation,
+// so a module that failed to render keeps serving its last good pages, rather than being pruned.
+func keepFailedModules(fsys filesystem.FileSystem, failures ModuleErrors) ([]File, error) {
+	failed := map[string]struct{}{}
+	for _, failure := range failures {
+		failed[failure.Prefix] = struct{}{}
+	}
+	manifest, _, err := readManifest(fsys)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, entry := range manifest.Files {
+		if _, ok := failed[entry.Module]; !ok {
+			continue
+		}
+		content, err := readFile(fsys, entry.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Path: entry.Path, Content: content, Module: entry.Module})
+	}
+	return files, nil
+}
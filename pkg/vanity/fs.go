@@ -0,0 +1,63 @@
+package vanity
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// readFile reads the named file of the file system.
+func readFile(fsys filesystem.FileSystem, name string) ([]byte, error) {
+	f, err := filesystem.Open(fsys, filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFile writes the data into the named file of the file system,
+// and it creates the missing parent directories.
+func writeFile(fsys filesystem.FileSystem, name string, data []byte, perm fs.FileMode) (rErr error) {
+	name = filepath.FromSlash(name)
+	if err := mkdirAll(fsys, filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, f.Close()) }()
+	_, err = f.Write(data)
+	return err
+}
+
+// mkdirAll creates the named directory of the file system, along with its missing parents.
+func mkdirAll(fsys filesystem.FileSystem, name string, perm fs.FileMode) error {
+	if name == "." || name == "" {
+		return nil
+	}
+	info, err := fsys.Stat(name)
+	if err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := mkdirAll(fsys, filepath.Dir(name), perm); err != nil {
+		return err
+	}
+	if err := fsys.Mkdir(name, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package vanity
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// templateFuncs are the functions of the page templates,
+// which let a custom template build a richer page from the module's data.
+// The env function reads the environment variables with getenv, which is os.Getenv when it's nil.
+func templateFuncs(as assets, theme *Theme, getenv func(string) string) template.FuncMap {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return template.FuncMap{
+		// asset tells the URL of an asset's fingerprinted copy, like {{ asset "css/site.css" }}
+		"asset": as.URL,
+		// the semver functions take the versions with their v prefix, like the version tags
+		"semverMajor":      semver.Major,
+		"semverMajorMinor": semver.MajorMinor,
+		"semverCanonical":  semver.Canonical,
+		"semverCompare":    semver.Compare,
+		"semverPrerelease": semver.Prerelease,
+		// urlJoin joins the path elements to a URL, like {{ urlJoin .RepositoryURL "blob" "master" "LICENSE" }}
+		"urlJoin": url.JoinPath,
+		// markdown renders a Markdown text, like a description, to HTML
+		"markdown": func(text string) (template.HTML, error) {
+			return renderMarkdown([]byte(text))
+		},
+		"date": formatDate,
+		// env reads an environment variable of the generator, like the name of the deployment
+		"env": getenv,
+		// t translates a message of the pages, like {{ t "documentation" }}, and lang tells the language of the page
+		"t":    translator(nil),
+		"lang": func() string { return "" },
+		// theme is the Theme of the site, which is nil without one
+		"theme": func() *Theme { return theme },
+	}
+}
+
+// formatDate formats a time with the layout of the time package, like {{ date "2006-01-02" .Time }}.
+// The time can be a time.Time, or an RFC 3339 text.
+func formatDate(layout string, value any) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date: %T is not a time", value)
+	}
+}
@@ -0,0 +1,734 @@
+// Package vanity generates the static site that serves the go-import meta tags of a vanity domain.
+package vanity
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"go.llib.dev/frameless/adapter/localfs"
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// GenerateOptions configure how the site is rendered and written into the output directory.
+type GenerateOptions struct {
+	// Prune enables the removal of the generated pages that are no longer part of the site.
+	Prune bool
+	// Workers is the number of modules rendered concurrently.
+	//
+	// default: the number of CPUs
+	Workers int
+	// Atomic makes the changes visible all at once,
+	// by applying them on a staging copy of the output directory that is swapped into its place.
+	// It only applies to WriteDir.
+	Atomic bool
+	// HeadersFile adds a _headers file to the site,
+	// which sets the Cache-Control header of the pages on static hosts like Netlify or Cloudflare Pages.
+	HeadersFile bool
+	// MaxAge is how long the clients may cache a page, when the HeadersFile or a static host's plugin is enabled.
+	MaxAge time.Duration
+	// SecurityHeaders are the security headers of the pages in the HeadersFile and the header files of the static hosts' plugins.
+	SecurityHeaders SecurityHeaders
+	// Index adds an index.html page to the domain root, which lists the modules of the domain by their categories,
+	// and an index.json of the same catalogue for tooling.
+	Index bool
+	// Search adds a search-index.json of the modules to the site, with their names, descriptions and tags,
+	// and a search box to the index page, which filters its modules by the search index.
+	Search bool
+	// IndexTemplate is the text of a custom html/template for the index page, which gets an IndexPage as its data.
+	//
+	// default: a table of the modules with their repository and documentation links
+	IndexTemplate string
+	// Sitemap adds a sitemap.xml of the pages to the site, with the time of their last change.
+	Sitemap bool
+	// Robots adds a robots.txt to the site, which lets the pages be crawled,
+	// except the ones of the modules with NoCrawl, and points to the sitemap.
+	Robots bool
+	// Badges adds a badge.svg next to the page of every module, which shows the module's latest release.
+	Badges bool
+	// Shields adds the shields.io endpoint files next to the page of every module,
+	// badge.json with the latest release, and go-version.json with the Go version that the release requires.
+	Shields bool
+	// Landing makes the pages of the modules landing pages, which show their README, install instructions,
+	// and documentation links to the browser visitors, rather than redirecting them.
+	// The READMEs are only shown when they were fetched.
+	// A module with a Template of its own is rendered with that.
+	Landing bool
+	// APIDocs adds the API documentation pages of the modules' packages under the docs directory of their pages,
+	// for the modules that pkg.go.dev can't document.
+	// The documentation is only rendered when it was fetched.
+	APIDocs bool
+	// Licenses shows the licenses of the modules on their landing pages, by their SPDX identifiers.
+	// The licenses are only shown when they were detected, and the index page lists the detected ones either way.
+	Licenses bool
+	// Changelog shows the latest entries of the modules' changelogs on their landing pages.
+	// The changelogs are only shown when they were fetched.
+	Changelog bool
+	// ChangelogEntries is the number of the latest changelog entries that are fetched for a landing page.
+	//
+	// default: 3
+	ChangelogEntries int
+	// Versions adds a version listing page under the versions directory of the modules' pages,
+	// with the publishing date and the release notes link of every version, and the landing pages link to it.
+	// The versions are only listed when they were detected.
+	Versions bool
+	// Assets is a directory of static files, like a favicon, stylesheets and images,
+	// which are copied into the site along with a fingerprinted copy of each.
+	// The templates get the URL of the fingerprinted copy by the asset function, like {{ asset "css/site.css" }}.
+	Assets fs.FS
+	// Templates is a directory of html/template files, which override the embedded templates of the module pages.
+	// A file can redefine the head, body and footer sub-templates of the page,
+	// or replace the whole page by its go-import.html file, which then has to keep the go-import meta tag.
+	// The templates get a Page as their data.
+	Templates fs.FS
+	// Plugins add files computed from the modules to the site, like the configuration files of a static host.
+	Plugins []OutputPlugin
+	// Report is an optional report of the generation, which the rendering and the writing of the site fill.
+	Report *Report
+	// OnWrite is called with the changes of the output directory after the site of a domain is written,
+	// like to purge the changed files from the cache of a CDN.
+	// A multi-domain Generator calls it with the site of every domain.
+	OnWrite func(domain string, changes []Change)
+	// Getenv looks up the environment variables that the env function of the templates reads,
+	// like the settings of a command, which may come from more places than the environment of the process.
+	//
+	// default: os.Getenv
+	Getenv func(key string) string
+	// PrivateGuides renders guide pages for the private modules, rather than leaving them out of the site.
+	// The pages keep the go-import meta tag, and tell the visitors the GOPRIVATE, GONOPROXY and GONOSUMDB settings,
+	// and the credentials of the repository, that the go command needs to download the module.
+	// The settings of every private module are in the goprivate.json file of the site too.
+	// Unlike the served private modules, the guide pages reveal the import paths and the repositories to anyone.
+	PrivateGuides bool
+	// Theme is the look of the pages, which the default templates are styled with.
+	//
+	// default: the unstyled pages of the browser
+	Theme *Theme
+	// Language is the language tag of the pages at the root of the site, like en or de,
+	// whose texts are of its catalog in the Catalogs.
+	//
+	// default: en, and the module pages have no lang attribute
+	Language string
+	// Catalogs are the texts of the landing pages, the index page and the notices of the module pages, by language tag.
+	// The pages of every language besides the root pages' are written into a directory of the language, like de/testcase/index.html.
+	// The texts that a catalog lacks are the English defaults.
+	Catalogs map[string]Catalog
+	// ContinueOnError makes a module that fails to render leave the rest of the site alone:
+	// the other modules are generated, the failed one keeps its pages of the previous generation,
+	// and the failures are returned at the end as ModuleErrors.
+	ContinueOnError bool
+}
+
+func (opts GenerateOptions) workers() int {
+	if opts.Workers < 1 {
+		return runtime.NumCPU()
+	}
+	return opts.Workers
+}
+
+// Generator generates the static site of a vanity domain.
+type Generator struct {
+	// Domain is the vanity domain, e.g. go.llib.dev
+	//
+	// The domain can have a base path, like example.com/go, when the site is hosted under a path of the domain,
+	// rather than at its root. The import prefixes of the modules are under the base path,
+	// the output directory is the content of the base path, and the URLs of the site are under it.
+	//
+	// A wildcard domain, like *.llib.dev, serves every module on a subdomain of its own, like foo.llib.dev,
+	// rather than on a path of a single domain, for hosts that route by subdomain.
+	// The import prefixes of the modules are their subdomains, and each subdomain is a site of its own.
+	Domain string
+	// Domains are the vanity domains of a multi-domain site, like go.llib.dev and go.example.dev,
+	// whose modules are configured together.
+	// WriteDir writes the site of each into a subdirectory named after the domain, with a CNAME and page tree of its own,
+	// and ForDomain gives the Generator of a single domain's site.
+	Domains []string
+	// Modules are the modules that the site serves.
+	Modules []Meta
+	// Options configure how the site is rendered and written.
+	Options GenerateOptions
+	// BranchDetector is an optional detector of the repositories' default branches,
+	// which the go-source patterns of the modules without a configured branch point to.
+	BranchDetector *BranchDetector
+}
+
+// LoadConfig reads and validates the imports file, and sets the Generator's modules from its entries.
+// The location can be a file, a directory of module files, or an http(s) URL.
+func (g *Generator) LoadConfig(ctx context.Context, location string, opts ImportsOptions) error {
+	dtos, err := ReadImports(ctx, location, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateImports(dtos, g.domains()...); err != nil {
+		return fmt.Errorf("invalid imports file:\n%w", err)
+	}
+	if g.BranchDetector != nil {
+		dtos = g.BranchDetector.DetectBranches(ctx, dtos)
+	}
+
+	var metas []Meta
+	for _, dto := range dtos {
+		meta, err := ToMeta(dto)
+		if err != nil {
+			return err
+		}
+		metas = append(metas, meta)
+	}
+
+	g.Modules = metas
+	return nil
+}
+
+// domains are the vanity domains of the site.
+func (g *Generator) domains() []string {
+	if len(g.Domains) == 0 {
+		return []string{g.Domain}
+	}
+	return g.Domains
+}
+
+// wildcardPrefix marks a wildcard domain, whose modules are served on its subdomains.
+const wildcardPrefix = "*."
+
+// Sites lists the domains of the sites that a multi-domain or wildcard Generator writes into subdirectories,
+// in the order of the configured domains, and the subdomains of a wildcard domain in the order of the modules.
+// It is empty for the site of a single domain.
+func (g *Generator) Sites() []string {
+	var (
+		domains  = g.domains()
+		wildcard bool
+	)
+	for _, domain := range domains {
+		wildcard = wildcard || strings.HasPrefix(domain, wildcardPrefix)
+	}
+	if len(domains) < 2 && !wildcard {
+		return nil
+	}
+	var (
+		sites []string
+		seen  = map[string]struct{}{}
+	)
+	add := func(site string) {
+		if _, ok := seen[site]; !ok {
+			seen[site] = struct{}{}
+			sites = append(sites, site)
+		}
+	}
+	for _, domain := range domains {
+		if !strings.HasPrefix(domain, wildcardPrefix) {
+			add(domain)
+			continue
+		}
+		for _, meta := range g.Modules {
+			if site, ok := domainOf(meta.Import.Prefix, []string{domain}); ok {
+				add(site)
+			}
+		}
+	}
+	return sites
+}
+
+// ForDomain gives the Generator of a single domain's site, with the modules under the domain.
+func (g *Generator) ForDomain(domain string) Generator {
+	sub := *g
+	sub.Domain, sub.Domains, sub.Modules = domain, nil, nil
+	for _, meta := range g.Modules {
+		if d, ok := domainOf(meta.Import.Prefix, g.domains()); ok && d == domain {
+			sub.Modules = append(sub.Modules, meta)
+		}
+	}
+	return sub
+}
+
+// Plan renders the site, and compares it against the content of the file system.
+// With ContinueOnError, the changes are returned along with the ModuleErrors of the failed modules,
+// whose files in the file system are planned as unchanged.
+func (g *Generator) Plan(ctx context.Context, fsys filesystem.FileSystem) ([]Change, error) {
+	if sites := g.Sites(); 0 < len(sites) {
+		return nil, fmt.Errorf("the site of %d domains is planned by domain, with their ForDomain generators", len(sites))
+	}
+	files, err := g.Render(ctx)
+	var failures ModuleErrors
+	if err != nil && !errors.As(err, &failures) {
+		return nil, err
+	}
+	if 0 < len(failures) {
+		kept, err := keepFailedModules(fsys, failures)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, kept...)
+	}
+	files, err = g.completeSite(fsys, files)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := planChanges(fsys, files, g.Options)
+	if err != nil {
+		return nil, err
+	}
+	if 0 < len(failures) {
+		return changes, failures
+	}
+	return changes, nil
+}
+
+// completeSite adds the files that are computed from the whole site to the rendered files:
+// the robots.txt, and the sitemap, whose last modification times are kept from the sitemap of the file system.
+func (g *Generator) completeSite(fsys filesystem.FileSystem, files []File) ([]File, error) {
+	if g.Options.Robots {
+		files = append(files, renderRobots(g.Domain, g.Modules, g.Options.Sitemap))
+	}
+	if g.Options.Sitemap {
+		sitemap, err := renderSitemap(fsys, g.Domain, crawledFiles(files, g.Modules))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sitemap)
+	}
+	if err := checkDuplicateFiles(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// WriteTo renders the site, and writes it into the file system.
+// The file system can be the local disk, an in-memory file system in tests, or any other store behind the interface.
+// With ContinueOnError, the site is written without the failed modules, and their ModuleErrors are returned afterwards.
+func (g *Generator) WriteTo(ctx context.Context, fsys filesystem.FileSystem) error {
+	changes, err := g.Plan(ctx, fsys)
+	var failures ModuleErrors
+	if err != nil && !errors.As(err, &failures) {
+		return err
+	}
+	if err := applyChanges(ctx, fsys, changes); err != nil {
+		return err
+	}
+	g.Options.Report.observeChanges(changes)
+	if g.Options.OnWrite != nil {
+		g.Options.OnWrite(g.Domain, changes)
+	}
+	if 0 < len(failures) {
+		return failures
+	}
+	return nil
+}
+
+// WriteDir renders the site, and writes it into a directory of the local disk.
+// With the Atomic option, the directory is updated by a staging directory swap.
+// The site of a multi-domain or wildcard Generator is written into a subdirectory per domain.
+func (g *Generator) WriteDir(ctx context.Context, outDirPath string) error {
+	var failures ModuleErrors
+	if sites := g.Sites(); 0 < len(sites) {
+		for _, domain := range sites {
+			sub := g.ForDomain(domain)
+			err := sub.WriteDir(ctx, filepath.Join(outDirPath, domain))
+			var siteFailures ModuleErrors
+			if errors.As(err, &siteFailures) {
+				failures = append(failures, siteFailures...)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", domain, err)
+			}
+		}
+		if 0 < len(failures) {
+			return failures
+		}
+		return nil
+	}
+	write := func(dirPath string) error {
+		err := g.WriteTo(ctx, localfs.FileSystem{RootPath: dirPath})
+		if errors.As(err, &failures) {
+			// the site is complete without the failed modules, so the staging directory is swapped in too
+			return nil
+		}
+		return err
+	}
+	var err error
+	if g.Options.Atomic {
+		err = writeDirAtomic(outDirPath, write)
+	} else if err = ensureDirectory(outDirPath); err == nil {
+		err = write(outDirPath)
+	}
+	if err != nil {
+		return err
+	}
+	if 0 < len(failures) {
+		return failures
+	}
+	return nil
+}
+
+// applyChanges writes the created and updated files into the file system.
+// Unchanged files are not written, so their modification time is kept,
+// and the published site doesn't get redeployed for nothing.
+func applyChanges(ctx context.Context, fsys filesystem.FileSystem, changes []Change) error {
+	var (
+		counts  = map[ChangeKind]int{}
+		orphans []string
+	)
+	for _, change := range changes {
+		counts[change.Kind]++
+		switch change.Kind {
+		case ChangeCreate, ChangeUpdate:
+			if err := writeFile(fsys, change.File.Path, change.File.Content, 0644); err != nil {
+				return fmt.Errorf("writing out %s failed: %w", change.File.Path, err)
+			}
+			logger.Info(ctx, "file written", logging.Field("path", change.File.Path))
+		case ChangeDelete:
+			orphans = append(orphans, change.File.Path)
+			logger.Info(ctx, "file pruned", logging.Field("path", change.File.Path))
+		}
+	}
+	if err := removeOrphans(fsys, orphans); err != nil {
+		return err
+	}
+	logger.Info(ctx, "site written",
+		logging.Field("written", counts[ChangeCreate]+counts[ChangeUpdate]),
+		logging.Field("unchanged", counts[ChangeUnchanged]),
+		logging.Field("pruned", counts[ChangeDelete]))
+	return nil
+}
+
+// File is a rendered file of the static site.
+type File struct {
+	// Path is the slash separated path of the file, relative to the output directory.
+	Path    string
+	Content []byte
+	// Module is the import prefix of the module that the file belongs to.
+	Module string
+}
+
+// Render renders every file of the static site in memory.
+// The modules are rendered concurrently by a bounded pool of workers,
+// and the errors are collected per module rather than stopping at the first failing one.
+// With ContinueOnError, the files of the other modules are returned along with the ModuleErrors of the failed ones.
+func (g *Generator) Render(ctx context.Context) ([]File, error) {
+	var (
+		domain = g.Domain
+		metas  = g.Modules
+	)
+	_, basePath := splitDomain(domain)
+	as, err := readAssets(g.Options.Assets, basePath)
+	if err != nil {
+		return nil, err
+	}
+	funcs := withLocale(templateFuncs(as, g.Options.Theme, g.Options.Getenv), g.Options.Language, g.Options.Catalogs[rootLanguage(g.Options.Language)])
+	tmpl, err := getImportTemplate(funcs, g.Options.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("getRedirectTemplate failed: %w", err)
+	}
+	locales, err := siteLocales(tmpl, funcs, g.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results   = make([][]File, len(metas))
+		errs      = make([]error, len(metas))
+		durations = make([]time.Duration, len(metas))
+		jobs      = make(chan int)
+		wg        sync.WaitGroup
+	)
+	for w := 0; w < g.Options.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				results[i], errs[i] = renderModule(tmpl, domain, metas[i], g.Options)
+				if errs[i] == nil && 0 < len(locales) {
+					var localized []File
+					localized, errs[i] = renderLocalizedModule(locales, domain, metas[i], g.Options)
+					results[i] = append(results[i], localized...)
+				}
+				durations[i] = time.Since(start)
+			}
+		}()
+	}
+feed:
+	for i := range metas {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	g.Options.Report.observeRender(metas, results, errs, durations)
+
+	var failures ModuleErrors
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, ModuleError{Prefix: metas[i].Import.Prefix, Err: err})
+			results[i] = nil
+		}
+	}
+	if 0 < len(failures) && !g.Options.ContinueOnError {
+		return nil, errorkit.Merge(failures.Unwrap()...)
+	}
+
+	host, _ := splitDomain(domain)
+	files := []File{{Path: "CNAME", Content: []byte(host)}}
+	if g.Options.HeadersFile {
+		files = append(files, renderHeaders(basePath, g.Options.MaxAge, g.Options.SecurityHeaders))
+	}
+	if g.Options.Index {
+		data, err := newIndexPage(domain, metas)
+		if err != nil {
+			return nil, err
+		}
+		if g.Options.Search {
+			data.SearchIndexURL = basePath + "/" + searchIndexFileName
+		}
+		index, err := renderIndex(data, g.Options.IndexTemplate, funcs)
+		if err != nil {
+			return nil, err
+		}
+		indexJSON, err := renderIndexJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, index, indexJSON)
+		for _, l := range locales {
+			index, err := renderIndex(data, g.Options.IndexTemplate, l.funcs)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", l.lang, err)
+			}
+			index.Path = path.Join(l.lang, index.Path)
+			files = append(files, index)
+		}
+	}
+	if g.Options.Search {
+		index, err := renderSearchIndex(domain, metas)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, index)
+	}
+	if g.Options.PrivateGuides {
+		config, ok, err := renderPrivateConfig(domain, metas)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append(files, config)
+		}
+	}
+	for _, moduleFiles := range results {
+		files = append(files, moduleFiles...)
+	}
+	files = append(files, as.files...)
+	for _, plugin := range g.Options.Plugins {
+		site := Site{Domain: domain, Modules: metas, Files: files, Assets: as.fingerprints, Options: g.Options}
+		pluginFiles, err := plugin.Render(site)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, pluginFiles...)
+	}
+	if 0 < len(failures) {
+		return files, failures
+	}
+	return files, nil
+}
+
+// renderModule renders the pages of a module and its nested modules, with their badges when they are enabled.
+func renderModule(tmpl *template.Template, domain string, meta Meta, opts GenerateOptions) ([]File, error) {
+	if !strings.Contains(meta.Import.Prefix, domain) {
+		return nil, nil
+	}
+	if meta.Private {
+		if opts.PrivateGuides {
+			return renderPrivateGuides(tmpl, domain, meta)
+		}
+		// a static host can't ask for credentials
+		return nil, nil
+	}
+
+	var files []File
+	for _, importPath := range pageImportPaths(meta) {
+		var (
+			name = importTemplateName
+			p    = newPage(meta, importPath)
+		)
+		switch meta.Template {
+		case "":
+			if opts.Landing {
+				p = newLandingPage(meta, importPath)
+			}
+		case TemplateLanding:
+			p = newLandingPage(meta, importPath)
+		default:
+			// a page of its own is likely richer than a redirect, so it gets the README too
+			if tmpl.Lookup(meta.Template) == nil {
+				return nil, fmt.Errorf("the %s template is not in the templates directory", meta.Template)
+			}
+			name, p = meta.Template, newLandingPage(meta, importPath)
+		}
+		if opts.Licenses && p.Landing {
+			p.License = meta.Licenses[importPath]
+		}
+		if opts.Changelog && p.Landing {
+			p.Changelog = meta.Changelogs[importPath]
+		}
+		if opts.Versions && p.Landing && 0 < len(meta.Versions[importPath]) {
+			p.VersionsURL = versionsURL(domain, importPath)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, p); err != nil {
+			return nil, fmt.Errorf("redirect template execution failed: %w", err)
+		}
+		files = append(files, File{
+			Path:    pagePath(domain, importPath),
+			Content: buf.Bytes(),
+			Module:  meta.Import.Prefix,
+		})
+		if opts.Badges && meta.Tombstone.Kind == "" {
+			files = append(files, renderBadge(domain, meta, importPath))
+		}
+		if opts.Shields && meta.Tombstone.Kind == "" {
+			shields, err := renderShields(domain, meta, importPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, shields...)
+		}
+		if opts.APIDocs && meta.Tombstone.Kind == "" {
+			docs, err := renderAPIDocs(tmpl, domain, meta, importPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, docs...)
+		}
+		if opts.Versions && meta.Tombstone.Kind == "" {
+			versions, err := renderVersions(tmpl, domain, meta, importPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, versions...)
+		}
+	}
+	return files, nil
+}
+
+// pageImportPaths are the import paths of a module's pages, which are the module's, and its nested modules'.
+func pageImportPaths(meta Meta) []string {
+	importPaths := []string{meta.Import.Prefix}
+	for _, nested := range meta.Nested {
+		importPaths = append(importPaths, nested.ImportPath)
+	}
+	// a major version subdirectory can be listed as a nested module too
+	for _, major := range meta.Majors {
+		if !isNestedImportPath(meta, major) {
+			importPaths = append(importPaths, major)
+		}
+	}
+	return importPaths
+}
+
+// pagePath tells where the page of an import path is placed within the output directory.
+func pagePath(domain, importPath string) string {
+	dir := strings.TrimPrefix(strings.TrimPrefix(importPath, domain), "/")
+	return path.Join(dir, "index.html")
+}
+
+// splitDomain splits the vanity domain into its host, and the base path that the site is hosted under.
+// The site of a domain like example.com/go lives under the /go path of example.com,
+// and its output directory is published there, like the GitHub Pages site of a project repository.
+func splitDomain(domain string) (host, basePath string) {
+	host, basePath, ok := strings.Cut(domain, "/")
+	if !ok {
+		return host, ""
+	}
+	return host, "/" + strings.Trim(basePath, "/")
+}
+
+// importTemplateName is the name of the module page template,
+// which a go-import.html file of the templates directory replaces.
+const importTemplateName = "go-import.html"
+
+// TemplateLanding is the template of a module, which makes its pages landing pages.
+const TemplateLanding = "landing"
+
+//go:embed go-import.html
+var goImportHTML string
+
+//go:embed landing.html
+var landingHTML string
+
+//go:embed apidocs.html
+var apiDocsHTML string
+
+//go:embed private.html
+var privateHTML string
+
+//go:embed versions.html
+var versionsHTML string
+
+// getImportTemplate is the Go import redirect template, with the body of the landing pages,
+// the API documentation pages, and the version listing pages.
+// The html files of the overrides directory are parsed last, so they redefine the embedded templates.
+func getImportTemplate(funcs template.FuncMap, overrides fs.FS) (*template.Template, error) {
+	tmpl := template.New(importTemplateName).Funcs(funcs)
+	for _, text := range []string{goImportHTML, landingHTML, apiDocsHTML, privateHTML, versionsHTML, themeHTML} {
+		if _, err := tmpl.Parse(text); err != nil {
+			return nil, err
+		}
+	}
+	if overrides == nil {
+		return tmpl, nil
+	}
+	names, err := fs.Glob(overrides, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("the templates directory has no .html files")
+	}
+	return tmpl.ParseFS(overrides, names...)
+}
+
+// checkDuplicateFiles makes sure that a file of the site isn't rendered twice,
+// like an asset with the path of a generated page, which would silently replace it.
+func checkDuplicateFiles(files []File) error {
+	seen := map[string]struct{}{}
+	for _, file := range files {
+		if _, ok := seen[file.Path]; ok {
+			return fmt.Errorf("%s is rendered more than once, an asset can't have the path of a generated file", file.Path)
+		}
+		seen[file.Path] = struct{}{}
+	}
+	return nil
+}
+
+// ensureDirectory attempts to create a directory at the specified path.
+// It returns nil if the directory was created successfully or already exists,
+// and an error if any occurred.
+func ensureDirectory(path string) error {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return err
+	}
+	return nil
+}
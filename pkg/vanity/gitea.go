@@ -0,0 +1,144 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// GiteaDiscovery finds the Go module repositories of an organization or user on a Gitea compatible host,
+// such as a self-hosted Gitea or Forgejo instance, or Codeberg.
+type GiteaDiscovery struct {
+	// Owner is the name of the organization or the user.
+	Owner string
+	// Token is an optional access token, which lets the private repositories be found.
+	Token string
+	// BaseURL is the address of the instance.
+	//
+	// default: https://codeberg.org
+	BaseURL string
+	// IncludeForks adds the forked repositories to the results.
+	IncludeForks bool
+	// IncludeArchived adds the archived repositories to the results.
+	IncludeArchived bool
+	// Client is the HTTP client of the API requests.
+	//
+	// default: a client that retries the temporary failures
+	Client *http.Client
+}
+
+type giteaRepositoryDTO struct {
+	Name          string `json:"name"`
+	HTMLURL       string `json:"html_url"`
+	Website       string `json:"website"`
+	DefaultBranch string `json:"default_branch"`
+	Fork          bool   `json:"fork"`
+	Archived      bool   `json:"archived"`
+	Empty         bool   `json:"empty"`
+}
+
+func (d GiteaDiscovery) Discover(ctx context.Context) ([]Repository, error) {
+	dtos, err := d.repositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repository
+	for _, dto := range dtos {
+		if dto.Empty || dto.DefaultBranch == "" {
+			continue
+		}
+		if (dto.Fork && !d.IncludeForks) || (dto.Archived && !d.IncludeArchived) {
+			continue
+		}
+		ok, err := d.hasGoMod(ctx, dto)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		dir := fmt.Sprintf("%s/src/branch/%s{/dir}", dto.HTMLURL, dto.DefaultBranch)
+		repos = append(repos, Repository{
+			Name:             dto.Name,
+			URL:              dto.HTMLURL,
+			Homepage:         dto.Website,
+			DirectoryPattern: dir,
+			FilePattern:      dir + "/{file}#L{line}",
+		})
+	}
+	return repos, nil
+}
+
+// repositories lists the repositories of the owner.
+// The organization endpoint is tried first, and the user endpoint is used when the owner is not an organization.
+func (d GiteaDiscovery) repositories(ctx context.Context) ([]giteaRepositoryDTO, error) {
+	owner := url.PathEscape(d.Owner)
+	dtos, err := d.listRepositories(ctx, "/orgs/"+owner+"/repos?limit=50")
+	if errors.Is(err, ErrNotFound) {
+		dtos, err = d.listRepositories(ctx, "/users/"+owner+"/repos?limit=50")
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("gitea: %s is not an organization or a user", d.Owner)
+	}
+	return dtos, err
+}
+
+// listRepositories reads every page of a repository listing, by following the next links.
+func (d GiteaDiscovery) listRepositories(ctx context.Context, endpoint string) ([]giteaRepositoryDTO, error) {
+	var (
+		all  []giteaRepositoryDTO
+		next = d.apiURL() + endpoint
+	)
+	for next != "" {
+		resp, err := d.get(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []giteaRepositoryDTO
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("gitea: failed to decode the repositories: %w", err)
+		}
+		all = append(all, page...)
+		next = nextLink(resp)
+	}
+	return all, nil
+}
+
+// hasGoMod tells whether there is a go.mod file in the root of the repository's default branch.
+func (d GiteaDiscovery) hasGoMod(ctx context.Context, dto giteaRepositoryDTO) (bool, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/raw/go.mod?ref=%s",
+		d.apiURL(), url.PathEscape(d.Owner), url.PathEscape(dto.Name), url.QueryEscape(dto.DefaultBranch))
+	resp, err := d.get(ctx, endpoint)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, drain(resp)
+}
+
+func (d GiteaDiscovery) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	if d.Token != "" {
+		header.Set("Authorization", "token "+d.Token)
+	}
+	resp, err := apiGet(ctx, d.Client, endpoint, header)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("gitea: %w", err)
+	}
+	return resp, err
+}
+
+func (d GiteaDiscovery) apiURL() string {
+	return strings.TrimSuffix(zerokit.Coalesce(d.BaseURL, "https://codeberg.org"), "/") + "/api/v1"
+}
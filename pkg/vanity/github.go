@@ -0,0 +1,136 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// GitHubDiscovery finds the Go module repositories of a GitHub organization or user.
+type GitHubDiscovery struct {
+	// Owner is the name of the organization or the user.
+	Owner string
+	// Token is an optional access token, which lifts the rate limits, and lets the private repositories be found.
+	Token string
+	// BaseURL is the address of the GitHub API, which is different on GitHub Enterprise Server.
+	//
+	// default: https://api.github.com
+	BaseURL string
+	// IncludeForks adds the forked repositories to the results.
+	IncludeForks bool
+	// IncludeArchived adds the archived repositories to the results.
+	IncludeArchived bool
+	// Client is the HTTP client of the API requests.
+	//
+	// default: a client that retries the temporary failures
+	Client *http.Client
+}
+
+type githubRepositoryDTO struct {
+	Name     string `json:"name"`
+	HTMLURL  string `json:"html_url"`
+	Homepage string `json:"homepage"`
+	Fork     bool   `json:"fork"`
+	Archived bool   `json:"archived"`
+}
+
+func (d GitHubDiscovery) Discover(ctx context.Context) ([]Repository, error) {
+	dtos, err := d.repositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repository
+	for _, dto := range dtos {
+		if (dto.Fork && !d.IncludeForks) || (dto.Archived && !d.IncludeArchived) {
+			continue
+		}
+		ok, err := d.hasGoMod(ctx, dto.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		repos = append(repos, Repository{
+			Name:     dto.Name,
+			URL:      dto.HTMLURL,
+			Homepage: dto.Homepage,
+		})
+	}
+	return repos, nil
+}
+
+// repositories lists the repositories of the owner.
+// The organization endpoint is tried first, since it lists the private repositories too,
+// and the user endpoint is used when the owner is not an organization.
+func (d GitHubDiscovery) repositories(ctx context.Context) ([]githubRepositoryDTO, error) {
+	owner := url.PathEscape(d.Owner)
+	dtos, err := d.listRepositories(ctx, "/orgs/"+owner+"/repos?per_page=100")
+	if errors.Is(err, ErrNotFound) {
+		dtos, err = d.listRepositories(ctx, "/users/"+owner+"/repos?per_page=100")
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("github: %s is not an organization or a user", d.Owner)
+	}
+	return dtos, err
+}
+
+// listRepositories reads every page of a repository listing, by following the next links.
+func (d GitHubDiscovery) listRepositories(ctx context.Context, endpoint string) ([]githubRepositoryDTO, error) {
+	var (
+		all  []githubRepositoryDTO
+		next = d.baseURL() + endpoint
+	)
+	for next != "" {
+		resp, err := d.get(ctx, next, "application/vnd.github+json")
+		if err != nil {
+			return nil, err
+		}
+		var page []githubRepositoryDTO
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("github: failed to decode the repositories: %w", err)
+		}
+		all = append(all, page...)
+		next = nextLink(resp)
+	}
+	return all, nil
+}
+
+// hasGoMod tells whether there is a go.mod file in the root of the repository's default branch.
+func (d GitHubDiscovery) hasGoMod(ctx context.Context, repo string) (bool, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/go.mod", d.baseURL(), url.PathEscape(d.Owner), url.PathEscape(repo))
+	resp, err := d.get(ctx, endpoint, "application/vnd.github.raw")
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, drain(resp)
+}
+
+func (d GitHubDiscovery) get(ctx context.Context, endpoint, accept string) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Accept", accept)
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if d.Token != "" {
+		header.Set("Authorization", "Bearer "+d.Token)
+	}
+	resp, err := apiGet(ctx, d.Client, endpoint, header)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	return resp, err
+}
+
+func (d GitHubDiscovery) baseURL() string {
+	return strings.TrimSuffix(zerokit.Coalesce(d.BaseURL, "https://api.github.com"), "/")
+}
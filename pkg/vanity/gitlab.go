@@ -0,0 +1,155 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// GitLabDiscovery finds the Go module repositories of a GitLab group, including its subgroups, or of a user.
+// It works with gitlab.com and with self-hosted GitLab instances too.
+type GitLabDiscovery struct {
+	// Group is the full path of the group, e.g. my-org/backend, or the name of a user.
+	Group string
+	// Token is an optional personal, group or project access token, which lets the private projects be found.
+	Token string
+	// BaseURL is the address of the GitLab instance.
+	//
+	// default: https://gitlab.com
+	BaseURL string
+	// IncludeForks adds the forked projects to the results.
+	IncludeForks bool
+	// IncludeArchived adds the archived projects to the results.
+	IncludeArchived bool
+	// Client is the HTTP client of the API requests.
+	//
+	// default: a client that retries the temporary failures
+	Client *http.Client
+}
+
+type gitlabProjectDTO struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	DefaultBranch     string `json:"default_branch"`
+	Archived          bool   `json:"archived"`
+	EmptyRepo         bool   `json:"empty_repo"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+	// VCSType is only set by Heptapod, the GitLab fork that hosts Mercurial projects too.
+	VCSType string `json:"vcs_type"`
+}
+
+func (d GitLabDiscovery) Discover(ctx context.Context) ([]Repository, error) {
+	dtos, err := d.projects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var repos []Repository
+	for _, dto := range dtos {
+		if dto.EmptyRepo || dto.DefaultBranch == "" {
+			continue
+		}
+		if (dto.ForkedFromProject != nil && !d.IncludeForks) || (dto.Archived && !d.IncludeArchived) {
+			continue
+		}
+		ok, err := d.hasGoMod(ctx, dto)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		repos = append(repos, Repository{
+			// the projects of the subgroups keep their subgroup path, e.g. backend/api
+			Name:             strings.TrimPrefix(dto.PathWithNamespace, strings.Trim(d.Group, "/")+"/"),
+			URL:              dto.WebURL,
+			VCS:              dto.vcs(),
+			DirectoryPattern: fmt.Sprintf("%s/-/tree/%s{/dir}", dto.WebURL, dto.DefaultBranch),
+			FilePattern:      fmt.Sprintf("%s/-/blob/%s{/dir}/{file}#L{line}", dto.WebURL, dto.DefaultBranch),
+		})
+	}
+	return repos, nil
+}
+
+// vcs tells the version control system of the project.
+func (dto gitlabProjectDTO) vcs() string {
+	if dto.VCSType == "hg" {
+		return VCSMercurial
+	}
+	return VCSGit
+}
+
+// projects lists the projects of the group and its subgroups.
+// When the group doesn't exist, the projects of the user with the same name are listed.
+func (d GitLabDiscovery) projects(ctx context.Context) ([]gitlabProjectDTO, error) {
+	group := url.PathEscape(strings.Trim(d.Group, "/"))
+	dtos, err := d.listProjects(ctx, "/groups/"+group+"/projects?include_subgroups=true&per_page=100")
+	if errors.Is(err, ErrNotFound) {
+		dtos, err = d.listProjects(ctx, "/users/"+group+"/projects?per_page=100")
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("gitlab: %s is not a group or a user", d.Group)
+	}
+	return dtos, err
+}
+
+// listProjects reads every page of a project listing, by following the next links.
+func (d GitLabDiscovery) listProjects(ctx context.Context, endpoint string) ([]gitlabProjectDTO, error) {
+	var (
+		all  []gitlabProjectDTO
+		next = d.apiURL() + endpoint
+	)
+	for next != "" {
+		resp, err := d.get(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		var page []gitlabProjectDTO
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("gitlab: failed to decode the projects: %w", err)
+		}
+		all = append(all, page...)
+		next = nextLink(resp)
+	}
+	return all, nil
+}
+
+// hasGoMod tells whether there is a go.mod file in the root of the project's default branch.
+func (d GitLabDiscovery) hasGoMod(ctx context.Context, dto gitlabProjectDTO) (bool, error) {
+	endpoint := fmt.Sprintf("%s/projects/%d/repository/files/go.mod/raw?ref=%s",
+		d.apiURL(), dto.ID, url.QueryEscape(dto.DefaultBranch))
+	resp, err := d.get(ctx, endpoint)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, drain(resp)
+}
+
+func (d GitLabDiscovery) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	header := http.Header{}
+	if d.Token != "" {
+		header.Set("PRIVATE-TOKEN", d.Token)
+	}
+	resp, err := apiGet(ctx, d.Client, endpoint, header)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+	return resp, err
+}
+
+func (d GitLabDiscovery) apiURL() string {
+	return strings.TrimSuffix(zerokit.Coalesce(d.BaseURL, "https://gitlab.com"), "/") + "/api/v4"
+}
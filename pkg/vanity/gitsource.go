@@ -0,0 +1,280 @@
+package vanity
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+)
+
+// GitSource serves the modules from local mirrors of their git repositories.
+// The mirrors are created on the first request, and they are fetched again when a version is not found in them.
+type GitSource struct {
+	// Dir is where the mirrors of the repositories are kept.
+	Dir string
+
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// List asks the repository for its tags with `git ls-remote`, so a new release is listed right after it is tagged,
+// without fetching the whole repository.
+// Only the canonical semantic versions of the module's major version are listed, in semver order.
+func (src *GitSource) List(ctx context.Context, mod ProxyModule) ([]string, error) {
+	out, err := git(ctx, "", "ls-remote", "--tags", "--refs", mod.RepoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		version, ok := strings.CutPrefix(fields[1], "refs/tags/"+tagPrefix(mod))
+		if !ok || !isModuleVersion(mod, version) {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	semver.Sort(versions)
+	return versions, nil
+}
+
+func (src *GitSource) Info(ctx context.Context, mod ProxyModule, version string) (ModuleInfo, error) {
+	dir, commit, err := src.resolve(ctx, mod, version)
+	if err != nil {
+		return ModuleInfo{}, err
+	}
+	out, err := git(ctx, dir, "log", "-1", "--format=%cI", commit)
+	if err != nil {
+		return ModuleInfo{}, err
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+	if err != nil {
+		return ModuleInfo{}, err
+	}
+	return ModuleInfo{Version: version, Time: t.UTC()}, nil
+}
+
+func (src *GitSource) GoMod(ctx context.Context, mod ProxyModule, version string) ([]byte, error) {
+	dir, commit, err := src.resolve(ctx, mod, version)
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(ctx, dir, "show", commit+":"+path.Join(mod.Dir, "go.mod"))
+	if err != nil {
+		// a module without a go.mod file gets a synthesized one, like the go command does
+		return []byte(fmt.Sprintf("module %s\n", mod.Path)), nil
+	}
+	return out, nil
+}
+
+// Zip packages the module from the `git archive` of the version,
+// and leaves the spec of the module zip files, like the path prefixes, the excluded files and the size limits, to golang.org/x/mod/zip.
+// So the nested modules are left out of their parent's zip, and the archive is the same as the one the go command would create.
+func (src *GitSource) Zip(ctx context.Context, mod ProxyModule, version string, w io.Writer) error {
+	dir, commit, err := src.resolve(ctx, mod, version)
+	if err != nil {
+		return err
+	}
+	archive, err := os.CreateTemp("", "module-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	// the line endings are not normalized, so the archive is the same on every OS
+	args := []string{"-c", "core.autocrlf=input", "-c", "core.eol=lf", "archive", "--format=zip", commit}
+	if mod.Dir != "" {
+		args = append(args, mod.Dir)
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = archive
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git archive: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	info, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(archive, info.Size())
+	if err != nil {
+		return err
+	}
+
+	var (
+		files      []modzip.File
+		hasLicense bool
+		prefix     string
+	)
+	if mod.Dir != "" {
+		prefix = mod.Dir + "/"
+	}
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		files = append(files, archiveFile{name: name, file: f})
+		hasLicense = hasLicense || name == "LICENSE"
+	}
+	if !hasLicense && mod.Dir != "" {
+		// a nested module inherits the license of the repository, like it does with the go command
+		if license, err := git(ctx, dir, "cat-file", "blob", commit+":LICENSE"); err == nil {
+			files = append(files, blobFile{name: "LICENSE", data: license})
+		}
+	}
+	return modzip.Create(w, module.Version{Path: mod.Path, Version: version}, files)
+}
+
+// archiveFile is a file of the `git archive` output.
+type archiveFile struct {
+	name string
+	file *zip.File
+}
+
+func (f archiveFile) Path() string                 { return f.name }
+func (f archiveFile) Lstat() (fs.FileInfo, error)  { return f.file.FileInfo(), nil }
+func (f archiveFile) Open() (io.ReadCloser, error) { return f.file.Open() }
+
+// blobFile is a file read from a git object.
+type blobFile struct {
+	name string
+	data []byte
+}
+
+func (f blobFile) Path() string                 { return f.name }
+func (f blobFile) Lstat() (fs.FileInfo, error)  { return blobFileInfo(f), nil }
+func (f blobFile) Open() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(f.data)), nil }
+
+type blobFileInfo blobFile
+
+func (fi blobFileInfo) Name() string       { return path.Base(fi.name) }
+func (fi blobFileInfo) Size() int64        { return int64(len(fi.data)) }
+func (fi blobFileInfo) Mode() fs.FileMode  { return 0644 }
+func (fi blobFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi blobFileInfo) IsDir() bool        { return false }
+func (fi blobFileInfo) Sys() any           { return nil }
+
+// resolve finds the commit of a module version.
+// When the version's tag is unknown, the mirror is fetched again once.
+func (src *GitSource) resolve(ctx context.Context, mod ProxyModule, version string) (dir, commit string, _ error) {
+	ref := "refs/tags/" + tagPrefix(mod) + version + "^{commit}"
+	for _, refresh := range []bool{false, true} {
+		dir, err := src.mirror(ctx, mod.RepoRoot, refresh)
+		if err != nil {
+			return "", "", err
+		}
+		out, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", ref)
+		if err == nil {
+			return dir, strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", "", fmt.Errorf("%s@%s: %w", mod.Path, version, ErrNotFound)
+}
+
+// mirror makes sure that there is a local mirror of the repository, and returns its directory.
+func (src *GitSource) mirror(ctx context.Context, repoURL string, refresh bool) (string, error) {
+	sum := sha256.Sum256([]byte(repoURL))
+	dir := filepath.Join(src.Dir, hex.EncodeToString(sum[:8])+".git")
+
+	lock := src.lock(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.MkdirAll(src.Dir, 0755); err != nil {
+			return "", err
+		}
+		if _, err := git(ctx, "", "clone", "--mirror", "--quiet", repoURL, dir); err != nil {
+			return "", err
+		}
+	case err != nil:
+		return "", err
+	case refresh:
+		if _, err := git(ctx, dir, "fetch", "--prune", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func (src *GitSource) lock(dir string) *sync.Mutex {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+	if src.locks == nil {
+		src.locks = map[string]*sync.Mutex{}
+	}
+	if _, ok := src.locks[dir]; !ok {
+		src.locks[dir] = &sync.Mutex{}
+	}
+	return src.locks[dir]
+}
+
+// tagPrefix is the prefix of the version tags of a module.
+// The tags of a module in a subdirectory are prefixed with the directory, e.g. adapter/kafka/v1.0.0
+// A major version subdirectory is not part of the prefix, so the tags of adapter/kafka/v2 look like adapter/kafka/v2.0.0
+func tagPrefix(mod ProxyModule) string {
+	dir := mod.Dir
+	if _, major, ok := module.SplitPathVersion(mod.Path); ok && major != "" && path.Base(dir) == major[1:] {
+		dir = path.Dir(dir)
+	}
+	if dir == "" || dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// isModuleVersion tells whether the version is a canonical semantic version that belongs to the module's major version.
+func isModuleVersion(mod ProxyModule, version string) bool {
+	if semver.Canonical(version) != version {
+		return false
+	}
+	_, major, ok := module.SplitPathVersion(mod.Path)
+	return ok && module.CheckPathMajor(version, major) == nil
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return gitEnv(ctx, dir, nil, args...)
+}
+
+// gitEnv runs a git command with additional environment variables, like the author of a commit.
+func gitEnv(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	name := args[0]
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	// the remote helpers of git outlive a killed git, and they would keep its output open
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,204 @@
+package vanity
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// goImport is a go-import meta tag, as the go command reads it from a page.
+type goImport struct {
+	Prefix, VCS, RepoRoot string
+}
+
+func (im goImport) String() string {
+	return im.Prefix + " " + im.VCS + " " + im.RepoRoot
+}
+
+// parseGoImports reads the go-import meta tags of a page, the way the go command does.
+// It reads the head of the page leniently, like HTML rather than XML, and stops at the body.
+// The mod tags come first, since the go command prefers them.
+func parseGoImports(r io.Reader) ([]goImport, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "utf-8", "ascii":
+			return input, nil
+		default:
+			return nil, fmt.Errorf("can't decode XML document using charset %q", charset)
+		}
+	}
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var imports []goImport
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) || len(imports) > 0 {
+				break
+			}
+			return nil, err
+		}
+		if e, ok := t.(xml.StartElement); ok && strings.EqualFold(e.Name.Local, "body") {
+			break
+		}
+		if e, ok := t.(xml.EndElement); ok && strings.EqualFold(e.Name.Local, "head") {
+			break
+		}
+		e, ok := t.(xml.StartElement)
+		if !ok || !strings.EqualFold(e.Name.Local, "meta") || xmlAttr(e, "name") != "go-import" {
+			continue
+		}
+		if f := strings.Fields(xmlAttr(e, "content")); len(f) == 3 {
+			imports = append(imports, goImport{Prefix: f[0], VCS: f[1], RepoRoot: f[2]})
+		}
+	}
+	sort.SliceStable(imports, func(i, j int) bool {
+		return imports[i].VCS == VCSMod && imports[j].VCS != VCSMod
+	})
+	return imports, nil
+}
+
+func xmlAttr(e xml.StartElement, name string) string {
+	for _, attr := range e.Attr {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// matchGoImport picks the go-import meta tag of an import path, the way the go command does:
+// the tag's prefix is the import path, or a parent of it, and a single tag may match,
+// unless a mod tag matches, which wins over the rest.
+func matchGoImport(imports []goImport, importPath string) (goImport, error) {
+	match := -1
+	var mismatches []string
+	for i, im := range imports {
+		if importPath != im.Prefix && !strings.HasPrefix(importPath, im.Prefix+"/") {
+			mismatches = append(mismatches, im.Prefix)
+			continue
+		}
+		if match >= 0 {
+			if imports[match].VCS == VCSMod && im.VCS != VCSMod {
+				break
+			}
+			return goImport{}, fmt.Errorf("multiple meta tags match import path %q", importPath)
+		}
+		match = i
+	}
+	if match < 0 {
+		if len(mismatches) == 0 {
+			return goImport{}, fmt.Errorf("no go-import meta tags for %s", importPath)
+		}
+		return goImport{}, fmt.Errorf("go-import meta tags don't match %s (meta tags for %s)", importPath, strings.Join(mismatches, ", "))
+	}
+	return imports[match], nil
+}
+
+// Resolution is the repository that the go command resolves an import path to.
+type Resolution struct {
+	// ImportPath is the resolved import path.
+	ImportPath string
+	// Prefix is the import prefix of the matching go-import meta tag, which is the import path of the repository root.
+	Prefix string
+	// VCS and RepoRoot are the version control system and the URL of the repository.
+	VCS      string
+	RepoRoot string
+}
+
+// ResolveImportPath resolves an import path from the pages of a static site, the way the go command resolves a vanity import path,
+// without requesting the live domain:
+// it reads the go-import meta tags of the import path's page, picks the matching one,
+// verifies it on the page of the tag's prefix when that is a parent of the import path,
+// and checks that the version control system and the repository URL are usable.
+// The pages are looked up like a static host serves them, with the 404.html page for a missing page.
+func ResolveImportPath(site fs.FS, domain, importPath string) (Resolution, error) {
+	if err := module.CheckPath(importPath); err != nil {
+		return Resolution{}, err
+	}
+	im, err := resolveGoImport(site, domain, importPath, importPath)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if im.Prefix != importPath {
+		// the go command doesn't trust the page of a package path alone, its repository root has to agree
+		root, err := resolveGoImport(site, domain, im.Prefix, importPath)
+		if err != nil {
+			return Resolution{}, err
+		}
+		if root != im {
+			return Resolution{}, fmt.Errorf("%s and %s disagree about go-import for %s", importPath, im.Prefix, importPath)
+		}
+	}
+	switch im.VCS {
+	case VCSGit, VCSMercurial, VCSSubversion, VCSBazaar, VCSFossil, VCSMod:
+	default:
+		return Resolution{}, fmt.Errorf("%s: unknown vcs %q", im.Prefix, im.VCS)
+	}
+	repoRoot, err := url.Parse(im.RepoRoot)
+	switch {
+	case err != nil:
+		return Resolution{}, fmt.Errorf("%s: invalid repo root %q: %w", im.Prefix, im.RepoRoot, err)
+	case repoRoot.Scheme == "":
+		return Resolution{}, fmt.Errorf("%s: invalid repo root %q: no scheme", im.Prefix, im.RepoRoot)
+	case repoRoot.Scheme == "file":
+		return Resolution{}, fmt.Errorf("%s: invalid repo root %q: file scheme disallowed", im.Prefix, im.RepoRoot)
+	}
+	return Resolution{ImportPath: importPath, Prefix: im.Prefix, VCS: im.VCS, RepoRoot: im.RepoRoot}, nil
+}
+
+// resolveGoImport reads the page of a path from the site, and picks the go-import meta tag of the import path.
+func resolveGoImport(site fs.FS, domain, pagePath, importPath string) (goImport, error) {
+	rel, ok := strings.CutPrefix(pagePath, domain)
+	if !ok || (rel != "" && rel[0] != '/') {
+		return goImport{}, fmt.Errorf("%s is not under the %s domain", pagePath, domain)
+	}
+	page, found, err := sitePage(site, strings.Trim(rel, "/"))
+	if err != nil {
+		return goImport{}, err
+	}
+	imports, err := parseGoImports(bytes.NewReader(page))
+	if err != nil {
+		return goImport{}, fmt.Errorf("parsing %s: %w", pagePath, err)
+	}
+	if len(imports) == 0 && !found {
+		return goImport{}, fmt.Errorf("no page in the site for %s", pagePath)
+	}
+	return matchGoImport(imports, importPath)
+}
+
+// sitePage reads the page that a static host serves on a path of the site:
+// the index.html of the path's directory, or the path's .html file.
+// When neither exists, it reads the 404.html page of the site, if there is one, and tells that the page wasn't found.
+func sitePage(site fs.FS, name string) ([]byte, bool, error) {
+	candidates := []string{path.Join(name, "index.html")}
+	if name != "" {
+		candidates = append(candidates, name+".html")
+	}
+	for _, candidate := range candidates {
+		content, err := fs.ReadFile(site, candidate)
+		if err == nil {
+			return content, true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, false, err
+		}
+	}
+	content, err := fs.ReadFile(site, "404.html")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+	return content, false, nil
+}
@@ -0,0 +1,122 @@
+package vanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.llib.dev/frameless/adapter/localfs"
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// DiffGolden renders the site, and compares it against its golden snapshot in a directory, like testdata/golden,
+// which makes the effect of a change to the templates or to the imports file visible in code review.
+// Unlike Plan, the whole directory is compared: a file of the snapshot that the site doesn't have is a ChangeDelete,
+// and there is no manifest with a generation time, so the snapshot only changes when the site does.
+// The site of a multi-domain or wildcard Generator is compared in a subdirectory per domain, like WriteDir writes it.
+func (g *Generator) DiffGolden(ctx context.Context, dir string) ([]Change, error) {
+	var files []File
+	if sites := g.Sites(); 0 < len(sites) {
+		for _, domain := range sites {
+			sub := g.ForDomain(domain)
+			siteFiles, err := sub.goldenFiles(ctx, localfs.FileSystem{RootPath: filepath.Join(dir, domain)})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", domain, err)
+			}
+			for _, file := range siteFiles {
+				file.Path = path.Join(domain, file.Path)
+				files = append(files, file)
+			}
+		}
+	} else {
+		siteFiles, err := g.goldenFiles(ctx, localfs.FileSystem{RootPath: dir})
+		if err != nil {
+			return nil, err
+		}
+		files = siteFiles
+	}
+	return diffGolden(localfs.FileSystem{RootPath: dir}, files)
+}
+
+// UpdateGolden renders the site into its golden snapshot directory, and returns the changes of the snapshot.
+func (g *Generator) UpdateGolden(ctx context.Context, dir string) ([]Change, error) {
+	changes, err := g.DiffGolden(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureDirectory(dir); err != nil {
+		return nil, err
+	}
+	return changes, applyChanges(ctx, localfs.FileSystem{RootPath: dir}, changes)
+}
+
+// VerifyGolden renders the site, and fails with the list of the differing files when it differs from its golden snapshot.
+// It suits a Go test of a vanity domain's imports file and templates, next to the snapshot in its testdata.
+func (g *Generator) VerifyGolden(ctx context.Context, dir string) error {
+	changes, err := g.DiffGolden(ctx, dir)
+	if err != nil {
+		return err
+	}
+	var differ []string
+	for _, change := range changes {
+		if change.Kind != ChangeUnchanged {
+			differ = append(differ, fmt.Sprintf("%s %s", change.Kind, change.File.Path))
+		}
+	}
+	if 0 < len(differ) {
+		return fmt.Errorf("the site differs from the golden snapshot in %s:\n%s", dir, strings.Join(differ, "\n"))
+	}
+	return nil
+}
+
+// goldenFiles renders the files of a single site, with the last modification times of the sitemap kept from the snapshot.
+func (g *Generator) goldenFiles(ctx context.Context, fsys filesystem.FileSystem) ([]File, error) {
+	files, err := g.Render(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return g.completeSite(fsys, files)
+}
+
+// diffGolden compares the rendered files against the snapshot, whose every other file is to be deleted.
+func diffGolden(fsys filesystem.FileSystem, files []File) ([]Change, error) {
+	var (
+		changes  []Change
+		rendered = map[string]struct{}{}
+	)
+	for _, file := range files {
+		rendered[file.Path] = struct{}{}
+		change, err := compareFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if _, err := fsys.Stat("."); errors.Is(err, fs.ErrNotExist) {
+		// there is no snapshot yet
+		return changes, nil
+	}
+	err := filesystem.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := filepath.ToSlash(name)
+		if _, ok := rendered[rel]; ok {
+			return nil
+		}
+		old, err := readFile(fsys, rel)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, Change{Kind: ChangeDelete, File: File{Path: rel}, Old: old})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,117 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// isGoWork tells whether the imports location is a Go workspace file.
+func isGoWork(location string) bool {
+	return filepath.Base(location) == "go.work"
+}
+
+// readImportsWork turns the Go workspace file of a local monorepo into an imports entry,
+// so the workspace stays the single source of truth of the monorepo's modules.
+// The import prefix is the module path of the repository root,
+// every other module of the use directives becomes a nested module,
+// and the repository is the origin remote of the checkout.
+func readImportsWork(ctx context.Context, workPath string) ([]ImportDTO, error) {
+	data, err := os.ReadFile(workPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open go.work file: %w", err)
+	}
+	work, err := modfile.ParseWork(workPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(workPath)
+
+	var (
+		prefix     string
+		submodules []string
+		modules    = map[string]string{}
+	)
+	for _, use := range work.Use {
+		dir := path.Clean(filepath.ToSlash(use.Path))
+		if filepath.IsAbs(use.Path) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, fmt.Errorf("go.work: %s is outside of the repository", use.Path)
+		}
+		modulePath, err := readModulePath(filepath.Join(root, filepath.FromSlash(dir), "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("go.work: %w", err)
+		}
+		if dir == "." {
+			prefix = modulePath
+			continue
+		}
+		modules[dir] = modulePath
+		submodules = append(submodules, dir)
+	}
+	if prefix == "" {
+		// without a module in the repository root, the prefix is told by the path of a nested module
+		for dir, modulePath := range modules {
+			if p, ok := strings.CutSuffix(modulePath, "/"+dir); ok {
+				prefix = p
+				break
+			}
+		}
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("go.work: unable to tell the import prefix of the repository")
+	}
+	for dir, modulePath := range modules {
+		if expected := path.Join(prefix, dir); modulePath != expected {
+			return nil, fmt.Errorf("go.work: the module in %s is %s, but its import path is %s", dir, modulePath, expected)
+		}
+	}
+
+	repo, err := originURL(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("go.work: unable to tell the repository of the workspace: %w", err)
+	}
+	return []ImportDTO{{
+		VCS:          "git",
+		ImportPrefix: prefix,
+		RootRepo:     repo,
+		Submodules:   submodules,
+	}}, nil
+}
+
+func readModulePath(goModPath string) (string, error) {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return "", err
+	}
+	modulePath := modfile.ModulePath(data)
+	if modulePath == "" {
+		return "", fmt.Errorf("%s has no module directive", goModPath)
+	}
+	return modulePath, nil
+}
+
+// originURL is the web URL of the checkout's origin remote.
+// An SSH remote, like git@github.com:org/repo.git, is turned into its https://github.com/org/repo form.
+func originURL(ctx context.Context, dir string) (string, error) {
+	out, err := git(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	remote := strings.TrimSpace(string(out))
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		u.Scheme, u.User = "https", nil
+		return strings.TrimSuffix(u.String(), ".git"), nil
+	}
+	if user, rest, ok := strings.Cut(remote, "@"); ok && !strings.Contains(user, "/") {
+		if host, repoPath, ok := strings.Cut(rest, ":"); ok {
+			return "https://" + host + "/" + strings.TrimSuffix(strings.TrimPrefix(repoPath, "/"), ".git"), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported origin remote: %s", remote)
+}
@@ -0,0 +1,184 @@
+package vanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// Catalog is the texts of the pages in a language, by their message keys, like documentation or gone.notice.
+// A text can have the fmt verbs of its arguments, like "%s is no longer available.".
+type Catalog map[string]string
+
+// defaultCatalog is the English texts of the pages, which fill the gaps of the other catalogs.
+var defaultCatalog = Catalog{
+	"gone":               "Gone:",
+	"gone.notice":        "%s is no longer available.",
+	"moved":              "Moved:",
+	"moved.notice":       "%s is now",
+	"deprecated":         "Deprecated:",
+	"replacement.before": "Use",
+	"replacement.after":  "instead.",
+	"redirect.before":    "Redirecting to",
+	"redirect.after":     "in %d seconds.",
+	"documentation":      "Documentation",
+	"versions":           "Versions",
+	"changelog":          "Changelog",
+	"license":            "License",
+	"repository":         "Repository",
+	"module":             "Module",
+	"description":        "Description",
+	"index.moved":        "moved to",
+	"index.deprecated":   "deprecated",
+	"index.other":        "Other",
+	"index.search":       "Search modules",
+}
+
+// translator is the t function of the templates, which formats the text of a message key in the language of the catalog,
+// like {{ t "gone.notice" .Import.Prefix }}.
+func translator(catalog Catalog) func(key string, args ...any) (string, error) {
+	return func(key string, args ...any) (string, error) {
+		text, ok := catalog[key]
+		if !ok {
+			if text, ok = defaultCatalog[key]; !ok {
+				return "", fmt.Errorf("unknown message key: %s", key)
+			}
+		}
+		if len(args) == 0 {
+			return text, nil
+		}
+		return fmt.Sprintf(text, args...), nil
+	}
+}
+
+// withLocale gives the template functions of a language, where t translates a message, and lang tells the language tag.
+func withLocale(funcs template.FuncMap, lang string, catalog Catalog) template.FuncMap {
+	localized := template.FuncMap{}
+	for name, fn := range funcs {
+		localized[name] = fn
+	}
+	localized["t"] = translator(catalog)
+	localized["lang"] = func() string { return lang }
+	return localized
+}
+
+// rootLanguage is the language of the pages at the root of the site, and of the server's pages by default.
+func rootLanguage(lang string) string {
+	return zerokit.Coalesce(lang, "en")
+}
+
+// ReadCatalogs reads the catalogs of a directory, which has a JSON file of the texts for every language,
+// named by the language tag, like de.json or pt-br.json.
+// The language tags of the catalogs are in lower case.
+func ReadCatalogs(fsys fs.FS) (map[string]Catalog, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("the locales directory has no .json files")
+	}
+	catalogs := map[string]Catalog{}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("failed to decode the %s catalog: %w", name, err)
+		}
+		for key := range catalog {
+			if _, ok := defaultCatalog[key]; !ok {
+				return nil, fmt.Errorf("the %s catalog has an unknown message key: %s", name, key)
+			}
+		}
+		catalogs[strings.ToLower(strings.TrimSuffix(name, ".json"))] = catalog
+	}
+	return catalogs, nil
+}
+
+// locale is a language of the site besides the language of the root pages,
+// whose pages are written into the directory of the language.
+type locale struct {
+	lang  string
+	funcs template.FuncMap
+	tmpl  *template.Template
+}
+
+// siteLocales are the languages of the site besides the language of the root pages, in the order of their tags,
+// with the templates of the module pages cloned for each, so they have to be made before the template is executed.
+func siteLocales(tmpl *template.Template, funcs template.FuncMap, opts GenerateOptions) ([]locale, error) {
+	var locales []locale
+	for lang, catalog := range opts.Catalogs {
+		if lang == rootLanguage(opts.Language) {
+			continue
+		}
+		clone, err := tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		localized := withLocale(funcs, lang, catalog)
+		locales = append(locales, locale{lang: lang, funcs: localized, tmpl: clone.Funcs(localized)})
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i].lang < locales[j].lang })
+	return locales, nil
+}
+
+// renderLocalizedModule renders the pages of a module in the languages of the site, into the directories of the languages.
+// The badges, the shields, the API documentation and the version listings aren't translated, so they are only at the root of the site.
+func renderLocalizedModule(locales []locale, domain string, meta Meta, opts GenerateOptions) ([]File, error) {
+	opts.Badges, opts.Shields, opts.APIDocs, opts.Versions = false, false, false, false
+	var files []File
+	for _, l := range locales {
+		pages, err := renderModule(l.tmpl, domain, meta, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.lang, err)
+		}
+		for _, page := range pages {
+			page.Path = path.Join(l.lang, page.Path)
+			files = append(files, page)
+		}
+	}
+	return files, nil
+}
+
+// NegotiateLanguage picks the language of a response from the Accept-Language header of the request,
+// among the languages of the catalogs and the default language.
+// The languages are matched by their tags in lower case, or by their base language, so de-AT gets de.
+func NegotiateLanguage(acceptLanguage, defaultLanguage string, catalogs map[string]Catalog) string {
+	var (
+		best    = defaultLanguage
+		quality = 0.0
+	)
+	defaultLanguage = strings.ToLower(defaultLanguage)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= quality {
+			continue
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		for _, candidate := range []string{tag, strings.SplitN(tag, "-", 2)[0]} {
+			if _, ok := catalogs[candidate]; ok || candidate == defaultLanguage {
+				best, quality = candidate, q
+				break
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,315 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// var findURL = regexp.MustCompile(`https?://[^\s+]+`)
+
+// ImportsOptions configure how the imports file is read.
+type ImportsOptions struct {
+	// Format forces the format of the imports file.
+	// When left empty, the format is detected from the file extension.
+	Format string
+	// Strict turns off the tolerant parsing of JSON imports files.
+	Strict bool
+	// Remote configures the fetching of an imports file from an http(s) URL.
+	Remote RemoteImportsConfig
+}
+
+type ImportDTO struct {
+	VCS              string   `json:"vcs" yaml:"vcs" toml:"vcs"`
+	ImportPrefix     string   `json:"import-prefix" yaml:"import-prefix" toml:"import-prefix"`
+	RootRepo         string   `json:"root-repo" yaml:"root-repo" toml:"root-repo"`
+	HomepageURL      string   `json:"homepage,omitempty" yaml:"homepage,omitempty" toml:"homepage,omitempty"`
+	DirectoryPattern string   `json:"directory-pattern,omitempty" yaml:"directory-pattern,omitempty" toml:"directory-pattern,omitempty"`
+	FilePattern      string   `json:"file-pattern,omitempty" yaml:"file-pattern,omitempty" toml:"file-pattern,omitempty"`
+	Submodules       []string `json:"submodules,omitempty" yaml:"submodules,omitempty" toml:"submodules,omitempty"`
+	Redirect         []string `json:"redirect,omitempty" yaml:"redirect,omitempty" toml:"redirect,omitempty"`
+	DocsURL          string   `json:"docs-url,omitempty" yaml:"docs-url,omitempty" toml:"docs-url,omitempty"`
+	RedirectMode     string   `json:"redirect-mode,omitempty" yaml:"redirect-mode,omitempty" toml:"redirect-mode,omitempty"`
+	RedirectDelay    int      `json:"redirect-delay,omitempty" yaml:"redirect-delay,omitempty" toml:"redirect-delay,omitempty"`
+	Private          bool     `json:"private,omitempty" yaml:"private,omitempty" toml:"private,omitempty"`
+	Branch           string   `json:"branch,omitempty" yaml:"branch,omitempty" toml:"branch,omitempty"`
+	Forge            string   `json:"forge,omitempty" yaml:"forge,omitempty" toml:"forge,omitempty"`
+	Origin           string   `json:"origin,omitempty" yaml:"origin,omitempty" toml:"origin,omitempty"`
+	Majors           []string `json:"majors,omitempty" yaml:"majors,omitempty" toml:"majors,omitempty"`
+	Deprecated       string   `json:"deprecated,omitempty" yaml:"deprecated,omitempty" toml:"deprecated,omitempty"`
+	Replacement      string   `json:"replacement,omitempty" yaml:"replacement,omitempty" toml:"replacement,omitempty"`
+	Type             string   `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	MovedTo          string   `json:"moved-to,omitempty" yaml:"moved-to,omitempty" toml:"moved-to,omitempty"`
+	Description      string   `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	Category         string   `json:"category,omitempty" yaml:"category,omitempty" toml:"category,omitempty"`
+	Tags             []string `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+	NoCrawl          bool     `json:"no-crawl,omitempty" yaml:"no-crawl,omitempty" toml:"no-crawl,omitempty"`
+	Title            string   `json:"title,omitempty" yaml:"title,omitempty" toml:"title,omitempty"`
+	Image            string   `json:"image,omitempty" yaml:"image,omitempty" toml:"image,omitempty"`
+	Template         string   `json:"template,omitempty" yaml:"template,omitempty" toml:"template,omitempty"`
+}
+
+// ImportsTOMLDTO is the document shape of a TOML imports file.
+// TOML has no top-level arrays, so the entries are listed as [[modules]] tables.
+type ImportsTOMLDTO struct {
+	Modules []ImportDTO `toml:"modules"`
+}
+
+// ToMeta turns an entry of the imports file into the meta data of the module's pages.
+func ToMeta(dto ImportDTO) (Meta, error) {
+	vcsRepoRoot, err := url.Parse(dto.RootRepo)
+	if err != nil {
+		return Meta{}, fmt.Errorf("failed to parse vcs repo root: %w", err)
+	}
+
+	imp := MetaImport{
+		Prefix: dto.ImportPrefix,
+		VCS: MetaImportVCS{
+			Name:     dto.VCS,
+			RepoRoot: vcsRepoRoot,
+		},
+	}
+	if dto.Origin != "" {
+		imp.VCS.Origin, err = url.Parse(dto.Origin)
+		if err != nil {
+			return Meta{}, fmt.Errorf("failed to parse origin: %w", err)
+		}
+	}
+
+	src := defaultSource(imp, MetaSource{
+		HomepageURL:      dto.HomepageURL,
+		DirectoryPattern: dto.DirectoryPattern,
+		FilePattern:      dto.FilePattern,
+		Branch:           dto.Branch,
+		Forge:            dto.Forge,
+	})
+
+	var nested []MetaNestedModule
+	for _, sub := range dto.Submodules {
+		sub = path.Clean(strings.Trim(sub, "/"))
+		nested = append(nested, MetaNestedModule{
+			Path:       sub,
+			ImportPath: path.Join(imp.Prefix, sub),
+		})
+	}
+
+	var majors []string
+	for _, major := range dto.Majors {
+		majors = append(majors, path.Join(imp.Prefix, path.Clean(strings.Trim(major, "/"))))
+	}
+
+	return Meta{
+		Import: imp,
+		Source: src,
+		Nested: nested,
+		Majors: majors,
+		Redirect: MetaRedirect{
+			Targets: dto.Redirect,
+			DocsURL: dto.DocsURL,
+			Mode:    dto.RedirectMode,
+			Delay:   time.Duration(dto.RedirectDelay) * time.Second,
+		},
+		Private: dto.Private,
+		Deprecation: MetaDeprecation{
+			Message:     dto.Deprecated,
+			Replacement: dto.Replacement,
+		},
+		Tombstone: MetaTombstone{
+			Kind:    dto.Type,
+			MovedTo: dto.MovedTo,
+		},
+		Description: dto.Description,
+		Category:    dto.Category,
+		Tags:        dto.Tags,
+		NoCrawl:     dto.NoCrawl,
+		Title:       dto.Title,
+		Image:       dto.Image,
+		Template:    dto.Template,
+	}, nil
+}
+
+// ReadImports reads the import entries from the imports file,
+// or when the path points to a directory, from every module file in that directory.
+// An http(s) URL is fetched from the remote location,
+// and a go.work file is turned into the entry of its repository.
+func ReadImports(ctx context.Context, path string, opts ImportsOptions) ([]ImportDTO, error) {
+	if IsRemoteImports(path) {
+		return readImportsURL(ctx, path, opts)
+	}
+	if isGoWork(path) {
+		return readImportsWork(ctx, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open imports file: %w", err)
+	}
+	if info.IsDir() {
+		return readImportsDir(path, opts)
+	}
+	return readImportsFile(path, opts)
+}
+
+// PullImports fast-forwards the git checkout that holds the imports file, or the directory of module files,
+// so a pushed change of the imports reaches the local copy of them.
+// An http(s) URL is fetched on every read anyway, so there is nothing to pull.
+func PullImports(ctx context.Context, location string) error {
+	if IsRemoteImports(location) {
+		return nil
+	}
+	dir := location
+	if info, err := os.Stat(location); err != nil || !info.IsDir() {
+		dir = filepath.Dir(location)
+	}
+	_, err := git(ctx, dir, "pull", "--ff-only", "--quiet")
+	return err
+}
+
+func readImportsFile(filePath string, opts ImportsOptions) ([]ImportDTO, error) {
+	// Open the file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil,
+			fmt.Errorf("failed to open imports file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := ImportsFormat(filePath, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos, err := decodeImports(format, data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s imports file: %w", format, err)
+	}
+	return dtos, nil
+}
+
+// readImportsDir reads a directory (e.g. imports.d/) where each file describes a single module.
+// Files are read in lexical order, hidden files and files with an unknown extension are ignored.
+// Every error mentions the file that caused it.
+func readImportsDir(dirPath string, opts ImportsOptions) ([]ImportDTO, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imports directory: %w", err)
+	}
+
+	var (
+		dtos    []ImportDTO
+		sources = map[string]string{}
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		format, err := ImportsFormat(entry.Name(), opts.Format)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dirPath, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		dto, err := decodeImport(format, data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to decode %s module file: %w", entry.Name(), format, err)
+		}
+		if src, ok := sources[dto.ImportPrefix]; ok {
+			return nil, fmt.Errorf("%s: duplicate import prefix %q, it is already defined in %s",
+				entry.Name(), dto.ImportPrefix, src)
+		}
+		sources[dto.ImportPrefix] = entry.Name()
+		dtos = append(dtos, dto)
+	}
+	return dtos, nil
+}
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ImportsFormat tells which format the imports file uses.
+// An explicitly requested format takes precedence over the file extension.
+func ImportsFormat(filePath, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filePath), ".")
+	}
+	switch strings.ToLower(format) {
+	case "json", "jsonc":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("unknown imports file format: %q", format)
+	}
+}
+
+func decodeImports(format string, data []byte, opts ImportsOptions) ([]ImportDTO, error) {
+	var dtos []ImportDTO
+	switch format {
+	case FormatJSON:
+		if !opts.Strict {
+			data = StandardizeJSON(data)
+		}
+		if err := json.Unmarshal(data, &dtos); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &dtos); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		var doc ImportsTOMLDTO
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		dtos = doc.Modules
+	default:
+		return nil, fmt.Errorf("unknown imports file format: %q", format)
+	}
+	return dtos, nil
+}
+
+// decodeImport decodes a single module entry, as found in the files of an imports directory.
+func decodeImport(format string, data []byte, opts ImportsOptions) (ImportDTO, error) {
+	var dto ImportDTO
+	switch format {
+	case FormatJSON:
+		if !opts.Strict {
+			data = StandardizeJSON(data)
+		}
+		if err := json.Unmarshal(data, &dto); err != nil {
+			return dto, err
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &dto); err != nil {
+			return dto, err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &dto); err != nil {
+			return dto, err
+		}
+	default:
+		return dto, fmt.Errorf("unknown imports file format: %q", format)
+	}
+	return dto, nil
+}
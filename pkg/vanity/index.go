@@ -0,0 +1,148 @@
+package vanity
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+const (
+	// indexFileName is the page of the domain root.
+	indexFileName = "index.html"
+	// indexJSONFileName is the machine-readable catalogue of the modules next to the index page, for tooling.
+	indexJSONFileName = "index.json"
+)
+
+//go:embed index.html
+var indexHTML string
+
+// IndexPage is the data of the index page template.
+type IndexPage struct {
+	// Domain is the vanity domain, e.g. go.llib.dev
+	Domain string
+	// Modules are the public modules of the domain, in the order of their import paths.
+	Modules []IndexModule
+	// Categories are the modules grouped by their category, in the order of the category names,
+	// with the modules without a category in the last group, whose Name is empty.
+	Categories []IndexCategory
+	// SearchIndexURL is the URL of the search index, when the site has one, which the search box of the page fetches.
+	SearchIndexURL string
+}
+
+// IndexModule is a module in the catalogue of the index page.
+type IndexModule struct {
+	ImportPath  string   `json:"import-path"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// License is the SPDX identifier of the module's license, when it was detected.
+	License string `json:"license,omitempty"`
+	// Repository is the web page of the module's source repository.
+	Repository string `json:"repository,omitempty"`
+	// PkgGoDevURL is the documentation of the module on pkg.go.dev.
+	PkgGoDevURL string `json:"documentation"`
+	// Deprecated is the deprecation message of the module.
+	Deprecated string `json:"deprecated,omitempty"`
+	// Replacement is the import path of the module that replaces a deprecated module.
+	Replacement string `json:"replacement,omitempty"`
+	// MovedTo is the new import path of a moved module.
+	MovedTo string `json:"moved-to,omitempty"`
+}
+
+// IndexCategory is a group of the modules on the index page.
+type IndexCategory struct {
+	Name    string
+	Modules []IndexModule
+}
+
+// Categorized tells whether the modules have categories, so the index page groups them.
+func (p IndexPage) Categorized() bool {
+	return 0 < len(p.Categories) && p.Categories[0].Name != ""
+}
+
+// newIndexPage collects the catalogue of the modules of the domain.
+// The private and the gone modules are left out.
+func newIndexPage(domain string, metas []Meta) (IndexPage, error) {
+	data := IndexPage{Domain: domain}
+	for _, meta := range metas {
+		if meta.Import.Prefix == domain {
+			return IndexPage{}, fmt.Errorf("the index page would replace the page of the %s module", meta.Import.Prefix)
+		}
+		if meta.Private || meta.Gone() || !strings.HasPrefix(meta.Import.Prefix, domain+"/") {
+			continue
+		}
+		var repo string
+		if src := meta.Import.VCS.SourceRepo(); src.RepoRoot != nil {
+			repo = src.RepoRoot.String()
+		}
+		data.Modules = append(data.Modules, IndexModule{
+			ImportPath:  meta.Import.Prefix,
+			Description: meta.Description,
+			Category:    meta.Category,
+			Tags:        meta.Tags,
+			License:     meta.Licenses[meta.Import.Prefix],
+			Repository:  repo,
+			PkgGoDevURL: "https://pkg.go.dev/" + meta.Import.Prefix,
+			Deprecated:  meta.Deprecation.Message,
+			Replacement: meta.Deprecation.Replacement,
+			MovedTo:     meta.Tombstone.MovedTo,
+		})
+	}
+	sort.Slice(data.Modules, func(i, j int) bool {
+		return data.Modules[i].ImportPath < data.Modules[j].ImportPath
+	})
+
+	categories := map[string][]IndexModule{}
+	for _, module := range data.Modules {
+		categories[module.Category] = append(categories[module.Category], module)
+	}
+	for name, modules := range categories {
+		data.Categories = append(data.Categories, IndexCategory{Name: name, Modules: modules})
+	}
+	sort.Slice(data.Categories, func(i, j int) bool {
+		a, b := data.Categories[i].Name, data.Categories[j].Name
+		if a == "" || b == "" {
+			return b == ""
+		}
+		return a < b
+	})
+	return data, nil
+}
+
+// renderIndex renders the catalogue of the modules into the page of the domain root.
+// A custom template text replaces the default template, and it gets an IndexPage as its data.
+func renderIndex(data IndexPage, tmplText string, funcs template.FuncMap) (File, error) {
+	if tmplText == "" {
+		tmplText = indexHTML
+	}
+	// the theme templates are parsed first, so a custom template can redefine them
+	tmpl, err := template.Must(template.New("index").Funcs(funcs).Parse(themeHTML)).Parse(tmplText)
+	if err != nil {
+		return File{}, fmt.Errorf("index template parsing failed: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return File{}, fmt.Errorf("index template execution failed: %w", err)
+	}
+	return File{Path: indexFileName, Content: buf.Bytes()}, nil
+}
+
+// renderIndexJSON renders the catalogue of the modules for tooling, like a search or a dashboard of the organization.
+func renderIndexJSON(data IndexPage) (File, error) {
+	modules := data.Modules
+	if modules == nil {
+		modules = []IndexModule{}
+	}
+	content, err := json.MarshalIndent(struct {
+		Domain  string        `json:"domain"`
+		Modules []IndexModule `json:"modules"`
+	}{Domain: data.Domain, Modules: modules}, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: indexJSONFileName, Content: append(content, '\n')}, nil
+}
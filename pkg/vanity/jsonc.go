@@ -0,0 +1,66 @@
+package vanity
+
+// StandardizeJSON turns a commented JSON (JSONC) document into standard JSON.
+// Line and block comments, and trailing commas in objects and arrays are replaced with whitespace,
+// so the offsets in the decoding errors still point to the right place in the original file.
+func StandardizeJSON(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	var (
+		inString   bool
+		lastComma  = -1
+		blankRange = func(from, to int) {
+			for i := from; i < to; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+		}
+	)
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			lastComma = -1
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blankRange(i, end)
+			i = end - 1
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end+1 < len(out) && !(out[end] == '*' && out[end+1] == '/') {
+				end++
+			}
+			if end += 2; end > len(out) {
+				end = len(out)
+			}
+			blankRange(i, end)
+			i = end - 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
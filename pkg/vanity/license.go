@@ -0,0 +1,120 @@
+package vanity
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// licenseNames are the file names of a license, in the order of their preference.
+var licenseNames = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "LICENCE.md", "COPYING", "COPYING.md", "License.md"}
+
+// licensePhrases identify the licenses by the phrases of their texts, by their SPDX identifiers.
+// The GNU licenses are told apart by their titles, since their texts refer to each other.
+// The licenses whose texts contain the phrases of another are listed before it, like the BSD-3-Clause before the BSD-2-Clause.
+var licensePhrases = []struct {
+	id      string
+	phrases []string
+}{
+	{id: "AGPL-3.0", phrases: []string{"gnu affero general public license version 3, 19 november 2007"}},
+	{id: "LGPL-3.0", phrases: []string{"gnu lesser general public license version 3, 29 june 2007"}},
+	{id: "LGPL-2.1", phrases: []string{"gnu lesser general public license version 2.1, february 1999"}},
+	{id: "GPL-3.0", phrases: []string{"gnu general public license version 3, 29 june 2007"}},
+	{id: "GPL-2.0", phrases: []string{"gnu general public license version 2, june 1991"}},
+	{id: "Apache-2.0", phrases: []string{"apache license", "version 2.0, january 2004"}},
+	{id: "MPL-2.0", phrases: []string{"mozilla public license version 2.0"}},
+	{id: "EPL-2.0", phrases: []string{"eclipse public license - v 2.0"}},
+	{id: "BSL-1.0", phrases: []string{"boost software license - version 1.0"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+	{id: "CC0-1.0", phrases: []string{"cc0 1.0 universal"}},
+	{id: "MIT", phrases: []string{"permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{id: "BSD-3-Clause", phrases: []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{id: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}},
+	{id: "ISC", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose", "copyright notice and this permission notice appear in all copies"}},
+	{id: "0BSD", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+}
+
+// spdxIdentifier matches the SPDX license identifier tag of a file, like SPDX-License-Identifier: MIT
+var spdxIdentifier = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+-]+)`)
+
+// ClassifyLicense tells the SPDX identifier of a license text, like MIT or Apache-2.0,
+// by its SPDX-License-Identifier tag, or by the phrases of the well-known licenses.
+// It returns an empty string for a license that it doesn't recognize.
+func ClassifyLicense(text []byte) string {
+	if m := spdxIdentifier.FindSubmatch(text); m != nil {
+		return string(m[1])
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(string(text)), " "))
+	for _, license := range licensePhrases {
+		matches := true
+		for _, phrase := range license.phrases {
+			matches = matches && strings.Contains(normalized, phrase)
+		}
+		if matches {
+			return license.id
+		}
+	}
+	return ""
+}
+
+// DetectLicenses sets the licenses of the modules and their nested modules,
+// from the license files of the default branch of their git and Mercurial repositories.
+// A nested module without a license file of its own has the license of the repository root.
+// A repository that can't be reached is only warned about, and its modules are left without a license,
+// like the modules whose license is not recognized.
+func DetectLicenses(ctx context.Context, metas []Meta) []Meta {
+	for i, meta := range metas {
+		if meta.Private || meta.Tombstone.Kind != "" || !isClonable(meta.Import.VCS.SourceRepo().Name) {
+			continue
+		}
+		licenses, err := detectLicenses(ctx, meta)
+		if err != nil {
+			logger.Warn(ctx, "license detection failed",
+				logging.Field("module", meta.Import.Prefix),
+				logging.ErrField(err))
+			continue
+		}
+		metas[i].Licenses = licenses
+	}
+	return metas
+}
+
+func detectLicenses(ctx context.Context, meta Meta) (_ map[string]string, rErr error) {
+	repo := meta.Import.VCS.SourceRepo()
+	clone, err := cloneRepo(ctx, repo.Name, repo.RepoRoot.String())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	licenseOf := func(dir string) (string, bool) {
+		for _, name := range licenseNames {
+			data, err := clone.ReadFile(ctx, path.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			return ClassifyLicense(data), true
+		}
+		return "", false
+	}
+	licenses := map[string]string{}
+	root, _ := licenseOf("")
+	if root != "" {
+		licenses[meta.Import.Prefix] = root
+	}
+	for _, nested := range meta.Nested {
+		license, ok := licenseOf(nested.Path)
+		if !ok {
+			license = root
+		}
+		if license != "" {
+			licenses[nested.ImportPath] = license
+		}
+	}
+	return licenses, nil
+}
@@ -0,0 +1,138 @@
+package vanity
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The severities of the lint findings.
+const (
+	// SeverityError is a finding that breaks the pages, or the go get of a module.
+	SeverityError = "error"
+	// SeverityWarning is a finding that likely works, but probably isn't what was meant.
+	SeverityWarning = "warning"
+)
+
+// LintFinding is a problem of an entry of the imports file, with its severity.
+type LintFinding struct {
+	ValidationError
+	// Severity is error or warning.
+	Severity string
+}
+
+// LintImports checks the entries of the imports file one by one, like ValidateImports,
+// and also against each other, which the validation doesn't do:
+// duplicate import paths, prefixes that shadow each other, submodule paths that escape their repository,
+// repositories that are the root of more than one prefix, and import paths that fall on the ReservedPaths of the server.
+// It reports every finding, ordered by the entries, rather than stopping at the first one.
+func LintImports(dtos []ImportDTO, domains ...string) []LintFinding {
+	var findings []LintFinding
+	for _, err := range validateImports(dtos, domains) {
+		findings = append(findings, LintFinding{ValidationError: err, Severity: SeverityError})
+	}
+	report := func(i int, severity, field, format string, args ...any) {
+		findings = append(findings, LintFinding{
+			ValidationError: ValidationError{Index: i, Prefix: dtos[i].ImportPrefix, Field: field, Message: fmt.Sprintf(format, args...)},
+			Severity:        severity,
+		})
+	}
+	entry := func(i int) string {
+		return fmt.Sprintf("entry #%d (%s)", i, dtos[i].ImportPrefix)
+	}
+
+	// the import paths that get a page, which are the prefixes, and the paths of the submodules and the major versions
+	owners := map[string]int{}
+	claim := func(i int, field, importPath string) {
+		if j, ok := owners[importPath]; ok {
+			report(i, SeverityError, field, "%s is already the import path of %s, so they would share a page", importPath, entry(j))
+			return
+		}
+		owners[importPath] = i
+		if reserved, ok := ReservedPath(importPath); ok {
+			report(i, SeverityError, field, "%s falls on the %s endpoint of the serve command, which takes its requests", importPath, reserved)
+		}
+	}
+	for i, dto := range dtos {
+		if dto.ImportPrefix == "" {
+			continue
+		}
+		claim(i, "import-prefix", dto.ImportPrefix)
+		for _, sub := range dto.Submodules {
+			trimmed := strings.Trim(sub, "/")
+			clean := path.Clean(trimmed)
+			switch {
+			case trimmed == "":
+				// the validation reports it
+			case clean == "." || clean == ".." || strings.HasPrefix(clean, "../"):
+				report(i, SeverityError, "submodules", "%q escapes the directory of the module", sub)
+			default:
+				if clean != sub {
+					report(i, SeverityWarning, "submodules", "%q is not a clean path, use %q", sub, clean)
+				}
+				claim(i, "submodules", path.Join(dto.ImportPrefix, clean))
+			}
+		}
+		for _, major := range dto.Majors {
+			if major := strings.Trim(major, "/"); isMajorPath(major) {
+				claim(i, "majors", path.Join(dto.ImportPrefix, path.Clean(major)))
+			}
+		}
+	}
+
+	for i, dto := range dtos {
+		if dto.ImportPrefix == "" || dto.Type == TombstoneGone || owners[dto.ImportPrefix] != i {
+			continue
+		}
+		for j, other := range dtos {
+			if i == j || other.ImportPrefix == "" || other.Type == TombstoneGone || owners[other.ImportPrefix] != j ||
+				!strings.HasPrefix(dto.ImportPrefix, other.ImportPrefix+"/") || sameRepo(dto, other) {
+				continue
+			}
+			// a module inside of the repository of another one is a submodule, which the repository reuse reports
+			report(i, SeverityWarning, "import-prefix",
+				"is under the import prefix of %s, whose repository must not have the %s package too, or the imports of it are ambiguous",
+				entry(j), strings.TrimPrefix(dto.ImportPrefix, other.ImportPrefix+"/"))
+		}
+	}
+
+	roots := map[string]int{}
+	for i, dto := range dtos {
+		// a moved module points to the repository of its new import path, and a module proxy serves many modules
+		if dto.Type != "" || dto.VCS == VCSMod || dto.RootRepo == "" {
+			continue
+		}
+		root := repoKey(dto.RootRepo)
+		j, ok := roots[root]
+		if !ok {
+			roots[root] = i
+			continue
+		}
+		if rel, ok := strings.CutPrefix(dto.ImportPrefix, dtos[j].ImportPrefix+"/"); ok {
+			report(i, SeverityError, "root-repo", "is also the repository of %s, list %s in its submodules or majors instead", entry(j), rel)
+			continue
+		}
+		report(i, SeverityError, "root-repo",
+			"is also the repository of %s, but the go command expects a repository root to hold a single module path", entry(j))
+	}
+
+	sort.SliceStable(findings, func(a, b int) bool { return findings[a].Index < findings[b].Index })
+	return findings
+}
+
+// sameRepo tells whether two entries point to the same repository.
+func sameRepo(a, b ImportDTO) bool {
+	return a.RootRepo != "" && repoKey(a.RootRepo) == repoKey(b.RootRepo)
+}
+
+// repoKey is the identity of a repository URL, which doesn't depend on the scheme, the case of the host,
+// the .git suffix or a trailing slash.
+func repoKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Host) + strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git")
+}
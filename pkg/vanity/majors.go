@@ -0,0 +1,96 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// DetectMajors adds the major versions found in the version tags of the git repositories to the configured ones,
+// so a new major version gets its pages as soon as it is tagged, like v2.0.0 or adapter/kafka/v2.0.0.
+// Only the tags of the module and of its nested modules are considered.
+func DetectMajors(ctx context.Context, metas []Meta) ([]Meta, error) {
+	var errs []error
+	for i, meta := range metas {
+		repo := meta.Import.VCS.SourceRepo()
+		if repo.Name != VCSGit || meta.Tombstone.Kind != "" {
+			continue
+		}
+		out, err := git(ctx, "", "ls-remote", "--tags", "--refs", repo.RepoRoot.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", meta.Import.Prefix, err))
+			continue
+		}
+		var tags []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if _, ref, ok := strings.Cut(line, "\t"); ok {
+				tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+			}
+		}
+		metas[i].Majors = mergeMajors(meta, majorPaths(meta, tags))
+	}
+	return metas, errorkit.Merge(errs...)
+}
+
+// majorPaths tells the import paths of the major versions from the version tags of the repository.
+func majorPaths(meta Meta, tags []string) []string {
+	dirs := map[string]string{"": meta.Import.Prefix}
+	for _, nested := range meta.Nested {
+		dirs[nested.Path+"/"] = nested.ImportPath
+	}
+	var paths []string
+	for _, tag := range tags {
+		i := strings.LastIndex(tag, "/") + 1
+		importPath, ok := dirs[tag[:i]]
+		version := tag[i:]
+		if !ok || semver.Canonical(version) != version {
+			continue
+		}
+		if major := semver.Major(version); major != "v0" && major != "v1" {
+			paths = append(paths, importPath+"/"+major)
+		}
+	}
+	return paths
+}
+
+// mergeMajors adds the detected major versions to the configured ones, without duplicates, in a stable order.
+func mergeMajors(meta Meta, detected []string) []string {
+	var (
+		majors = meta.Majors
+		seen   = map[string]struct{}{}
+	)
+	for _, major := range majors {
+		seen[major] = struct{}{}
+	}
+	sort.Strings(detected)
+	for _, major := range detected {
+		if _, ok := seen[major]; ok || isNestedImportPath(meta, major) {
+			continue
+		}
+		seen[major] = struct{}{}
+		majors = append(majors, major)
+	}
+	return majors
+}
+
+// isNestedImportPath tells whether the import path belongs to a nested module of the module.
+func isNestedImportPath(meta Meta, importPath string) bool {
+	for _, nested := range meta.Nested {
+		if nested.ImportPath == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// isMajorPath tells whether the slash separated path ends with a major version from v2 on, like v2 or adapter/kafka/v2.
+func isMajorPath(p string) bool {
+	_, major, ok := module.SplitPathVersion(path.Join("example.com", p))
+	return ok && strings.HasPrefix(major, "/")
+}
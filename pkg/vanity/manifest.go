@@ -0,0 +1,81 @@
+package vanity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"time"
+
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// manifestFileName is the name of the manifest file within the output directory.
+const manifestFileName = "manifest.json"
+
+// Manifest lists the files of the generated site,
+// so deploy tooling can verify their integrity and detect drift on the server.
+type Manifest struct {
+	Generator string `json:"generator"`
+	// GeneratedAt is the time when the listed files last changed.
+	GeneratedAt time.Time       `json:"generated-at"`
+	Files       []ManifestEntry `json:"files"`
+}
+
+type ManifestEntry struct {
+	// Path is the slash separated path of the file, relative to the output directory.
+	Path string `json:"path"`
+	// SHA256 is the hex encoded checksum of the file content.
+	SHA256 string `json:"sha256"`
+	// Module is the import prefix of the module that the file was generated from.
+	Module string `json:"module,omitempty"`
+}
+
+// renderManifest renders the manifest of the rendered files.
+// When the files are the same as in the current manifest of the output directory,
+// the generation time is kept, so an unchanged site leaves the manifest unchanged too.
+func renderManifest(fsys filesystem.FileSystem, files []File) (File, error) {
+	manifest := Manifest{Generator: "generate-go-redirect"}
+	for _, file := range files {
+		sum := sha256.Sum256(file.Content)
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Path:   file.Path,
+			SHA256: hex.EncodeToString(sum[:]),
+			Module: file.Module,
+		})
+	}
+	current, ok, err := readManifest(fsys)
+	if err != nil {
+		return File{}, err
+	}
+	if ok && reflect.DeepEqual(current.Files, manifest.Files) {
+		manifest.GeneratedAt = current.GeneratedAt
+	} else {
+		manifest.GeneratedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: manifestFileName, Content: append(content, '\n')}, nil
+}
+
+// readManifest reads the manifest of the output directory.
+// It reports false when the output directory has no manifest yet.
+func readManifest(fsys filesystem.FileSystem) (Manifest, bool, error) {
+	data, err := readFile(fsys, manifestFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, false, nil
+	}
+	if err != nil {
+		return Manifest{}, false, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, false, fmt.Errorf("invalid %s: %w", manifestFileName, err)
+	}
+	return manifest, true, nil
+}
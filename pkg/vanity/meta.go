@@ -0,0 +1,204 @@
+package vanity
+
+import (
+	"html/template"
+	"net/url"
+	"time"
+)
+
+type Meta struct {
+	Import MetaImport
+	Source MetaSource
+	// Nested holds the Go modules that live in a subdirectory of the repository.
+	Nested []MetaNestedModule
+	// Majors are the import paths of the major versions from v2 on, like go.llib.dev/testcase/v2,
+	// which are developed on a major branch rather than in a subdirectory.
+	// They share the go-import meta of the module, but a static host needs a page for each of them.
+	Majors []string
+	// Redirect configures where the visitors with a browser are sent.
+	Redirect MetaRedirect
+	// Private modules are only revealed to authenticated clients by the server,
+	// and they are left out of the static site.
+	Private bool
+	// Deprecation tells the visitors that the module is no longer maintained.
+	Deprecation MetaDeprecation
+	// Tombstone marks the import path of a module that was renamed or removed.
+	Tombstone MetaTombstone
+	// Description is a short summary of the module, which the index page lists,
+	// and the link previews of the module's pages show.
+	Description string
+	// Category is the group of the module on the index page.
+	Category string
+	// Tags are the labels of the module on the index page, like the topics of a repository.
+	Tags []string
+	// NoCrawl asks the search engines not to crawl the pages of the module, and leaves them out of the sitemap.
+	NoCrawl bool
+	// Releases are the latest releases of the module, of its nested modules and of its major versions, by import path.
+	// They are only known when they were detected.
+	Releases map[string]MetaRelease
+	// Versions are the tagged versions of the module, of its nested modules and of its major versions, by import path,
+	// with the newest version first.
+	// They are only known when they were detected.
+	Versions map[string][]MetaVersion
+	// Readmes are the rendered READMEs of the module and of its nested modules, by import path.
+	// They are only known when they were fetched.
+	Readmes map[string]template.HTML
+	// Licenses are the SPDX identifiers of the licenses of the module and of its nested modules, by import path.
+	// They are only known when they were detected.
+	Licenses map[string]string
+	// Changelogs are the rendered latest entries of the changelogs of the module and of its nested modules, by import path.
+	// They are only known when they were fetched.
+	Changelogs map[string]template.HTML
+	// APIDocs are the API documentation of the packages of the module and of its nested modules, by module path.
+	// They are only known when they were fetched.
+	APIDocs map[string][]PackageDoc
+	// Title is the title of the link previews of the module's pages, in chat tools and on social media.
+	//
+	// default: the import path of the page
+	Title string
+	// Image is the absolute URL of the image of the link previews of the module's pages.
+	Image string
+	// Template is the page template of the module, which is landing for a landing page,
+	// or the name of a file in the templates directory, like flagship.html, for a page of its own.
+	//
+	// default: the go-import.html page, which is a redirect unless every page is a landing page
+	Template string
+}
+
+const (
+	// TombstoneMoved is the tombstone of a renamed module.
+	// Its pages still carry the go-import meta, which points to the repository of the new import path,
+	// so the go command reports the new module path to the users of the old one.
+	TombstoneMoved = "moved"
+	// TombstoneGone is the tombstone of a removed module, whose pages have no go-import meta.
+	TombstoneGone = "gone"
+)
+
+// MetaTombstone is the notice of a module that no longer lives under its import path.
+type MetaTombstone struct {
+	// Kind is moved or gone, and it is empty for the modules that are in place.
+	Kind string `enum:"moved,gone,"`
+	// MovedTo is the new import path of a moved module.
+	MovedTo string
+}
+
+// Moved tells whether the module was renamed.
+func (meta Meta) Moved() bool {
+	return meta.Tombstone.Kind == TombstoneMoved
+}
+
+// Gone tells whether the module was removed.
+func (meta Meta) Gone() bool {
+	return meta.Tombstone.Kind == TombstoneGone
+}
+
+// MetaDeprecation is the deprecation notice of a module.
+// The module is not deprecated when the Message is empty.
+type MetaDeprecation struct {
+	// Message tells why the module is deprecated, like the Deprecated comment of its go.mod file.
+	Message string
+	// Replacement is the optional import path of the module that replaces it.
+	Replacement string
+}
+
+// Deprecated tells whether the module is deprecated.
+func (meta Meta) Deprecated() bool {
+	return meta.Deprecation.Message != ""
+}
+
+// MetaNestedModule is a Go module that lives in a subdirectory of the Meta's repository root.
+// The go tool resolves it through the very same go-import meta as the root module,
+// so it only needs its own page with the parent's meta tags.
+type MetaNestedModule struct {
+	// Path is the module directory relative to the repository root.
+	Path string
+	// ImportPath is the module path of the nested module.
+	ImportPath string
+}
+
+// MetaRedirect configures the redirect of the visitors who open a page in a browser, rather than with the go tool.
+type MetaRedirect struct {
+	// Targets are the redirect targets in the order of priority, and the first available one is used.
+	// A target is one of docs, pkg.go.dev, homepage or repo.
+	//
+	// default: homepage
+	Targets []string
+	// DocsURL is the custom documentation URL of the module, which the docs target uses.
+	DocsURL string
+	// Mode is how the visitors are sent to the redirect target:
+	// script sends them right away with a script, which keeps the page out of the browser history,
+	// refresh sends them right away with a meta refresh, which works without JavaScript too,
+	// delayed shows the page with a link to the target, and sends them after the Delay with a meta refresh,
+	// and none leaves them on the page with a link to the target.
+	//
+	// default: script
+	Mode string `enum:"script,refresh,delayed,none,"`
+	// Delay is how long the delayed redirect shows the page before sending the visitors away.
+	//
+	// default: 5s
+	Delay time.Duration
+}
+
+// Immediate tells whether the visitors are sent to the redirect target right away,
+// which the server and the static hosts can do with an HTTP redirect too.
+func (r MetaRedirect) Immediate() bool {
+	return r.Mode == "" || r.Mode == RedirectModeScript || r.Mode == RedirectModeRefresh
+}
+
+// DelaySeconds is the Delay of the delayed redirect in whole seconds, as the meta refresh takes it.
+func (r MetaRedirect) DelaySeconds() int {
+	if r.Delay <= 0 {
+		return 5
+	}
+	return int(r.Delay.Seconds())
+}
+
+type MetaImport struct {
+	Prefix string
+	VCS    MetaImportVCS
+}
+
+type MetaImportVCS struct {
+	Name string `enum:"git,hg,svn,bzr,fossil,mod,"`
+	// RepoRoot is the repository root URL, or the base URL of the module proxy when the Name is mod.
+	RepoRoot *url.URL
+	// Origin is the git repository of a mod module, which the built-in module proxy builds the module from.
+	Origin *url.URL
+}
+
+// SourceRepo is the repository that holds the source code of the module.
+// It is the repository root itself, except for a mod module with an Origin,
+// whose source is in the Origin git repository, rather than behind the module proxy.
+func (vcs MetaImportVCS) SourceRepo() MetaImportVCS {
+	if vcs.Name == VCSMod && vcs.Origin != nil {
+		return MetaImportVCS{Name: VCSGit, RepoRoot: vcs.Origin}
+	}
+	return vcs
+}
+
+// MetaSource
+//
+// {dir} - The import path with prefix and leading "/" trimmed.
+// {/dir} - If {dir} is not the empty string, then {/dir} is replaced by "/" +
+// {dir}. Otherwise, {/dir} is replaced with the empty string.
+//
+// {file} - The name of the file
+// {line} - The decimal line number.
+type MetaSource struct {
+	// HomepageURL is the home URL that the source uses
+	//
+	// default: _
+	HomepageURL string
+	// Directory is the directory pattern it should use
+	DirectoryPattern string
+	// File is the file pattern that the go import should use
+	FilePattern string
+	// Branch is the branch that the default patterns point to.
+	//
+	// default: master, or default for Mercurial repositories
+	Branch string
+	// Forge is the kind of the repository's code host, which tells the shape of the default patterns.
+	//
+	// default: detected from the host of the repository
+	Forge string `enum:"github,gitlab,bitbucket,sourcehut,gitea,azure,"`
+}
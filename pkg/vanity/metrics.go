@@ -0,0 +1,138 @@
+package vanity
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics collects the traffic statistics of a Server,
+// and exposes them in the Prometheus text format.
+// The zero value is ready to use.
+type Metrics struct {
+	mutex    sync.Mutex
+	goGets   map[string]uint64
+	notFound uint64
+	reloads  map[string]uint64
+	latency  histogram
+}
+
+// PrivateModulesLabel is the module label of the requests of every private module in the Metrics,
+// which can't be an import path.
+const PrivateModulesLabel = "(private)"
+
+// latencyBuckets are the upper bounds of the response latency histogram, in seconds.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, le := range latencyBuckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ObserveRequest records a handled request.
+// The module is empty when the request path didn't match any module, and it's the PrivateModulesLabel for a private module.
+func (m *Metrics) ObserveRequest(module string, goGet bool, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if module == "" {
+		m.notFound++
+	} else if goGet {
+		if m.goGets == nil {
+			m.goGets = map[string]uint64{}
+		}
+		m.goGets[module]++
+	}
+	m.latency.observe(latency.Seconds())
+}
+
+// ObserveReload records a reload of the imports file.
+func (m *Metrics) ObserveReload(err error) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.reloads == nil {
+		m.reloads = map[string]uint64{}
+	}
+	if err != nil {
+		m.reloads["failure"]++
+	} else {
+		m.reloads["success"]++
+	}
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.writeText(w)
+}
+
+// writeText writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) writeText(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP vanity_go_get_requests_total Number of go-get requests per module.\n")
+	b.WriteString("# TYPE vanity_go_get_requests_total counter\n")
+	for _, module := range sortedKeys(m.goGets) {
+		fmt.Fprintf(&b, "vanity_go_get_requests_total{module=%s} %d\n", strconv.Quote(module), m.goGets[module])
+	}
+
+	b.WriteString("# HELP vanity_not_found_requests_total Number of requests for paths that match no module.\n")
+	b.WriteString("# TYPE vanity_not_found_requests_total counter\n")
+	fmt.Fprintf(&b, "vanity_not_found_requests_total %d\n", m.notFound)
+
+	b.WriteString("# HELP vanity_request_duration_seconds Latency of the responses.\n")
+	b.WriteString("# TYPE vanity_request_duration_seconds histogram\n")
+	for i, le := range latencyBuckets {
+		var count uint64
+		if m.latency.counts != nil {
+			count = m.latency.counts[i]
+		}
+		fmt.Fprintf(&b, "vanity_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(le, 'g', -1, 64), count)
+	}
+	fmt.Fprintf(&b, "vanity_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latency.count)
+	fmt.Fprintf(&b, "vanity_request_duration_seconds_sum %s\n", strconv.FormatFloat(m.latency.sum, 'g', -1, 64))
+	fmt.Fprintf(&b, "vanity_request_duration_seconds_count %d\n", m.latency.count)
+
+	b.WriteString("# HELP vanity_config_reloads_total Number of imports file reloads by result.\n")
+	b.WriteString("# TYPE vanity_config_reloads_total counter\n")
+	for _, result := range []string{"success", "failure"} {
+		fmt.Fprintf(&b, "vanity_config_reloads_total{result=%q} %d\n", result, m.reloads[result])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// ModulePathError tells that the go.mod file of a module doesn't declare the module's import path.
+type ModulePathError struct {
+	// ImportPath is the configured import path of the module.
+	ImportPath string
+	// File is the path of the go.mod file within the repository.
+	File string
+	// Message tells what is wrong with the go.mod file.
+	Message string
+}
+
+func (err ModulePathError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", err.ImportPath, err.File, err.Message)
+}
+
+// CheckModulePaths reads the go.mod files from the default branch of every git and Mercurial repository,
+// and checks that their module directives match the configured import paths,
+// so a mistyped prefix or submodule, or an unreachable repository, is caught before its broken pages are published.
+// A module directive with a major version suffix, like go.llib.dev/testcase/v2, matches the import path without it.
+// A deprecated module without a Deprecated comment in its go.mod file is only warned about.
+func CheckModulePaths(ctx context.Context, metas []Meta) error {
+	var errs []error
+	for _, meta := range metas {
+		if !isClonable(meta.Import.VCS.SourceRepo().Name) || meta.Tombstone.Kind != "" {
+			continue
+		}
+		if err := checkModulePaths(ctx, meta); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errorkit.Merge(errs...)
+}
+
+func hasDeprecatedComment(goMod []byte) bool {
+	f, err := modfile.ParseLax("go.mod", goMod, nil)
+	return err == nil && f.Module != nil && f.Module.Deprecated != ""
+}
+
+func checkModulePaths(ctx context.Context, meta Meta) (rErr error) {
+	repo := meta.Import.VCS.SourceRepo()
+	clone, err := cloneRepo(ctx, repo.Name, repo.RepoRoot.String())
+	if err != nil {
+		return fmt.Errorf("%s: %w", meta.Import.Prefix, err)
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	type goMod struct{ file, importPath string }
+	goMods := []goMod{{file: "go.mod", importPath: meta.Import.Prefix}}
+	for _, nested := range meta.Nested {
+		goMods = append(goMods, goMod{file: path.Join(nested.Path, "go.mod"), importPath: nested.ImportPath})
+	}
+	var errs []error
+	for _, m := range goMods {
+		file, importPath := m.file, m.importPath
+		report := func(format string, args ...any) {
+			errs = append(errs, ModulePathError{ImportPath: importPath, File: file, Message: fmt.Sprintf(format, args...)})
+		}
+		data, err := clone.ReadFile(ctx, file)
+		if err != nil {
+			report("no go.mod file in the repository")
+			continue
+		}
+		modulePath := modfile.ModulePath(data)
+		if modulePath == "" {
+			report("no module directive")
+			continue
+		}
+		if file == "go.mod" && meta.Deprecated() && !hasDeprecatedComment(data) {
+			// the go command only tells the users of the module about the deprecation by the comment
+			logger.Warn(ctx, "the deprecated module has no Deprecated comment in its go.mod file",
+				logging.Field("module", importPath))
+		}
+		if prefix, major, ok := module.SplitPathVersion(modulePath); modulePath == importPath || (ok && major != "" && prefix == importPath) {
+			continue
+		}
+		report("declares module %s", modulePath)
+	}
+	return errorkit.Merge(errs...)
+}
@@ -0,0 +1,196 @@
+package vanity
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redirectsFileName is the name of the redirect rules file that static hosts like Netlify or Cloudflare Pages read.
+const redirectsFileName = "_redirects"
+
+// Netlify is the output plugin of the sites deployed to Netlify,
+// which adds the _redirects and _headers files of the site, in place of a hand-written rules file.
+//
+// The _redirects file answers the go-get requests of the packages under a module with the page of the module,
+// and sends the browser visitors of the module pages to their redirect targets with an HTTP redirect,
+// rather than with the script of the page.
+// The pages that show something to the visitors,
+// like the landing pages and the pages of the deprecated, moved and gone modules, are left as they are,
+// and so are all the pages, when the templates are overridden.
+//
+// The _headers file sets the Cache-Control of the pages by the MaxAge option,
+// lets the fingerprinted copies of the assets be cached forever,
+// and sets the SecurityHeaders option on the responses, with a Content-Security-Policy per page,
+// which by default only lets the own inline scripts of the page run.
+type Netlify struct {
+	// ContentSecurityPolicy replaces the Content-Security-Policy of the pages,
+	// in place of the one of the SecurityHeaders option.
+	//
+	// default: the Content-Security-Policy of the SecurityHeaders option
+	ContentSecurityPolicy string
+}
+
+func (n Netlify) Render(site Site) ([]File, error) {
+	var redirects strings.Builder
+	for _, rule := range redirectRules(site) {
+		fmt.Fprintln(&redirects, rule)
+	}
+
+	_, basePath := splitDomain(site.Domain)
+	var headers strings.Builder
+	security := site.Options.SecurityHeaders
+	fmt.Fprintf(&headers, "%s/*\n%s", basePath, security.headerRules(""))
+	for _, file := range site.Files {
+		if path.Base(file.Path) != "index.html" {
+			continue
+		}
+		csp := n.ContentSecurityPolicy
+		if csp == "" {
+			csp = security.pagePolicy(file.Content)
+		}
+		// a rule per page, since the values of the rules that match the same path are joined
+		for _, urlPath := range pageURLPaths(basePath, file.Path) {
+			fmt.Fprintf(&headers, "%s\n  Cache-Control: %s\n", urlPath, cacheControl(site.Options.MaxAge))
+			if csp != "" {
+				fmt.Fprintf(&headers, "  Content-Security-Policy: %s\n", csp)
+			}
+		}
+	}
+	fingerprinted := make([]string, 0, len(site.Assets))
+	for _, p := range site.Assets {
+		fingerprinted = append(fingerprinted, p)
+	}
+	sort.Strings(fingerprinted)
+	for _, p := range fingerprinted {
+		fmt.Fprintf(&headers, "%s/%s\n  Cache-Control: public, max-age=31536000, immutable\n", basePath, p)
+	}
+
+	return []File{
+		{Path: redirectsFileName, Content: []byte(redirects.String())},
+		{Path: headersFileName, Content: []byte(headers.String())},
+	}, nil
+}
+
+// redirectRule is a rule of a _redirects file.
+type redirectRule struct {
+	From string
+	// Query is the query parameter that the rule is conditioned on, like go-get=1.
+	Query  string
+	To     string
+	Status int
+	// Force applies the rule even when a file exists at its path.
+	Force bool
+}
+
+func (r redirectRule) String() string {
+	fields := []string{r.From}
+	if r.Query != "" {
+		fields = append(fields, r.Query)
+	}
+	status := strconv.Itoa(r.Status)
+	if r.Force {
+		status += "!"
+	}
+	return strings.Join(append(fields, r.To, status), "  ")
+}
+
+// modulePage is the page of a module's import path on the site.
+type modulePage struct {
+	meta       Meta
+	importPath string
+}
+
+// modulePages are the pages of the site's modules,
+// with the pages of the nested modules before the pages of their parents, so the first matching page is the innermost.
+func modulePages(site Site) []modulePage {
+	var pages []modulePage
+	for _, meta := range site.Modules {
+		if meta.Private || !strings.Contains(meta.Import.Prefix, site.Domain) {
+			continue
+		}
+		for _, importPath := range pageImportPaths(meta) {
+			pages = append(pages, modulePage{meta: meta, importPath: importPath})
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].importPath > pages[j].importPath })
+	return pages
+}
+
+// redirectRules are the redirect rules of the module pages.
+// Since the first matching rule applies, the rules of the nested modules come before the rules of their parents.
+func redirectRules(site Site) []redirectRule {
+	_, basePath := splitDomain(site.Domain)
+	var rules []redirectRule
+	for _, p := range modulePages(site) {
+		var (
+			pagePath = pagePath(site.Domain, p.importPath)
+			urlPaths = pageURLPaths(basePath, pagePath)
+			file     = basePath + "/" + pagePath
+			splat    = strings.TrimSuffix(urlPaths[0], "/") + "/*"
+		)
+		// the go command asks for the packages of the module by their import paths
+		rules = append(rules,
+			redirectRule{From: urlPaths[0], Query: "go-get=1", To: file, Status: 200, Force: true},
+			redirectRule{From: splat, Query: "go-get=1", To: file, Status: 200, Force: true})
+		if !redirectsVisitors(p.meta, site.Options) {
+			continue
+		}
+		target := p.meta.RedirectURL(p.importPath)
+		if target == "" {
+			continue
+		}
+		for _, urlPath := range urlPaths {
+			rules = append(rules, redirectRule{From: urlPath, To: target, Status: 302, Force: true})
+		}
+		if p.importPath != site.Domain {
+			// unforced, so the files next to the page, like its badge and API documentation, are still served,
+			// while the packages of the module are sent to their own redirect targets
+			rules = append(rules, redirectRule{From: splat, To: p.meta.RedirectURL(p.importPath + "/:splat"), Status: 302})
+		}
+	}
+	return rules
+}
+
+// redirectsVisitors tells whether the pages of the module send their browser visitors to the module's redirect target right away,
+// which is what the default template does, unless the module has a notice or a landing page to show,
+// or its redirect mode keeps the visitors on the page for a while.
+func redirectsVisitors(meta Meta, opts GenerateOptions) bool {
+	landing := meta.Template != "" || opts.Landing
+	return !landing && opts.Templates == nil && !meta.Deprecated() && meta.Tombstone.Kind == "" && meta.Redirect.Immediate()
+}
+
+// pageURLPaths are the URL paths that a page is served at, with and without the trailing slash.
+func pageURLPaths(basePath, pagePath string) []string {
+	dir := path.Dir(pagePath)
+	if dir == "." {
+		if basePath == "" {
+			return []string{"/"}
+		}
+		return []string{basePath, basePath + "/"}
+	}
+	return []string{basePath + "/" + dir, basePath + "/" + dir + "/"}
+}
+
+// inlineScriptPattern matches the script elements of a page, with their attributes and content.
+var inlineScriptPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+
+// contentSecurityPolicy is the default Content-Security-Policy of a page,
+// which lets the inline scripts of the page run by their hashes.
+func contentSecurityPolicy(page []byte) string {
+	scripts := "'self'"
+	for _, match := range inlineScriptPattern.FindAllSubmatch(page, -1) {
+		if strings.Contains(strings.ToLower(string(match[1])), "src=") {
+			continue
+		}
+		sum := sha256.Sum256(match[2])
+		scripts += " 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	}
+	return "default-src 'self'; script-src " + scripts +
+		"; style-src 'self' 'unsafe-inline'; img-src 'self' https: data:; object-src 'none'; base-uri 'none'; frame-ancestors 'none'"
+}
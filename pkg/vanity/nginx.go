@@ -0,0 +1,40 @@
+package vanity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nginxHeadersFileName is the nginx configuration snippet of the headers of the site.
+const nginxHeadersFileName = "nginx-headers.conf"
+
+// Nginx is the output plugin of the sites served by nginx,
+// which adds a configuration snippet of the headers of the site, to include in the server block of the site:
+//
+//	include /var/www/go.llib.dev/nginx-headers.conf;
+//
+// The snippet sets the Cache-Control of the pages by the MaxAge option, and the SecurityHeaders option, on every response.
+// Since the headers of a server block apply to the whole site, the Content-Security-Policy is only set when it's configured,
+// rather than per page.
+type Nginx struct{}
+
+func (Nginx) Render(site Site) ([]File, error) {
+	var (
+		security = site.Options.SecurityHeaders
+		conf     strings.Builder
+	)
+	fmt.Fprintf(&conf, "# the headers of the %s site, to include in its server block\n", site.Domain)
+	fmt.Fprintf(&conf, "add_header Cache-Control %s always;\n", nginxQuote(cacheControl(site.Options.MaxAge)))
+	for _, header := range security.siteHeaders() {
+		fmt.Fprintf(&conf, "add_header %s %s always;\n", header[0], nginxQuote(header[1]))
+	}
+	if csp := security.sitePolicy(); csp != "" {
+		fmt.Fprintf(&conf, "add_header Content-Security-Policy %s always;\n", nginxQuote(csp))
+	}
+	return []File{{Path: nginxHeadersFileName, Content: []byte(conf.String())}}, nil
+}
+
+// nginxQuote quotes a value of the nginx configuration, which may contain spaces and semicolons, like a policy.
+func nginxQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
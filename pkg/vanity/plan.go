@@ -0,0 +1,73 @@
+package vanity
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+type ChangeKind string
+
+const (
+	ChangeCreate    ChangeKind = "create"
+	ChangeUpdate    ChangeKind = "update"
+	ChangeUnchanged ChangeKind = "unchanged"
+	ChangeDelete    ChangeKind = "delete"
+)
+
+// Change describes what would happen with a rendered file when it is written to the output directory.
+type Change struct {
+	Kind ChangeKind
+	File File
+	// Old is the current content of the file in the output directory.
+	Old []byte
+}
+
+// planChanges compares the rendered files, and their manifest, against the content of the file system.
+func planChanges(fsys filesystem.FileSystem, files []File, opts GenerateOptions) ([]Change, error) {
+	manifest, err := renderManifest(fsys, files)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, manifest)
+
+	var changes []Change
+	for _, file := range files {
+		change, err := compareFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if opts.Prune {
+		orphans, err := findOrphans(fsys, files)
+		if err != nil {
+			return nil, err
+		}
+		for _, orphan := range orphans {
+			old, err := readFile(fsys, orphan)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, Change{Kind: ChangeDelete, File: File{Path: orphan}, Old: old})
+		}
+	}
+	return changes, nil
+}
+
+// compareFile compares a rendered file against its current content in the file system.
+func compareFile(fsys filesystem.FileSystem, file File) (Change, error) {
+	old, err := readFile(fsys, file.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return Change{Kind: ChangeCreate, File: file}, nil
+	case err != nil:
+		return Change{}, err
+	case bytes.Equal(old, file.Content):
+		return Change{Kind: ChangeUnchanged, File: file, Old: old}, nil
+	default:
+		return Change{Kind: ChangeUpdate, File: file, Old: old}, nil
+	}
+}
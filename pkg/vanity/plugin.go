@@ -0,0 +1,21 @@
+package vanity
+
+// OutputPlugin adds files to the rendered site, which are computed from its modules and files,
+// like the redirect rules and custom headers of a static host.
+type OutputPlugin interface {
+	Render(site Site) ([]File, error)
+}
+
+// Site is the rendered site of a vanity domain, which the output plugins extend.
+type Site struct {
+	// Domain is the vanity domain of the site, with its base path, if it has one.
+	Domain string
+	// Modules are the modules of the site.
+	Modules []Meta
+	// Files are the rendered files of the site.
+	Files []File
+	// Assets are the paths of the fingerprinted copies of the assets, by the paths of the assets.
+	Assets map[string]string
+	// Options are the options that the site is rendered with.
+	Options GenerateOptions
+}
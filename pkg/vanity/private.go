@@ -0,0 +1,88 @@
+package vanity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// privateConfigFileName is the name of the machine-readable go settings of the private modules within the site.
+const privateConfigFileName = "goprivate.json"
+
+// renderPrivateGuides renders the guide pages of a private module and its nested modules,
+// which keep the go-import meta tag, and tell the visitors how to set up the go command to download the module.
+func renderPrivateGuides(tmpl *template.Template, domain string, meta Meta) ([]File, error) {
+	var files []File
+	for _, importPath := range pageImportPaths(meta) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, importTemplateName, newPrivateGuidePage(meta, importPath)); err != nil {
+			return nil, fmt.Errorf("private guide template execution failed: %w", err)
+		}
+		files = append(files, File{
+			Path:    pagePath(domain, importPath),
+			Content: buf.Bytes(),
+			Module:  meta.Import.Prefix,
+		})
+	}
+	return files, nil
+}
+
+// newPrivateGuidePage is the page of a private module's import path, which the browser visitors stay on.
+func newPrivateGuidePage(meta Meta, importPath string) Page {
+	p := newPage(meta, importPath)
+	p.RedirectURL = ""
+	p.PrivateGuide = true
+	if repo := meta.Import.VCS.SourceRepo(); repo.RepoRoot != nil {
+		p.RepositoryURL = strings.TrimSuffix(repo.RepoRoot.String(), ".git")
+		p.RepositoryHost = repo.RepoRoot.Hostname()
+	}
+	return p
+}
+
+// privateConfigDTO is the machine-readable form of the go settings of the private modules,
+// which a setup script or a CI pipeline can apply with go env -w.
+type privateConfigDTO struct {
+	Env     map[string]string        `json:"env"`
+	Modules []privateConfigModuleDTO `json:"modules"`
+}
+
+type privateConfigModuleDTO struct {
+	Path string `json:"path"`
+	VCS  string `json:"vcs"`
+	// Repo is the repository, whose host asks for the credentials.
+	Repo string `json:"repo,omitempty"`
+	Host string `json:"host,omitempty"`
+}
+
+// renderPrivateConfig renders the go settings of the domain's private modules.
+// It reports false when the domain has no private modules.
+func renderPrivateConfig(domain string, metas []Meta) (File, bool, error) {
+	var (
+		config   = privateConfigDTO{Env: map[string]string{}, Modules: []privateConfigModuleDTO{}}
+		prefixes []string
+	)
+	for _, meta := range metas {
+		if !meta.Private || meta.Gone() || !isUnderDomain(meta.Import.Prefix, domain) {
+			continue
+		}
+		prefixes = append(prefixes, meta.Import.Prefix)
+		module := privateConfigModuleDTO{Path: meta.Import.Prefix, VCS: meta.Import.VCS.Name}
+		if repo := meta.Import.VCS.SourceRepo(); repo.RepoRoot != nil {
+			module.Repo, module.Host = repo.RepoRoot.String(), repo.RepoRoot.Hostname()
+		}
+		config.Modules = append(config.Modules, module)
+	}
+	if len(prefixes) == 0 {
+		return File{}, false, nil
+	}
+	for _, name := range []string{"GOPRIVATE", "GONOPROXY", "GONOSUMDB"} {
+		config.Env[name] = strings.Join(prefixes, ",")
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return File{}, false, err
+	}
+	return File{Path: privateConfigFileName, Content: append(content, '\n')}, true, nil
+}
@@ -0,0 +1,266 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ErrNotFound is returned by a ModuleSource when the module or its version doesn't exist.
+const ErrNotFound errorkit.Error = "not found"
+
+// ProxyModule is a module that the module proxy serves.
+type ProxyModule struct {
+	// Path is the module path.
+	Path string
+	// RepoRoot is the URL of the module's repository.
+	RepoRoot string
+	// Dir is the directory of the module within the repository, empty for the repository root.
+	Dir string
+	// Private tells whether the module is only served to authenticated clients.
+	Private bool
+}
+
+// ModuleInfo is the response of the module proxy's .info endpoint.
+type ModuleInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// ModuleSource provides the data of the modules that the module proxy serves.
+type ModuleSource interface {
+	// List returns the known versions of the module.
+	List(ctx context.Context, mod ProxyModule) ([]string, error)
+	// Info returns the metadata of a module version.
+	Info(ctx context.Context, mod ProxyModule, version string) (ModuleInfo, error)
+	// GoMod returns the go.mod file of a module version.
+	GoMod(ctx context.Context, mod ProxyModule, version string) ([]byte, error)
+	// Zip writes the zip archive of a module version.
+	Zip(ctx context.Context, mod ProxyModule, version string, w io.Writer) error
+}
+
+// isProxyPath tells whether the request path is an endpoint of the module proxy protocol.
+func isProxyPath(p string) bool {
+	return strings.Contains(p, "/@v/") || strings.HasSuffix(p, "/@latest")
+}
+
+// serveProxy answers the GOPROXY protocol requests of the configured modules,
+// so the vanity domain can act as its own module proxy.
+//
+//	GOPROXY=https://go.llib.dev go get go.llib.dev/testcase
+func (s *Server) serveProxy(w http.ResponseWriter, r *http.Request) {
+	// the proxy of a domain with a base path is at the base path, like GOPROXY=https://example.com/go
+	_, basePath := splitDomain(s.Domain)
+	escaped, endpoint := strings.TrimPrefix(r.URL.Path, basePath), "@latest"
+	if i := strings.Index(escaped, "/@v/"); 0 <= i {
+		escaped, endpoint = escaped[:i], escaped[i+len("/@v/"):]
+	} else {
+		escaped = strings.TrimSuffix(escaped, "/@latest")
+	}
+	modulePath, err := module.UnescapePath(strings.TrimPrefix(escaped, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	mod, ok := s.proxyModule(modulePath)
+	if !ok {
+		http.Error(w, "not found: unknown module "+modulePath, http.StatusNotFound)
+		return
+	}
+	if mod.Private && !s.authorize(w, r) {
+		return
+	}
+
+	ctx := r.Context()
+	switch {
+	case endpoint == "list":
+		versions, err := s.Proxy.List(ctx, mod)
+		if err != nil {
+			s.proxyError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", cacheControl(s.MaxAge))
+		for _, v := range versions {
+			_, _ = io.WriteString(w, v+"\n")
+		}
+
+	case endpoint == "@latest":
+		versions, err := s.Proxy.List(ctx, mod)
+		if err != nil {
+			s.proxyError(w, r, err)
+			return
+		}
+		version, ok := latestVersion(versions)
+		if !ok {
+			s.proxyError(w, r, ErrNotFound)
+			return
+		}
+		info, err := s.Proxy.Info(ctx, mod, version)
+		if err != nil {
+			s.proxyError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl(s.MaxAge))
+		writeJSON(w, info)
+
+	default:
+		ext := endpoint[strings.LastIndex(endpoint, ".")+1:]
+		version, err := module.UnescapeVersion(strings.TrimSuffix(endpoint, "."+ext))
+		if err != nil || semver.Canonical(version) != version {
+			http.NotFound(w, r)
+			return
+		}
+		// the artifacts of a released version never change, but the header is only set once they are produced,
+		// so the failures, like a version that isn't tagged yet, are not cached along with them
+		switch ext {
+		case "info":
+			info, err := s.Proxy.Info(ctx, mod, version)
+			if err != nil {
+				s.proxyError(w, r, err)
+				return
+			}
+			w.Header().Set("Cache-Control", immutableCacheControl)
+			writeJSON(w, info)
+		case "mod":
+			data, err := s.Proxy.GoMod(ctx, mod, version)
+			if err != nil {
+				s.proxyError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Cache-Control", immutableCacheControl)
+			_, _ = w.Write(data)
+		case "zip":
+			if _, err := s.Proxy.Info(ctx, mod, version); err != nil {
+				s.proxyError(w, r, err)
+				return
+			}
+			s.serveZip(w, r, mod, version)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// proxyModule finds the configured module, or nested module, of the module path.
+// A major version suffix that is not listed as a nested module, like go.llib.dev/testcase/v2,
+// belongs to the module without the suffix, and its code is on a major version branch of the same directory.
+func (s *Server) proxyModule(modulePath string) (ProxyModule, bool) {
+	table := s.modules.Load()
+	if table == nil {
+		return ProxyModule{}, false
+	}
+	basePath := modulePath
+	meta, ok := (*table)[modulePath]
+	if !ok {
+		if prefix, major, ok := module.SplitPathVersion(modulePath); ok && major != "" && !strings.HasPrefix(major, ".") {
+			basePath = prefix
+		}
+		meta, ok = (*table)[basePath]
+		if !ok || basePath == modulePath {
+			return ProxyModule{}, false
+		}
+	}
+	if meta.Tombstone.Kind != "" {
+		// the repository of a moved module declares the new module path
+		return ProxyModule{}, false
+	}
+	repo := meta.Import.VCS.SourceRepo()
+	if repo.Name != VCSGit {
+		// the module zips are built from git repositories,
+		// and the go command fetches the others directly, as the go-import meta tells it
+		return ProxyModule{}, false
+	}
+	mod := ProxyModule{Path: modulePath, RepoRoot: repo.RepoRoot.String(), Private: meta.Private}
+	for _, nested := range meta.Nested {
+		if nested.ImportPath == basePath {
+			mod.Dir = nested.Path
+		}
+	}
+	return mod, true
+}
+
+// latestVersion picks the highest release version, like the go command does,
+// and only falls back to the highest pre-release when there is no release at all.
+func latestVersion(versions []string) (string, bool) {
+	var latest, latestPre string
+	for _, v := range versions {
+		if semver.Prerelease(v) != "" {
+			if semver.Compare(v, latestPre) > 0 {
+				latestPre = v
+			}
+			continue
+		}
+		if semver.Compare(v, latest) > 0 {
+			latest = v
+		}
+	}
+	if latest == "" {
+		latest = latestPre
+	}
+	return latest, latest != ""
+}
+
+// immutableCacheControl is the Cache-Control of the artifacts of a released version, which never change.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// serveZip spools the zip archive of the module version into a temporary file before it answers,
+// so a failed archive is answered with an error that is not cached, rather than with a broken archive cached for a year.
+func (s *Server) serveZip(w http.ResponseWriter, r *http.Request, mod ProxyModule, version string) {
+	spool, err := os.CreateTemp("", "module-*.zip")
+	if err != nil {
+		s.proxyError(w, r, err)
+		return
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+	if err := s.Proxy.Zip(r.Context(), mod, version, spool); err != nil {
+		s.proxyError(w, r, fmt.Errorf("module zip of %s@%s failed: %w", mod.Path, version, err))
+		return
+	}
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		s.proxyError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	_, _ = io.Copy(w, spool)
+}
+
+// proxyError answers the failure of a proxy request, which is not cached,
+// since a missing version may be tagged later, and a failed fetch may succeed on a retry.
+func (s *Server) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Cache-Control", "no-store")
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	logger.Error(r.Context(), "module proxy failure", logging.ErrField(err))
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,210 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"golang.org/x/mod/module"
+)
+
+// CachedSource keeps the .info, .mod and .zip artifacts of a ModuleSource on disk,
+// so repeated fetches of a module version don't run the git operations again.
+// The version lists are not cached, since they change with every new release.
+type CachedSource struct {
+	Source ModuleSource
+	// Dir is where the artifacts are kept, in the layout of the module cache: <module>/@v/<version>.<ext>
+	Dir string
+	// TTL is how long an artifact is served from the cache before it is fetched again.
+	// When zero, the artifacts don't expire.
+	TTL time.Duration
+	// MaxSize is the limit of the cache size in bytes.
+	// Above the limit, the oldest artifacts are evicted.
+	// When zero, the cache size is not limited.
+	MaxSize int64
+
+	mutex sync.Mutex
+}
+
+func (c *CachedSource) List(ctx context.Context, mod ProxyModule) ([]string, error) {
+	return c.Source.List(ctx, mod)
+}
+
+func (c *CachedSource) Info(ctx context.Context, mod ProxyModule, version string) (ModuleInfo, error) {
+	data, err := c.fetch(ctx, mod, version, "info", func(w io.Writer) error {
+		info, err := c.Source.Info(ctx, mod, version)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(info)
+	})
+	if err != nil {
+		return ModuleInfo{}, err
+	}
+	var info ModuleInfo
+	return info, json.Unmarshal(data, &info)
+}
+
+func (c *CachedSource) GoMod(ctx context.Context, mod ProxyModule, version string) ([]byte, error) {
+	return c.fetch(ctx, mod, version, "mod", func(w io.Writer) error {
+		data, err := c.Source.GoMod(ctx, mod, version)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+func (c *CachedSource) Zip(ctx context.Context, mod ProxyModule, version string, w io.Writer) error {
+	name, err := c.path(mod, version, "zip")
+	if err != nil {
+		return err
+	}
+	if !c.fresh(name) {
+		if err := c.store(name, func(w io.Writer) error { return c.Source.Zip(ctx, mod, version, w) }); err != nil {
+			return err
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// fetch returns the cached artifact, or stores the output of the source in the cache first.
+func (c *CachedSource) fetch(ctx context.Context, mod ProxyModule, version, ext string, source func(w io.Writer) error) ([]byte, error) {
+	name, err := c.path(mod, version, ext)
+	if err != nil {
+		return nil, err
+	}
+	if c.fresh(name) {
+		if data, err := os.ReadFile(name); err == nil {
+			return data, nil
+		}
+	}
+	var buf bytes.Buffer
+	if err := source(&buf); err != nil {
+		return nil, err
+	}
+	if err := c.store(name, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}); err != nil {
+		// a failing cache shouldn't fail the request
+		logger.Warn(ctx, "module proxy cache write failed", logging.Field("path", name), logging.ErrField(err))
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *CachedSource) path(mod ProxyModule, version, ext string) (string, error) {
+	escapedPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.Dir, filepath.FromSlash(escapedPath), "@v", escapedVersion+"."+ext), nil
+}
+
+// fresh tells whether the artifact is in the cache, and it hasn't expired yet.
+func (c *CachedSource) fresh(name string) bool {
+	info, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	return c.TTL == 0 || time.Since(info.ModTime()) < c.TTL
+}
+
+// store writes the artifact into a temporary file next to its place, and renames it when it is complete,
+// so a concurrent request never reads a partially written artifact.
+func (c *CachedSource) store(name string, write func(w io.Writer) error) (rErr error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rErr != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if err := write(f); err != nil {
+		return errorkit.Merge(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the oldest artifacts until the cache fits into its size limit.
+func (c *CachedSource) evict() error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	type artifact struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var (
+		artifacts []artifact
+		total     int64
+	)
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].modTime.Before(artifacts[j].modTime)
+	})
+	for _, a := range artifacts {
+		if total <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(a.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= a.size
+	}
+	return nil
+}
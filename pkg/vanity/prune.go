@@ -0,0 +1,95 @@
+package vanity
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// generatorMarker is present in every page that the generator renders.
+// Pages without it are considered hand-placed, and they are never pruned.
+const generatorMarker = `<meta name="generator" content="generate-go-redirect">`
+
+// findOrphans looks for generated files in the output directory that are no longer part of the rendered site.
+// A file counts as generated when the previous manifest lists it, or when it is a page with the generator marker.
+// It returns their slash separated paths relative to the output directory.
+func findOrphans(fsys filesystem.FileSystem, files []File) ([]string, error) {
+	var rendered = map[string]struct{}{}
+	for _, file := range files {
+		rendered[file.Path] = struct{}{}
+	}
+	var orphans []string
+	manifest, _, err := readManifest(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range manifest.Files {
+		if _, ok := rendered[entry.Path]; ok {
+			continue
+		}
+		_, err := fsys.Stat(filepath.FromSlash(entry.Path))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rendered[entry.Path] = struct{}{} // so the walk doesn't list it twice
+		orphans = append(orphans, entry.Path)
+	}
+	err = filesystem.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != "." && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "index.html" {
+			return nil
+		}
+		rel := filepath.ToSlash(path)
+		if _, ok := rendered[rel]; ok {
+			return nil
+		}
+		content, err := readFile(fsys, rel)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(content, []byte(generatorMarker)) {
+			orphans = append(orphans, rel)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return orphans, err
+}
+
+// removeOrphans deletes the orphaned pages,
+// and the directories that became empty by their removal.
+func removeOrphans(fsys filesystem.FileSystem, orphans []string) error {
+	for _, orphan := range orphans {
+		name := filepath.FromSlash(orphan)
+		if err := fsys.Remove(name); err != nil {
+			return err
+		}
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			entries, err := filesystem.ReadDir(fsys, dir)
+			if err != nil || 0 < len(entries) {
+				break
+			}
+			if err := fsys.Remove(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package vanity
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// GitHubPages publishes the site to the branch of a git repository that GitHub Pages serves, like gh-pages.
+// The branch is pushed with git, so the credentials of the remote are the ones that git already uses,
+// like the GITHUB_TOKEN of a checkout action.
+type GitHubPages struct {
+	// Repository is the directory of the local git repository that the branch is committed in.
+	//
+	// default: the current directory
+	Repository string
+	// Remote is the remote that the branch is fetched from and pushed to.
+	//
+	// default: origin
+	Remote string
+	// Branch is the branch that GitHub Pages serves.
+	//
+	// default: gh-pages
+	Branch string
+	// Message is the message of the commit.
+	//
+	// default: Publish the vanity import pages
+	Message string
+	// AuthorName and AuthorEmail are the author and the committer of the commit.
+	//
+	// default: the user of the repository's git config
+	AuthorName  string
+	AuthorEmail string
+	// NoPush only commits the branch of the local repository.
+	NoPush bool
+}
+
+// Publish commits the content of the site's directory as the whole tree of a new commit on top of the branch,
+// and pushes the branch to the remote.
+// The commit is made with the plumbing commands of git, so neither the working tree nor the index
+// of the repository is touched, and the site's directory may even be inside of the repository.
+// It returns the new commit, or an empty string when the site is the same as the tip of the branch.
+func (p GitHubPages) Publish(ctx context.Context, siteDir string) (string, error) {
+	var (
+		repo   = zerokit.Coalesce(p.Repository, ".")
+		remote = zerokit.Coalesce(p.Remote, "origin")
+		branch = zerokit.Coalesce(p.Branch, "gh-pages")
+		ref    = "refs/heads/" + branch
+	)
+	siteDir, err := filepath.Abs(siteDir)
+	if err != nil {
+		return "", err
+	}
+
+	// the new commit is on top of the remote's branch, so the push is a fast-forward
+	parentRef := ref
+	if !p.NoPush {
+		out, err := git(ctx, repo, "ls-remote", "--heads", remote, ref)
+		if err != nil {
+			return "", err
+		}
+		parentRef = "refs/remotes/" + remote + "/" + branch
+		if len(strings.TrimSpace(string(out))) == 0 {
+			parentRef = "" // the first commit of the branch
+		} else if _, err := git(ctx, repo, "fetch", "--quiet", remote, "+"+ref+":"+parentRef); err != nil {
+			return "", err
+		}
+	}
+	var parent string
+	if parentRef != "" {
+		// a missing branch fails the verification, in which case the commit is the first of the branch
+		if out, err := git(ctx, repo, "rev-parse", "--verify", "--quiet", parentRef+"^{commit}"); err == nil {
+			parent = strings.TrimSpace(string(out))
+		}
+	}
+
+	tree, err := p.writeTree(ctx, repo, siteDir)
+	if err != nil {
+		return "", err
+	}
+	if parent != "" {
+		out, err := git(ctx, repo, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(string(out)) == tree {
+			return "", nil
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", zerokit.Coalesce(p.Message, "Publish the vanity import pages")}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	var env []string
+	if p.AuthorName != "" {
+		env = append(env, "GIT_AUTHOR_NAME="+p.AuthorName, "GIT_COMMITTER_NAME="+p.AuthorName)
+	}
+	if p.AuthorEmail != "" {
+		env = append(env, "GIT_AUTHOR_EMAIL="+p.AuthorEmail, "GIT_COMMITTER_EMAIL="+p.AuthorEmail)
+	}
+	out, err := gitEnv(ctx, repo, env, args...)
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(out))
+
+	if _, err := git(ctx, repo, "update-ref", "-m", "publish", ref, commit); err != nil {
+		return "", err
+	}
+	if !p.NoPush {
+		if _, err := git(ctx, repo, "push", "--quiet", remote, commit+":"+ref); err != nil {
+			return "", err
+		}
+	}
+	return commit, nil
+}
+
+// writeTree writes the site's directory into the object database of the repository, and returns its tree.
+// The files are staged in a temporary index, which leaves the index of the repository untouched.
+func (p GitHubPages) writeTree(ctx context.Context, repo, siteDir string) (string, error) {
+	tmp, err := os.MkdirTemp("", "vanity-publish-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmp, "index"), "GIT_WORK_TREE=" + siteDir}
+	// the .gitignore files of the repository don't apply to the generated site, so every file is added
+	if _, err := gitEnv(ctx, repo, env, "add", "--all", "--force", ":/"); err != nil {
+		return "", err
+	}
+	out, err := gitEnv(ctx, repo, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
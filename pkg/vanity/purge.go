@@ -0,0 +1,248 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// CachePurger invalidates the cached copies of the site's files on a CDN, so the changed pages take effect right away,
+// rather than when their cached copies expire.
+type CachePurger interface {
+	// Purge invalidates the cached copies of the absolute URLs.
+	Purge(ctx context.Context, urls []string) error
+}
+
+// PurgeURLs are the absolute URLs of the changed files of a domain's site, in the order of their paths,
+// which a CDN in front of the site may have cached. The unchanged files are left out.
+// A page is purged with and without the trailing slash of its directory, and with the ?go-get=1 query of the go command,
+// since a CDN caches them apart by default.
+func PurgeURLs(domain string, changes []Change) []string {
+	host, basePath := splitDomain(domain)
+	seen := map[string]struct{}{}
+	for _, change := range changes {
+		if change.Kind == ChangeUnchanged {
+			continue
+		}
+		if path.Base(change.File.Path) != "index.html" {
+			seen[basePath+"/"+change.File.Path] = struct{}{}
+			continue
+		}
+		for _, p := range pageURLPaths(basePath, change.File.Path) {
+			seen[p] = struct{}{}
+			seen[p+"?go-get=1"] = struct{}{}
+		}
+	}
+	urls := make([]string, 0, len(seen))
+	for p := range seen {
+		urls = append(urls, "https://"+host+p)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// CloudflarePurger purges the URLs from the cache of a Cloudflare zone.
+// https://developers.cloudflare.com/api/operations/zone-purge
+type CloudflarePurger struct {
+	// ZoneID is the identifier of the zone of the domain.
+	ZoneID string
+	// APIToken is an API token with the Cache Purge permission of the zone.
+	APIToken string
+	// Endpoint is the base URL of the Cloudflare API.
+	//
+	// default: https://api.cloudflare.com/client/v4
+	Endpoint string
+	// Client is the HTTP client of the API requests.
+	Client *http.Client
+}
+
+// cloudflarePurgeBatch is the number of URLs that a single purge request of Cloudflare takes.
+const cloudflarePurgeBatch = 30
+
+type cloudflareResponseDTO struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (p CloudflarePurger) Purge(ctx context.Context, urls []string) error {
+	endpoint := strings.TrimSuffix(zerokit.Coalesce(p.Endpoint, "https://api.cloudflare.com/client/v4"), "/") +
+		"/zones/" + url.PathEscape(p.ZoneID) + "/purge_cache"
+	for start := 0; start < len(urls); start += cloudflarePurgeBatch {
+		end := start + cloudflarePurgeBatch
+		if len(urls) < end {
+			end = len(urls)
+		}
+		body, err := json.Marshal(map[string][]string{"files": urls[start:end]})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+p.APIToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := zerokit.Coalesce(p.Client, discoveryClient()).Do(req)
+		if err != nil {
+			return fmt.Errorf("cloudflare: %w", err)
+		}
+		var dto cloudflareResponseDTO
+		err = json.NewDecoder(resp.Body).Decode(&dto)
+		_ = drain(resp)
+		if err != nil {
+			return fmt.Errorf("cloudflare: %s: the response can't be decoded: %w", resp.Status, err)
+		}
+		if !dto.Success {
+			var msgs []string
+			for _, e := range dto.Errors {
+				msgs = append(msgs, e.Message)
+			}
+			return fmt.Errorf("cloudflare: the purge failed: %s: %s", resp.Status, strings.Join(msgs, "; "))
+		}
+	}
+	return nil
+}
+
+// FastlyPurger purges the URLs from the cache of Fastly, one URL at a time.
+// https://www.fastly.com/documentation/reference/api/purging/
+type FastlyPurger struct {
+	// APIToken is an API token with the purge_select scope.
+	APIToken string
+	// Soft marks the cached copies as outdated rather than removing them,
+	// so they can still be served while the origin can't be reached.
+	Soft bool
+	// Endpoint is the base URL of the Fastly API.
+	//
+	// default: https://api.fastly.com
+	Endpoint string
+	// Client is the HTTP client of the API requests.
+	Client *http.Client
+}
+
+func (p FastlyPurger) Purge(ctx context.Context, urls []string) error {
+	endpoint := strings.TrimSuffix(zerokit.Coalesce(p.Endpoint, "https://api.fastly.com"), "/")
+	for _, u := range urls {
+		// the purged URL is the path of the request, without its scheme, and with its query escaped
+		purged := strings.ReplaceAll(strings.TrimPrefix(u, "https://"), "?", "%3F")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/purge/"+purged, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Fastly-Key", p.APIToken)
+		req.Header.Set("Accept", "application/json")
+		if p.Soft {
+			req.Header.Set("Fastly-Soft-Purge", "1")
+		}
+		resp, err := zerokit.Coalesce(p.Client, discoveryClient()).Do(req)
+		if err != nil {
+			return fmt.Errorf("fastly: %w", err)
+		}
+		if resp.StatusCode < 200 || 299 < resp.StatusCode {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			_ = drain(resp)
+			return fmt.Errorf("fastly: the purge of %s failed: %s\n%s", u, resp.Status, msg)
+		}
+		_ = drain(resp)
+	}
+	return nil
+}
+
+// CloudFrontPurger invalidates the paths of the URLs in an Amazon CloudFront distribution.
+// CloudFront invalidates a path with every query string of it, so the query strings of the URLs are dropped.
+// https://docs.aws.amazon.com/cloudfront/latest/APIReference/API_CreateInvalidation.html
+type CloudFrontPurger struct {
+	// DistributionID is the identifier of the distribution of the domain.
+	DistributionID  string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint is the base URL of the CloudFront API.
+	//
+	// default: https://cloudfront.amazonaws.com
+	Endpoint string
+	// Client is the HTTP client of the API requests.
+	Client *http.Client
+}
+
+// cloudFrontPurgeBatch is the number of paths that a single invalidation of CloudFront takes.
+const cloudFrontPurgeBatch = 3000
+
+type cloudFrontInvalidationBatchDTO struct {
+	XMLName         xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Paths           cloudFrontPathsDTO
+	CallerReference string
+}
+
+type cloudFrontPathsDTO struct {
+	Quantity int
+	Items    []string `xml:"Items>Path"`
+}
+
+func (p CloudFrontPurger) Purge(ctx context.Context, urls []string) error {
+	var (
+		paths []string
+		seen  = map[string]struct{}{}
+	)
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("cloudfront: %w", err)
+		}
+		if _, ok := seen[u.EscapedPath()]; !ok {
+			seen[u.EscapedPath()] = struct{}{}
+			paths = append(paths, u.EscapedPath())
+		}
+	}
+	endpoint, err := url.Parse(zerokit.Coalesce(p.Endpoint, "https://cloudfront.amazonaws.com"))
+	if err != nil {
+		return fmt.Errorf("cloudfront: invalid endpoint: %w", err)
+	}
+	canonicalPath := strings.TrimSuffix(endpoint.EscapedPath(), "/") + "/2020-05-31/distribution/" + awsEscape(p.DistributionID, false) + "/invalidation"
+	for start := 0; start < len(paths); start += cloudFrontPurgeBatch {
+		end := start + cloudFrontPurgeBatch
+		if len(paths) < end {
+			end = len(paths)
+		}
+		body, err := xml.Marshal(cloudFrontInvalidationBatchDTO{
+			Paths: cloudFrontPathsDTO{Quantity: end - start, Items: paths[start:end]},
+			// the caller reference tells the retries of an invalidation apart from a new one
+			CallerReference: fmt.Sprintf("generate-go-redirect-%d-%d", time.Now().UnixNano(), start),
+		})
+		if err != nil {
+			return err
+		}
+		body = append([]byte(xml.Header), body...)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Scheme+"://"+endpoint.Host+canonicalPath, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/xml")
+		creds := awsCredentials{AccessKeyID: p.AccessKeyID, SecretAccessKey: p.SecretAccessKey, SessionToken: p.SessionToken}
+		// CloudFront is a global service, whose requests are signed for us-east-1
+		signAWS(req, canonicalPath, "", body, creds, "us-east-1", "cloudfront")
+		resp, err := zerokit.Coalesce(p.Client, discoveryClient()).Do(req)
+		if err != nil {
+			return fmt.Errorf("cloudfront: %w", err)
+		}
+		if resp.StatusCode < 200 || 299 < resp.StatusCode {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			_ = drain(resp)
+			return fmt.Errorf("cloudfront: the invalidation failed: %s\n%s", resp.Status, msg)
+		}
+		_ = drain(resp)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// ReachabilityOptions configure how the repositories are probed.
+type ReachabilityOptions struct {
+	// Workers is the number of repositories probed concurrently.
+	//
+	// default: 8
+	Workers int
+	// Timeout is how long a single probe may take.
+	//
+	// default: 30s
+	Timeout time.Duration
+}
+
+// Reachability is the outcome of probing the repository of a module.
+type Reachability struct {
+	// Prefix is the import prefix of the module.
+	Prefix string
+	// VCS and RepoRoot are the version control system and the URL of the probed repository.
+	VCS      string
+	RepoRoot string
+	// Skipped tells that there is no probe for the version control system, so the repository wasn't checked.
+	Skipped bool
+	// Duration is how long the probe took.
+	Duration time.Duration
+	// Err is the failure of the probe, which is nil for a reachable repository.
+	Err error
+}
+
+// Reachable tells whether the repository answered the probe.
+func (r Reachability) Reachable() bool {
+	return !r.Skipped && r.Err == nil
+}
+
+// CheckReachability probes the repository root of every module with the protocol of its version control system,
+// like git ls-remote or hg identify, and a module proxy with the version list of the module,
+// so the dead repository links are caught before their pages go live.
+// The repositories are probed concurrently, each within the timeout,
+// and the results are in the order of the modules. The gone modules have no repository to probe.
+func CheckReachability(ctx context.Context, metas []Meta, opts ReachabilityOptions) []Reachability {
+	var probed []Meta
+	for _, meta := range metas {
+		if !meta.Gone() && meta.Import.VCS.RepoRoot != nil {
+			probed = append(probed, meta)
+		}
+	}
+	var (
+		results = make([]Reachability, len(probed))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+		workers = opts.Workers
+	)
+	if workers < 1 {
+		workers = 8
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = probeRepo(ctx, probed[i], opts.Timeout)
+			}
+		}()
+	}
+	for i := range probed {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func probeRepo(ctx context.Context, meta Meta, timeout time.Duration) Reachability {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		vcs      = meta.Import.VCS
+		repoRoot = vcs.RepoRoot.String()
+		result   = Reachability{Prefix: meta.Import.Prefix, VCS: vcs.Name, RepoRoot: repoRoot}
+		start    = time.Now()
+		err      error
+	)
+	switch vcs.Name {
+	case VCSGit:
+		_, err = git(ctx, "", "ls-remote", repoRoot, "HEAD")
+	case VCSMercurial:
+		_, err = hg(ctx, "", "identify", repoRoot)
+	case VCSSubversion:
+		err = probeCommand(ctx, "svn", "info", "--non-interactive", repoRoot)
+	case VCSBazaar:
+		err = probeCommand(ctx, "bzr", "info", repoRoot)
+	case VCSMod:
+		err = probeModuleProxy(ctx, repoRoot, meta.Import.Prefix)
+	default:
+		result.Skipped = true
+		return result
+	}
+	result.Duration = time.Since(start)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("no answer within %s", timeout)
+	}
+	result.Err = err
+	return result
+}
+
+// probeCommand runs the command of a version control system, which only has to succeed.
+func probeCommand(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// probeModuleProxy asks the module proxy for the version list of the module.
+func probeModuleProxy(ctx context.Context, proxyURL, modulePath string) error {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(proxyURL, "/")+"/"+escaped+"/@v/list", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := discoveryClient().Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = drain(resp)
+		return fmt.Errorf("GET %s: unexpected status code: %s", req.URL, resp.Status)
+	}
+	return drain(resp)
+}
@@ -0,0 +1,78 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"path"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+)
+
+// renderMarkdown renders Markdown to HTML, with the GitHub flavoured extensions.
+func renderMarkdown(source []byte) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := goldmark.New(goldmark.WithExtensions(extension.GFM)).Convert(source, &buf); err != nil {
+		return "", err
+	}
+	// goldmark leaves out the raw HTML of the Markdown, so its output is safe to embed
+	return template.HTML(buf.String()), nil
+}
+
+// readmeNames are the file names of a README, in the order of their preference.
+var readmeNames = []string{"README.md", "readme.md", "README.markdown", "Readme.md"}
+
+// FetchReadmes sets the rendered READMEs of the modules and their nested modules,
+// from the default branch of their git and Mercurial repositories.
+// The Markdown is rendered with the GitHub flavoured extensions, and the raw HTML of a README is left out.
+// A repository that can't be reached is only warned about, and its modules are left without a README.
+func FetchReadmes(ctx context.Context, metas []Meta) []Meta {
+	for i, meta := range metas {
+		if meta.Private || meta.Tombstone.Kind != "" || !isClonable(meta.Import.VCS.SourceRepo().Name) {
+			continue
+		}
+		readmes, err := fetchReadmes(ctx, meta)
+		if err != nil {
+			logger.Warn(ctx, "README fetching failed",
+				logging.Field("module", meta.Import.Prefix),
+				logging.ErrField(err))
+			continue
+		}
+		metas[i].Readmes = readmes
+	}
+	return metas
+}
+
+func fetchReadmes(ctx context.Context, meta Meta) (_ map[string]template.HTML, rErr error) {
+	repo := meta.Import.VCS.SourceRepo()
+	clone, err := cloneRepo(ctx, repo.Name, repo.RepoRoot.String())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	dirs := map[string]string{meta.Import.Prefix: ""}
+	for _, nested := range meta.Nested {
+		dirs[nested.ImportPath] = nested.Path
+	}
+	readmes := map[string]template.HTML{}
+	for importPath, dir := range dirs {
+		for _, name := range readmeNames {
+			data, err := clone.ReadFile(ctx, path.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			readme, err := renderMarkdown(data)
+			if err != nil {
+				return nil, err
+			}
+			readmes[importPath] = readme
+			break
+		}
+	}
+	return readmes, nil
+}
@@ -0,0 +1,119 @@
+package vanity
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+const (
+	RedirectDocs     = "docs"
+	RedirectPkgGoDev = "pkg.go.dev"
+	RedirectHomepage = "homepage"
+	RedirectRepo     = "repo"
+)
+
+var redirectTargets = []string{RedirectDocs, RedirectPkgGoDev, RedirectHomepage, RedirectRepo}
+
+// The modes of the redirect of the browser visitors.
+const (
+	RedirectModeScript  = "script"
+	RedirectModeRefresh = "refresh"
+	RedirectModeDelayed = "delayed"
+	RedirectModeNone    = "none"
+)
+
+func isRedirectTarget(target string) bool {
+	for _, t := range redirectTargets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectURL tells where a browser visitor of the import path is sent.
+// The import path can be the module's, a nested module's, or one of their packages.
+// It returns an empty string when none of the redirect targets are available.
+func (meta Meta) RedirectURL(importPath string) string {
+	targets := meta.Redirect.Targets
+	if len(targets) == 0 {
+		targets = []string{RedirectHomepage}
+	}
+	for _, target := range targets {
+		switch target {
+		case RedirectDocs:
+			if meta.Redirect.DocsURL != "" {
+				return meta.Redirect.DocsURL
+			}
+		case RedirectPkgGoDev:
+			return "https://pkg.go.dev/" + importPath
+		case RedirectHomepage:
+			if meta.Source.HomepageURL != "" {
+				return meta.Source.HomepageURL
+			}
+		case RedirectRepo:
+			if repo := meta.Import.VCS.SourceRepo(); repo.RepoRoot != nil {
+				return repo.RepoRoot.String()
+			}
+		}
+	}
+	return ""
+}
+
+// Page is the data of the module page templates.
+// The Meta of the module is embedded, so its fields, like .Import.Prefix or .Description, are at hand as they are.
+type Page struct {
+	Meta
+	// ImportPath is the import path of the page, which is the module's, or a nested module's.
+	ImportPath string
+	// RedirectURL is where the browser visitors of the page are sent.
+	RedirectURL string
+	// Landing makes the page a landing page, which the browser visitors stay on, rather than a redirect.
+	Landing bool
+	// Readme is the rendered README of the landing page's module.
+	Readme template.HTML
+	// License is the SPDX identifier of the license of the landing page's module, when the site shows it.
+	License string
+	// Changelog is the rendered latest entries of the changelog of the landing page's module, when the site shows them.
+	Changelog template.HTML
+	// RepositoryURL is the web page of the module's source repository.
+	RepositoryURL string
+	// VersionsURL is the version listing page of the landing page's module, when the site has one.
+	VersionsURL string
+	// PrivateGuide makes the page the guide of a private module, which tells how to set up the go command to download it.
+	PrivateGuide bool
+	// RepositoryHost is the host of the private module's repository, which the credentials are for.
+	RepositoryHost string
+}
+
+// Refresh is the content of the meta refresh of the page, like "0; url=https://github.com/org/repo",
+// which is empty when the page doesn't send its visitors away with one.
+func (p Page) Refresh() string {
+	if p.RedirectURL == "" || p.Landing || p.PrivateGuide || p.Deprecated() || p.Tombstone.Kind != "" {
+		return ""
+	}
+	switch p.Redirect.Mode {
+	case RedirectModeRefresh:
+		return "0; url=" + p.RedirectURL
+	case RedirectModeDelayed:
+		return fmt.Sprintf("%d; url=%s", p.Redirect.DelaySeconds(), p.RedirectURL)
+	default:
+		return ""
+	}
+}
+
+func newPage(meta Meta, importPath string) Page {
+	return Page{Meta: meta, ImportPath: importPath, RedirectURL: meta.RedirectURL(importPath)}
+}
+
+// newLandingPage is the page of an import path with its README, install instructions and documentation links.
+func newLandingPage(meta Meta, importPath string) Page {
+	p := newPage(meta, importPath)
+	p.Landing = true
+	p.Readme = meta.Readmes[importPath]
+	if repo := meta.Import.VCS.SourceRepo(); repo.RepoRoot != nil {
+		p.RepositoryURL = strings.TrimSuffix(repo.RepoRoot.String(), ".git")
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package vanity
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"golang.org/x/mod/modfile"
+)
+
+// MetaRelease is the latest release of a module.
+type MetaRelease struct {
+	// Version is the highest release version, or the highest pre-release when there is no release yet.
+	Version string
+	// GoVersion is the Go version that the go.mod file of the release requires.
+	GoVersion string
+}
+
+// DetectReleases sets the latest release of the modules, their nested modules and their major versions,
+// from the version tags of their git repositories, the same way as the module proxy lists them.
+// A repository that can't be reached is only warned about, and its modules are left without a release.
+func DetectReleases(ctx context.Context, metas []Meta) []Meta {
+	var src GitSource
+	for i, meta := range metas {
+		repo := meta.Import.VCS.SourceRepo()
+		if repo.Name != VCSGit || meta.Tombstone.Kind != "" {
+			continue
+		}
+		mods := []ProxyModule{{Path: meta.Import.Prefix, RepoRoot: repo.RepoRoot.String()}}
+		for _, nested := range meta.Nested {
+			mods = append(mods, ProxyModule{Path: nested.ImportPath, RepoRoot: repo.RepoRoot.String(), Dir: nested.Path})
+		}
+		for _, major := range meta.Majors {
+			mods = append(mods, ProxyModule{Path: major, RepoRoot: repo.RepoRoot.String()})
+		}
+		releases := map[string]MetaRelease{}
+		for _, mod := range mods {
+			versions, err := src.List(ctx, mod)
+			if err != nil {
+				logger.Warn(ctx, "release detection failed",
+					logging.Field("module", meta.Import.Prefix),
+					logging.ErrField(err))
+				break
+			}
+			version, ok := latestVersion(versions)
+			if !ok {
+				continue
+			}
+			goVersion, err := releaseGoVersion(ctx, mod, version)
+			if err != nil {
+				logger.Warn(ctx, "the go.mod file of the release can't be read",
+					logging.Field("module", mod.Path),
+					logging.Field("version", version),
+					logging.ErrField(err))
+			}
+			releases[mod.Path] = MetaRelease{Version: version, GoVersion: goVersion}
+		}
+		metas[i].Releases = releases
+	}
+	return metas
+}
+
+// releaseGoVersion reads the go directive of the module's go.mod file from the tag of the release.
+func releaseGoVersion(ctx context.Context, mod ProxyModule, version string) (_ string, rErr error) {
+	dir, err := os.MkdirTemp("", "clone-*.git")
+	if err != nil {
+		return "", err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, os.RemoveAll(dir)) }()
+
+	tag := tagPrefix(mod) + version
+	if _, err := git(ctx, "", "clone", "--quiet", "--bare", "--depth=1", "--filter=blob:none", "--branch", tag, mod.RepoRoot, dir); err != nil {
+		return "", err
+	}
+	data, err := git(ctx, dir, "show", "HEAD:"+path.Join(mod.Dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return "", err
+	}
+	if f.Go == nil {
+		return "", nil
+	}
+	return f.Go.Version, nil
+}
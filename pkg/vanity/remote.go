@@ -0,0 +1,175 @@
+package vanity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/httpkit"
+	"go.llib.dev/frameless/pkg/retry"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// RemoteImportsConfig is the configuration for fetching the imports file over HTTP.
+type RemoteImportsConfig struct {
+	// Timeout is the time limit for a single request attempt.
+	//
+	// default: 30s
+	Timeout time.Duration
+	// Retries is the number of retries on a temporary failure, which a negative number turns off.
+	//
+	// default: 3
+	Retries int
+	// SHA256 is the expected hex encoded checksum of the fetched imports file.
+	// When set, a fetched file with a different checksum is rejected.
+	SHA256 string
+}
+
+// IsRemoteImports tells whether the imports location is an http(s) URL.
+func IsRemoteImports(location string) bool {
+	return strings.HasPrefix(location, "https://") ||
+		strings.HasPrefix(location, "http://")
+}
+
+// readImportsURL fetches the imports file from a remote location, such as a config service or a raw GitHub URL.
+func readImportsURL(ctx context.Context, location string, opts ImportsOptions) ([]ImportDTO, error) {
+	conf := opts.Remote
+	retries := zerokit.Coalesce(conf.Retries, 3)
+	if retries < 0 {
+		retries = 0
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid imports url: %w", err)
+	}
+
+	// the timeout is of every attempt, while the context of the request limits them all together
+	client := &http.Client{
+		Transport: httpkit.RetryRoundTripper{
+			Transport: attemptTimeout{Timeout: zerokit.Coalesce(conf.Timeout, 30*time.Second)},
+			RetryStrategy: retry.ExponentialBackoff{
+				MaxRetries: retries,
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch imports file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch imports file: unexpected status code: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imports file response: %w", err)
+	}
+
+	if conf.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if checksum := hex.EncodeToString(sum[:]); !strings.EqualFold(checksum, conf.SHA256) {
+			return nil, fmt.Errorf("imports file checksum mismatch: expected %s, got %s", conf.SHA256, checksum)
+		}
+	}
+
+	format, err := ImportsFormat(u.Path, opts.Format)
+	if err != nil {
+		// the URL path doesn't always have an extension,
+		// so we fall back to the content type of the response.
+		format, err = ImportsFormat("", contentTypeFormat(resp.Header.Get("Content-Type")))
+		if err != nil {
+			return nil, fmt.Errorf("unable to tell the format of %s, use the -format flag", path.Base(u.Path))
+		}
+	}
+
+	dtos, err := decodeImports(format, data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s imports file: %w", format, err)
+	}
+	return dtos, nil
+}
+
+// attemptTimeout limits a single attempt of a request, from sending it until its response body is closed.
+type attemptTimeout struct {
+	// Transport makes the requests.
+	//
+	// default: http.DefaultTransport
+	Transport http.RoundTripper
+	// Timeout is the time limit of an attempt, which is unlimited when it's zero.
+	Timeout time.Duration
+}
+
+func (t attemptTimeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if t.Timeout <= 0 {
+		return transport.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timedOut := ctx.Err() != nil && req.Context().Err() == nil
+		cancel()
+		if timedOut {
+			// a timed out attempt is a temporary failure, which is retried
+			return nil, attemptTimeoutError{err: err}
+		}
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose is a response body that ends the context of its attempt when it's closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+type attemptTimeoutError struct{ err error }
+
+func (e attemptTimeoutError) Error() string   { return "request attempt timed out: " + e.err.Error() }
+func (e attemptTimeoutError) Unwrap() error   { return e.err }
+func (e attemptTimeoutError) Timeout() bool   { return true }
+func (e attemptTimeoutError) Temporary() bool { return true }
+
+func contentTypeFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	case "application/toml":
+		return FormatTOML
+	default:
+		return ""
+	}
+}
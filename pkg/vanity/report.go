@@ -0,0 +1,216 @@
+package vanity
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The statuses of the modules in a Report.
+const (
+	// ModuleWritten is a module whose pages were created or updated.
+	ModuleWritten = "written"
+	// ModuleUnchanged is a module whose pages were already up to date.
+	ModuleUnchanged = "unchanged"
+	// ModuleFailed is a module whose pages failed to render.
+	ModuleFailed = "failed"
+	// ModuleSkipped is a module without pages in the site, like a private module.
+	ModuleSkipped = "skipped"
+	// ModuleAborted is a module whose pages were not written, since the generation failed.
+	ModuleAborted = "aborted"
+)
+
+// Report is the machine-readable outcome of a generation, for the CI pipelines to surface its failures and statistics.
+// When it's set in the GenerateOptions, the rendering and the writing of the site fill it,
+// and Finish completes it with the outcome of the whole generation.
+type Report struct {
+	// Status is ok, partial when some modules failed with the ContinueOnError option, or failed when the generation failed.
+	Status string `json:"status"`
+	// Error is the failure of the generation.
+	Error string `json:"error,omitempty"`
+	// Started is when the generation started.
+	Started time.Time `json:"started"`
+	// DurationMS is how long the generation took, in milliseconds.
+	DurationMS int64 `json:"duration-ms"`
+	// Files counts the files of the whole site, including the ones that no module owns, like the index page.
+	Files ReportFiles `json:"files"`
+	// Modules are the outcomes of the modules, in the order of the imports file.
+	Modules []ModuleReport `json:"modules"`
+	// Warnings are the warnings logged during the generation, like an unreachable repository.
+	Warnings []ReportWarning `json:"warnings"`
+
+	mu sync.Mutex
+}
+
+// ReportFiles counts the files of the output directory by what happened to them.
+type ReportFiles struct {
+	Written int `json:"written"`
+	Skipped int `json:"skipped"`
+	Deleted int `json:"deleted"`
+}
+
+// ModuleReport is the outcome of a module.
+type ModuleReport struct {
+	// Prefix is the import prefix of the module.
+	Prefix string `json:"prefix"`
+	// Status is written, unchanged, failed, skipped or aborted.
+	Status string `json:"status"`
+	// Error is the failure of rendering the pages of the module.
+	Error string `json:"error,omitempty"`
+	// Files counts the files of the module.
+	Files ReportFiles `json:"files"`
+	// DurationMS is how long the rendering of the module's pages took, in milliseconds.
+	DurationMS int64 `json:"duration-ms"`
+}
+
+// ReportWarning is a warning logged during the generation.
+type ReportWarning struct {
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// NewReport creates the report of a generation that starts now.
+func NewReport() *Report {
+	return &Report{Started: time.Now().UTC(), Modules: []ModuleReport{}, Warnings: []ReportWarning{}}
+}
+
+// AddWarning records a warning of the generation. It's safe to call concurrently.
+func (r *Report) AddWarning(message string, fields map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, ReportWarning{Message: message, Fields: fields})
+}
+
+func (r *Report) module(prefix string) *ModuleReport {
+	for i := range r.Modules {
+		if r.Modules[i].Prefix == prefix {
+			return &r.Modules[i]
+		}
+	}
+	r.Modules = append(r.Modules, ModuleReport{Prefix: prefix})
+	return &r.Modules[len(r.Modules)-1]
+}
+
+// observeRender records the outcome of rendering the modules.
+// The modules that had nothing to render in the site are left out, since they may have pages in another site.
+func (r *Report) observeRender(metas []Meta, results [][]File, errs []error, durations []time.Duration) {
+	if r == nil {
+		return
+	}
+	for i, meta := range metas {
+		if errs[i] == nil && len(results[i]) == 0 {
+			continue
+		}
+		m := r.module(meta.Import.Prefix)
+		m.DurationMS += durations[i].Milliseconds()
+		if errs[i] != nil {
+			m.Error = errs[i].Error()
+		}
+	}
+}
+
+// observeChanges records the changes applied on the output directory.
+func (r *Report) observeChanges(changes []Change) {
+	if r == nil {
+		return
+	}
+	for _, change := range changes {
+		count := func(files *ReportFiles) {
+			switch change.Kind {
+			case ChangeCreate, ChangeUpdate:
+				files.Written++
+			case ChangeUnchanged:
+				files.Skipped++
+			case ChangeDelete:
+				files.Deleted++
+			}
+		}
+		count(&r.Files)
+		if change.File.Module != "" {
+			count(&r.module(change.File.Module).Files)
+		}
+	}
+}
+
+// Finish completes the report with the outcome of the generation:
+// its duration, its error, and the status of every module, ordered like the modules.
+func (r *Report) Finish(metas []Meta, err error) {
+	r.DurationMS = time.Since(r.Started).Milliseconds()
+	r.Status = "ok"
+	var failures ModuleErrors
+	switch {
+	case errors.As(err, &failures):
+		r.Status, r.Error = "partial", err.Error()
+	case err != nil:
+		r.Status, r.Error = "failed", err.Error()
+	}
+	modules := make([]ModuleReport, 0, len(metas))
+	for _, meta := range metas {
+		m := *r.module(meta.Import.Prefix)
+		switch {
+		case m.Error != "":
+			m.Status = ModuleFailed
+		case 0 < m.Files.Written:
+			m.Status = ModuleWritten
+		case 0 < m.Files.Skipped:
+			m.Status = ModuleUnchanged
+		case meta.Private:
+			m.Status = ModuleSkipped
+		case err != nil && len(failures) == 0:
+			m.Status = ModuleAborted
+		default:
+			m.Status = ModuleSkipped
+		}
+		modules = append(modules, m)
+	}
+	r.Modules = modules
+}
+
+// WriteSummary writes the human-readable summary of the report as Markdown,
+// which CI systems like GitHub Actions show on the page of the run.
+func (r *Report) WriteSummary(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Vanity import pages: %s\n\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", r.Error)
+	}
+	fmt.Fprintf(&b, "%d files written, %d unchanged files skipped, %d files pruned in %s.\n\n",
+		r.Files.Written, r.Files.Skipped, r.Files.Deleted, time.Duration(r.DurationMS)*time.Millisecond)
+	b.WriteString("| module | status | written | skipped | error |\n")
+	b.WriteString("|--------|--------|---------|---------|-------|\n")
+	for _, m := range r.Modules {
+		fmt.Fprintf(&b, "| `%s` | %s | %d | %d | %s |\n", m.Prefix, m.Status, m.Files.Written, m.Files.Skipped, markdownCell(m.Error))
+	}
+	if 0 < len(r.Warnings) {
+		b.WriteString("\n**Warnings**\n\n")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(&b, "- %s", warning.Message)
+			if 0 < len(warning.Fields) {
+				var fields []string
+				for key, value := range warning.Fields {
+					// an error field is logged as an object with its message
+					if obj, ok := value.(map[string]any); ok && obj["message"] != nil {
+						value = obj["message"]
+					}
+					fields = append(fields, fmt.Sprintf("%s=%v", key, value))
+				}
+				sort.Strings(fields)
+				fmt.Fprintf(&b, " (%s)", strings.Join(fields, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes the text of a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,45 @@
+package vanity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// robotsFileName is the name of the robots.txt within the output directory.
+const robotsFileName = "robots.txt"
+
+// renderRobots renders the robots.txt of the site.
+// Every page may be crawled, except the pages of the modules that opt out with NoCrawl,
+// and the crawlers are pointed at the sitemap, when the site has one.
+// The paths are under the base path of the domain,
+// though the crawlers only read the robots.txt of a site hosted at the root of its domain.
+func renderRobots(domain string, metas []Meta, sitemap bool) File {
+	_, basePath := splitDomain(domain)
+	var disallowed []string
+	for _, meta := range metas {
+		// a private module is not published, and its name is not revealed here either
+		if !meta.NoCrawl || meta.Private {
+			continue
+		}
+		dir := strings.TrimPrefix(strings.TrimPrefix(meta.Import.Prefix, domain), "/")
+		if dir == "" {
+			disallowed = append(disallowed, basePath+"/")
+			continue
+		}
+		// the module's page, and the pages under it, without matching the modules with the same prefix
+		disallowed = append(disallowed, basePath+"/"+dir+"$", basePath+"/"+dir+"/")
+	}
+	sort.Strings(disallowed)
+
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	fmt.Fprintf(&b, "Allow: %s/\n", basePath)
+	for _, p := range disallowed {
+		fmt.Fprintf(&b, "Disallow: %s\n", p)
+	}
+	if sitemap {
+		fmt.Fprintf(&b, "\nSitemap: https://%s/%s\n", domain, sitemapFileName)
+	}
+	return File{Path: robotsFileName, Content: []byte(b.String())}
+}
@@ -0,0 +1,258 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// S3Bucket is an S3 bucket, or a bucket of an S3 compatible object storage,
+// like Google Cloud Storage with HMAC keys, Cloudflare R2 or MinIO.
+// The requests are signed with AWS Signature Version 4.
+type S3Bucket struct {
+	// Name is the name of the bucket.
+	Name string
+	// Prefix is the key prefix of the site's objects within the bucket, like a directory.
+	Prefix string
+	// Region is the region of the bucket, like us-east-1.
+	//
+	// default: us-east-1
+	Region string
+	// Endpoint is the address of an S3 compatible storage, like https://storage.googleapis.com.
+	// The bucket is addressed by the path, like https://storage.googleapis.com/bucket/key.
+	//
+	// default: the S3 endpoint of the region
+	Endpoint string
+	// AccessKeyID and SecretAccessKey are the credentials that the requests are signed with.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials.
+	SessionToken string
+	// Client is the HTTP client of the requests.
+	//
+	// default: a client that retries the temporary failures
+	Client *http.Client
+}
+
+type s3ListBucketResultDTO struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List lists the objects under the prefix with the ListObjectsV2 API.
+// The ETag of an object uploaded in a single request is the MD5 hash of its content.
+func (b S3Bucket) List(ctx context.Context) (map[string]string, error) {
+	var (
+		objects = map[string]string{}
+		prefix  = b.keyPrefix()
+		token   string
+	)
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListBucketResultDTO
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: failed to decode the object listing: %w", err)
+		}
+		for _, obj := range result.Contents {
+			objects[strings.TrimPrefix(obj.Key, prefix)] = strings.Trim(obj.ETag, `"`)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (b S3Bucket) Put(ctx context.Context, obj Object) error {
+	sum := md5.Sum(obj.Content)
+	header := http.Header{}
+	header.Set("Content-Type", obj.ContentType)
+	header.Set("Cache-Control", obj.CacheControl)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := b.do(ctx, http.MethodPut, b.keyPrefix()+obj.Key, nil, obj.Content, header)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (b S3Bucket) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, b.keyPrefix()+key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return drain(resp)
+}
+
+func (b S3Bucket) keyPrefix() string {
+	if prefix := strings.Trim(b.Prefix, "/"); prefix != "" {
+		return prefix + "/"
+	}
+	return ""
+}
+
+func (b S3Bucket) region() string {
+	return zerokit.Coalesce(b.Region, "us-east-1")
+}
+
+func (b S3Bucket) endpoint() string {
+	return strings.TrimSuffix(zerokit.Coalesce(b.Endpoint, "https://s3."+b.region()+".amazonaws.com"), "/")
+}
+
+// do sends a signed request to the bucket, or to an object of it by its key.
+func (b S3Bucket) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	endpoint, err := url.Parse(b.endpoint())
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid endpoint: %w", err)
+	}
+	canonicalPath := endpoint.EscapedPath() + "/" + awsEscape(b.Name, false)
+	if key != "" {
+		canonicalPath += "/" + awsEscape(key, true)
+	}
+	canonicalQuery := awsCanonicalQuery(query)
+	rawURL := endpoint.Scheme + "://" + endpoint.Host + canonicalPath
+	if canonicalQuery != "" {
+		rawURL += "?" + canonicalQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	creds := awsCredentials{AccessKeyID: b.AccessKeyID, SecretAccessKey: b.SecretAccessKey, SessionToken: b.SessionToken}
+	signAWS(req, canonicalPath, canonicalQuery, body, creds, b.region(), "s3")
+
+	resp, err := zerokit.Coalesce(b.Client, discoveryClient()).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	if resp.StatusCode < 200 || 299 < resp.StatusCode {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3: %s %s: unexpected status code: %s\n%s", method, req.URL.Redacted(), resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// awsCredentials are the credentials of the requests signed with AWS Signature Version 4.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// signAWS signs the request to the service of the region with AWS Signature Version 4.
+// The canonical path and query are the escaped path and query of the request's URL.
+func signAWS(req *http.Request, canonicalPath, canonicalQuery string, body []byte, creds awsCredentials, region, service string) {
+	var (
+		now         = time.Now().UTC()
+		amzDate     = now.Format("20060102T150405Z")
+		scope       = now.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+		payloadHash = sha256.Sum256(body)
+	)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	// the host and the x-amz headers are signed, the rest of the headers don't have to be
+	signed := []string{"host"}
+	for k := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			signed = append(signed, k)
+		}
+	}
+	sort.Strings(signed)
+	var canonicalHeaders strings.Builder
+	for _, k := range signed {
+		value := req.Header.Get(k)
+		if k == "host" {
+			value = req.URL.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, strings.TrimSpace(value))
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), region, service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, strings.Join(signed, ";"), hex.EncodeToString(hmacSHA256(signingKey, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape encodes a path or a query value by the rules of Signature Version 4,
+// which only leave the unreserved characters unescaped, and the slashes of a key.
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsCanonicalQuery is the query string in the canonical form of Signature Version 4, sorted by the keys.
+func awsCanonicalQuery(query url.Values) string {
+	var params []string
+	for k, values := range query {
+		for _, v := range values {
+			params = append(params, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
@@ -0,0 +1,150 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+)
+
+// SubmoduleScanner finds the nested modules of a repository by the go.mod files in it,
+// so the submodules of a monorepo don't have to be listed by hand, and they don't drift from the repository.
+type SubmoduleScanner struct {
+	// CheckoutDir is an optional directory of local checkouts, where each checkout is named after its repository.
+	// A repository without a local checkout is cloned.
+	CheckoutDir string
+}
+
+// ScanSubmodules adds the nested modules found in the repository of each module to its configured ones.
+func ScanSubmodules(ctx context.Context, metas []Meta, scanner SubmoduleScanner) ([]Meta, error) {
+	var errs []error
+	for i, meta := range metas {
+		repo := meta.Import.VCS.SourceRepo()
+		if !isClonable(repo.Name) || meta.Tombstone.Kind != "" {
+			continue
+		}
+		dirs, err := scanner.Scan(ctx, repo.Name, repo.RepoRoot.String())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", meta.Import.Prefix, err))
+			continue
+		}
+		known := map[string]struct{}{}
+		for _, nested := range meta.Nested {
+			known[nested.Path] = struct{}{}
+		}
+		for _, dir := range dirs {
+			if _, ok := known[dir]; ok {
+				continue
+			}
+			meta.Nested = append(meta.Nested, MetaNestedModule{
+				Path:       dir,
+				ImportPath: path.Join(meta.Import.Prefix, dir),
+			})
+		}
+		metas[i] = meta
+	}
+	return metas, errorkit.Merge(errs...)
+}
+
+// Scan returns the directories of the nested modules in the repository, relative to its root.
+// The vcs is the version control system of the repository, e.g. git or hg.
+func (s SubmoduleScanner) Scan(ctx context.Context, vcs, repoURL string) ([]string, error) {
+	if s.CheckoutDir != "" {
+		name := strings.TrimSuffix(path.Base(strings.TrimSuffix(repoURL, "/")), ".git")
+		checkout := filepath.Join(s.CheckoutDir, name)
+		if info, err := os.Stat(checkout); err == nil && info.IsDir() {
+			return scanCheckout(checkout)
+		}
+	}
+	return scanClone(ctx, vcs, repoURL)
+}
+
+// scanCheckout walks a local checkout for the go.mod files.
+func scanCheckout(dir string) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(os.DirFS(dir), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && name != "." && isIgnoredDir(d.Name()) {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && d.Name() == "go.mod" {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nestedModuleDirs(files), nil
+}
+
+// scanClone lists the go.mod files of the repository from the tree of its default branch.
+func scanClone(ctx context.Context, vcs, repoURL string) (_ []string, rErr error) {
+	clone, err := cloneRepo(ctx, vcs, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, clone.Close()) }()
+
+	names, err := clone.Files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range names {
+		if path.Base(name) == "go.mod" {
+			files = append(files, name)
+		}
+	}
+	return nestedModuleDirs(files), nil
+}
+
+// shallowClone makes a temporary clone of the default branch's last commit,
+// without the file contents, which are only fetched when they are read.
+func shallowClone(ctx context.Context, repoURL string) (string, error) {
+	dir, err := os.MkdirTemp("", "clone-*.git")
+	if err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, "", "clone", "--quiet", "--bare", "--depth=1", "--filter=blob:none", repoURL, dir); err != nil {
+		return "", errorkit.Merge(err, os.RemoveAll(dir))
+	}
+	return dir, nil
+}
+
+// nestedModuleDirs turns the paths of the go.mod files into the directories of the nested modules.
+// The root module, and the directories that the go command ignores, like testdata and vendor, are left out.
+func nestedModuleDirs(goModFiles []string) []string {
+	var dirs []string
+	for _, name := range goModFiles {
+		dir := path.Dir(name)
+		if dir == "." || isIgnoredPath(dir) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func isIgnoredPath(dir string) bool {
+	for _, elem := range strings.Split(dir, "/") {
+		if isIgnoredDir(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnoredDir tells whether the go command ignores the directory.
+func isIgnoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor"
+}
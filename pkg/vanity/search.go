@@ -0,0 +1,66 @@
+package vanity
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+)
+
+// searchIndexFileName is the search index of the modules within the site.
+const searchIndexFileName = "search-index.json"
+
+type searchIndexDTO struct {
+	Domain  string           `json:"domain"`
+	Modules []searchEntryDTO `json:"modules"`
+}
+
+type searchEntryDTO struct {
+	// Path is the import path of the module.
+	Path string `json:"path"`
+	// Name is the title of the module, or the last element of its import path.
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Submodules are the import paths of the nested modules, so a search for them finds their module.
+	Submodules []string `json:"submodules,omitempty"`
+	// URL is the URL path of the module's page.
+	URL string `json:"url"`
+}
+
+// renderSearchIndex renders the search index of the public modules of the domain,
+// which a client-side search, like the one of the index page, fetches.
+// The private and the gone modules are left out, like from the index page.
+func renderSearchIndex(domain string, metas []Meta) (File, error) {
+	_, basePath := splitDomain(domain)
+	index := searchIndexDTO{Domain: domain, Modules: []searchEntryDTO{}}
+	for _, meta := range metas {
+		prefix := meta.Import.Prefix
+		if meta.Private || meta.Gone() || (prefix != domain && !strings.HasPrefix(prefix, domain+"/")) {
+			continue
+		}
+		entry := searchEntryDTO{
+			Path:        prefix,
+			Name:        meta.Title,
+			Description: meta.Description,
+			Category:    meta.Category,
+			Tags:        meta.Tags,
+			URL:         pageURLPaths(basePath, pagePath(domain, prefix))[0],
+		}
+		if entry.Name == "" {
+			entry.Name = path.Base(prefix)
+		}
+		for _, nested := range meta.Nested {
+			entry.Submodules = append(entry.Submodules, nested.ImportPath)
+		}
+		index.Modules = append(index.Modules, entry)
+	}
+	sort.Slice(index.Modules, func(i, j int) bool { return index.Modules[i].Path < index.Modules[j].Path })
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: searchIndexFileName, Content: append(content, '\n')}, nil
+}
@@ -0,0 +1,93 @@
+package vanity
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SecurityHeaders are the security headers of the pages, which the server sets on its responses,
+// and the header files of the static hosts declare.
+// An empty header falls back to its default, and SecurityHeaderOff leaves it out.
+type SecurityHeaders struct {
+	// ContentSecurityPolicy is the Content-Security-Policy of the pages.
+	//
+	// default: the resources of the site, https images, and the inline scripts of the page by their hashes
+	ContentSecurityPolicy string
+	// ContentTypeOptions is the X-Content-Type-Options header of every response.
+	//
+	// default: nosniff
+	ContentTypeOptions string
+	// ReferrerPolicy is the Referrer-Policy header of every response.
+	//
+	// default: strict-origin-when-cross-origin
+	ReferrerPolicy string
+}
+
+// SecurityHeaderOff is the value of a security header that leaves it out.
+const SecurityHeaderOff = "off"
+
+// siteHeaders are the headers of every response of the site, as name and value pairs.
+func (h SecurityHeaders) siteHeaders() [][2]string {
+	var headers [][2]string
+	for _, header := range [][3]string{
+		{"X-Content-Type-Options", h.ContentTypeOptions, "nosniff"},
+		{"Referrer-Policy", h.ReferrerPolicy, "strict-origin-when-cross-origin"},
+	} {
+		name, value, fallback := header[0], header[1], header[2]
+		switch value {
+		case SecurityHeaderOff:
+			continue
+		case "":
+			value = fallback
+		}
+		headers = append(headers, [2]string{name, value})
+	}
+	return headers
+}
+
+// pagePolicy is the Content-Security-Policy of a page, which is empty when it's turned off.
+func (h SecurityHeaders) pagePolicy(page []byte) string {
+	switch h.ContentSecurityPolicy {
+	case SecurityHeaderOff:
+		return ""
+	case "":
+		return contentSecurityPolicy(page)
+	default:
+		return h.ContentSecurityPolicy
+	}
+}
+
+// sitePolicy is the Content-Security-Policy of the whole site, for the hosts that can't set it per page.
+// The default policy depends on the scripts of each page, so only a configured policy applies to the whole site.
+func (h SecurityHeaders) sitePolicy() string {
+	if h.ContentSecurityPolicy == SecurityHeaderOff {
+		return ""
+	}
+	return h.ContentSecurityPolicy
+}
+
+// headerRules are the lines of the headers in a _headers file rule.
+func (h SecurityHeaders) headerRules(csp string) string {
+	var b strings.Builder
+	for _, header := range h.siteHeaders() {
+		fmt.Fprintf(&b, "  %s: %s\n", header[0], header[1])
+	}
+	if csp != "" {
+		fmt.Fprintf(&b, "  Content-Security-Policy: %s\n", csp)
+	}
+	return b.String()
+}
+
+// set sets the security headers of a response, with the Content-Security-Policy of the page, when the response is a page.
+func (h SecurityHeaders) set(header http.Header, page []byte) {
+	for _, sh := range h.siteHeaders() {
+		header.Set(sh[0], sh[1])
+	}
+	if page == nil {
+		return
+	}
+	if csp := h.pagePolicy(page); csp != "" {
+		header.Set("Content-Security-Policy", csp)
+	}
+}
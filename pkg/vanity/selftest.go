@@ -0,0 +1,334 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// SelfTestOptions configure how the go command downloads the modules in a SelfTest.
+type SelfTestOptions struct {
+	// Go is the go command.
+	//
+	// default: go
+	Go string
+	// Workers is the number of import paths downloaded concurrently.
+	//
+	// default: 4
+	Workers int
+	// Timeout is how long the download of a single import path may take.
+	//
+	// default: 2m
+	Timeout time.Duration
+	// Env is added to the environment of the go command, like the credentials of a private repository.
+	Env []string
+}
+
+// SelfTestResult is the outcome of downloading an import path with the go command.
+type SelfTestResult struct {
+	// ImportPath is the downloaded import path.
+	ImportPath string
+	// Version is the latest version of the module, as the go command resolved it.
+	Version string
+	// Duration is how long the download took.
+	Duration time.Duration
+	// Err is the failure of the download, with the output of the go command.
+	Err error
+}
+
+// SelfTest proves that the go command resolves the import paths of the modules from the rendered sites,
+// before the sites go live: it runs go mod download for the latest version of every import path,
+// with the sites served locally over HTTPS in place of their domains.
+//
+// The sites are the rendered output directories by their domains.
+// The go command reaches them through a local proxy, which tunnels the connections of the domains to a local HTTPS server
+// with a temporary certificate, and the rest, like the repositories, to where they point.
+// The domains are in GOINSECURE, so the go command accepts the temporary certificate,
+// and in GONOSUMDB, since the checksum database can't know the modules of a site that isn't live yet.
+// The downloads go into a scratch module cache, so nothing is served from the cache of the user.
+func SelfTest(ctx context.Context, sites map[string]fs.FS, metas []Meta, opts SelfTestOptions) (_ []SelfTestResult, rErr error) {
+	var importPaths []string
+	for _, meta := range metas {
+		if meta.Private || meta.Gone() {
+			continue
+		}
+		for domain := range sites {
+			if isUnderDomain(meta.Import.Prefix, domain) {
+				importPaths = append(importPaths, pageImportPaths(meta)...)
+				break
+			}
+		}
+	}
+
+	server, err := newSelfTestServer(sites)
+	if err != nil {
+		return nil, err
+	}
+	defer server.Close()
+	scratch, err := os.MkdirTemp("", "vanity-selftest-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, os.RemoveAll(scratch)) }()
+
+	var hosts []string
+	for host := range server.hosts {
+		hosts = append(hosts, host)
+	}
+	proxyURL := "http://" + server.proxy.Addr().String()
+	env := append(os.Environ(),
+		"GOPATH="+filepath.Join(scratch, "gopath"),
+		"GOMODCACHE="+filepath.Join(scratch, "gopath", "pkg", "mod"),
+		// the module cache is read-only by default, which would keep the scratch directory from being removed
+		"GOFLAGS=-modcacherw",
+		"GOPROXY=direct",
+		"GOWORK=off",
+		"GOINSECURE="+strings.Join(hosts, ","),
+		"GONOSUMDB="+strings.Join(hosts, ","),
+		"HTTPS_PROXY="+proxyURL, "https_proxy="+proxyURL,
+		"HTTP_PROXY="+proxyURL, "http_proxy="+proxyURL,
+		"NO_PROXY=", "no_proxy=",
+		"GIT_TERMINAL_PROMPT=0",
+	)
+	env = append(env, opts.Env...)
+
+	var (
+		results = make([]SelfTestResult, len(importPaths))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+		workers = opts.Workers
+	)
+	if workers < 1 {
+		workers = 4
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = goModDownload(ctx, importPaths[i], scratch, env, opts)
+			}
+		}()
+	}
+feed:
+	for i := range importPaths {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// goModDownload downloads the latest version of an import path with the go command.
+func goModDownload(ctx context.Context, importPath, dir string, env []string, opts SelfTestOptions) SelfTestResult {
+	result := SelfTestResult{ImportPath: importPath}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, zerokit.Coalesce(opts.Go, "go"), "mod", "download", "-json", importPath+"@latest")
+	cmd.Dir, cmd.Env, cmd.Stdout, cmd.Stderr = dir, env, &stdout, &stderr
+	// a git subprocess may hold the pipes open after the go command is killed
+	cmd.WaitDelay = time.Second
+	runErr := cmd.Run()
+	result.Duration = time.Since(start)
+
+	var dto struct {
+		Version string
+		Error   string
+	}
+	_ = json.Unmarshal(stdout.Bytes(), &dto)
+	switch {
+	case dto.Error != "":
+		result.Err = fmt.Errorf("%s", dto.Error)
+	case runErr != nil:
+		result.Err = fmt.Errorf("go mod download: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	default:
+		result.Version = dto.Version
+	}
+	return result
+}
+
+// selfTestServer serves the sites over HTTPS, and a proxy that tunnels the connections of their domains to it.
+type selfTestServer struct {
+	hosts map[string]map[string]fs.FS // the sites by their host and base path
+	https net.Listener
+	proxy net.Listener
+	wg    sync.WaitGroup
+}
+
+func newSelfTestServer(sites map[string]fs.FS) (*selfTestServer, error) {
+	s := &selfTestServer{hosts: map[string]map[string]fs.FS{}}
+	for domain, site := range sites {
+		host, basePath := splitDomain(domain)
+		if s.hosts[host] == nil {
+			s.hosts[host] = map[string]fs.FS{}
+		}
+		s.hosts[host][basePath] = site
+	}
+	var names []string
+	for host := range s.hosts {
+		names = append(names, host)
+	}
+	cert, err := selfSignedCertificate(names)
+	if err != nil {
+		return nil, err
+	}
+	httpsListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	proxyListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errorkit.Merge(err, httpsListener.Close())
+	}
+	s.https = tls.NewListener(httpsListener, &tls.Config{Certificates: []tls.Certificate{cert}})
+	s.proxy = proxyListener
+	s.serve(s.https, http.HandlerFunc(s.serveSite))
+	s.serve(s.proxy, http.HandlerFunc(s.serveProxy))
+	return s, nil
+}
+
+func (s *selfTestServer) serve(l net.Listener, handler http.Handler) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = http.Serve(l, handler)
+	}()
+}
+
+func (s *selfTestServer) Close() error {
+	err := errorkit.Merge(s.https.Close(), s.proxy.Close())
+	s.wg.Wait()
+	return err
+}
+
+// serveSite serves the pages of the sites like a static host, with the 404.html page for a missing page.
+func (s *selfTestServer) serveSite(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	for basePath, site := range s.hosts[host] {
+		name, ok := strings.CutPrefix(r.URL.Path, basePath)
+		if !ok || (name != "" && name[0] != '/') {
+			continue
+		}
+		page, found, err := sitePage(site, strings.Trim(name, "/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write(page)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// serveProxy tunnels the connections of the sites' domains to the local HTTPS server, and the rest to their destination.
+func (s *selfTestServer) serveProxy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		// only the repositories over plain HTTP get here, since the sites are reached over HTTPS
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for key, values := range resp.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+		return
+	}
+	target := r.Host
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		if _, ok := s.hosts[host]; ok {
+			target = s.https.Addr().String()
+		}
+	}
+	upstream, err := net.DialTimeout("tcp", target, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		http.Error(w, "the connection can't be tunneled", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return
+	}
+	go func() {
+		defer conn.Close()
+		defer upstream.Close()
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}()
+}
+
+// selfSignedCertificate creates a temporary certificate of the host names.
+func selfSignedCertificate(names []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "generate-go-redirect self-test"},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
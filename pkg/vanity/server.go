@@ -0,0 +1,212 @@
+package vanity
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server answers the go-get requests of the vanity domain dynamically,
+// with the same pages that the static site would serve.
+// It lets a single small server, behind any reverse proxy, replace the static-site host.
+type Server struct {
+	// Domain is the vanity domain, e.g. go.llib.dev
+	Domain string
+	// Metrics is an optional collector of the traffic statistics.
+	Metrics *Metrics
+	// MaxAge is how long the clients, module proxies and CDNs may cache a page.
+	// When zero, they have to revalidate the page with its ETag on every use.
+	MaxAge time.Duration
+	// Auth holds the credentials that grant access to the private modules.
+	// When nil, the private modules are not served to anyone.
+	Auth *Credentials
+	// Proxy is an optional source of the module data.
+	// When set, the server answers the GOPROXY protocol requests of the configured modules too.
+	Proxy ModuleSource
+	// SecurityHeaders are the security headers of the responses,
+	// with the Content-Security-Policy on the pages.
+	SecurityHeaders SecurityHeaders
+	// Theme is the look of the pages, which is set before the server handles requests.
+	//
+	// default: the unstyled pages of the browser
+	Theme *Theme
+	// Language is the language tag of the pages, when the Accept-Language of a request matches none of the Catalogs.
+	//
+	// default: en
+	Language string
+	// Catalogs are the texts of the notices of the pages by language tag,
+	// which the pages are served in by the Accept-Language of the requests.
+	// The texts that a catalog lacks are the English defaults.
+	Catalogs map[string]Catalog
+
+	// base is the template of the pages, which is cloned for every language, and so it is never executed itself.
+	base      *template.Template
+	templates sync.Map // the templates of the pages by language tag
+	modules   atomic.Pointer[moduleTable]
+}
+
+// moduleTable maps the import paths of the modules, and of their nested modules, to the module's meta.
+type moduleTable map[string]Meta
+
+// ReservedPaths are the paths of the operational endpoints of the serve command, like the health checks,
+// which take the requests of a module whose import path falls on them, so such a module can't be served.
+// A path with a trailing slash reserves the paths under it too.
+var ReservedPaths = []string{"/healthz", "/readyz", "/webhook", "/sumdb/"}
+
+// ReservedPath is the reserved path of the ReservedPaths that the import path falls on, if any.
+func ReservedPath(importPath string) (string, bool) {
+	_, p, _ := strings.Cut(importPath, "/")
+	p = "/" + p
+	for _, reserved := range ReservedPaths {
+		if p == reserved || p == strings.TrimSuffix(reserved, "/") ||
+			(strings.HasSuffix(reserved, "/") && strings.HasPrefix(p, reserved)) {
+			return reserved, true
+		}
+	}
+	return "", false
+}
+
+// NewServer creates a Server of the vanity domain.
+// The server is not ready until its modules are set with SetModules.
+func NewServer(domain string) (*Server, error) {
+	tmpl, err := getImportTemplate(templateFuncs(assets{}, nil, nil), nil)
+	if err != nil {
+		return nil, fmt.Errorf("getRedirectTemplate failed: %w", err)
+	}
+	return &Server{Domain: domain, base: tmpl}, nil
+}
+
+// template is the template of the pages in a language.
+func (s *Server) template(lang string) (*template.Template, error) {
+	if tmpl, ok := s.templates.Load(lang); ok {
+		return tmpl.(*template.Template), nil
+	}
+	clone, err := s.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	pageLang := lang
+	if len(s.Catalogs) == 0 {
+		// a single language server keeps the pages as they are, without a lang attribute
+		pageLang = s.Language
+	}
+	funcs := withLocale(template.FuncMap{}, pageLang, s.Catalogs[lang])
+	funcs["theme"] = func() *Theme { return s.Theme }
+	clone.Funcs(funcs)
+	tmpl, _ := s.templates.LoadOrStore(lang, clone)
+	return tmpl.(*template.Template), nil
+}
+
+// SetModules replaces the modules that the server resolves.
+// It is safe to call while the server handles requests.
+func (s *Server) SetModules(metas []Meta) {
+	table := moduleTable{}
+	for _, meta := range metas {
+		table[meta.Import.Prefix] = meta
+		for _, nested := range meta.Nested {
+			table[nested.ImportPath] = meta
+		}
+	}
+	s.modules.Store(&table)
+}
+
+// Ready tells whether the server has its modules set, and so it can answer the go-get requests.
+func (s *Server) Ready() bool {
+	return s.modules.Load() != nil
+}
+
+// Lookup finds the module of an import path.
+// The import path can point to a package within a module, then the closest enclosing module is returned.
+func (s *Server) Lookup(importPath string) (Meta, bool) {
+	table := s.modules.Load()
+	if table == nil {
+		return Meta{}, false
+	}
+	for p := importPath; p != "." && p != "/"; p = path.Dir(p) {
+		if meta, ok := (*table)[p]; ok {
+			return meta, true
+		}
+		if !strings.Contains(p, "/") {
+			break
+		}
+	}
+	return Meta{}, false
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	s.SecurityHeaders.set(w.Header(), nil)
+
+	if s.Proxy != nil && isProxyPath(r.URL.Path) {
+		s.serveProxy(w, r)
+		return
+	}
+
+	start := time.Now()
+	// the request path of a domain with a base path starts with the base path too
+	host, _ := splitDomain(s.Domain)
+	importPath := strings.TrimSuffix(host+path.Clean("/"+r.URL.Path), "/")
+	meta, ok := s.Lookup(importPath)
+	defer func() {
+		label := meta.Import.Prefix
+		if meta.Private {
+			// the metrics would reveal the import paths that the private modules hide from the anonymous visitors
+			label = PrivateModulesLabel
+		}
+		s.Metrics.ObserveRequest(label, r.URL.Query().Get("go-get") == "1", time.Since(start))
+	}()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if meta.Private && !s.authorize(w, r) {
+		return
+	}
+
+	// the page of a deprecated, moved or gone module shows its notice, rather than sending the visitors away,
+	// and so does the page of a delayed redirect, or of none
+	if target := meta.RedirectURL(importPath); target != "" && r.URL.Query().Get("go-get") != "1" && !meta.Deprecated() && meta.Tombstone.Kind == "" && meta.Redirect.Immediate() {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	lang := rootLanguage(s.Language)
+	if 0 < len(s.Catalogs) {
+		lang = NegotiateLanguage(r.Header.Get("Accept-Language"), lang, s.Catalogs)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	tmpl, err := s.template(lang)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newPage(meta, importPath)); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl(s.MaxAge))
+	s.SecurityHeaders.set(w.Header(), buf.Bytes())
+	if meta.Gone() {
+		// unlike a static host, the server can tell the go command that the module is gone for good
+		w.WriteHeader(http.StatusGone)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(buf.Bytes())
+		}
+		return
+	}
+	w.Header().Set("ETag", etag(buf.Bytes()))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
@@ -0,0 +1,134 @@
+package vanity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// SiteCheckError tells that the page of an import path doesn't resolve to the configured repository.
+type SiteCheckError struct {
+	// ImportPath is the import path whose page was checked.
+	ImportPath string
+	// Message tells what is wrong with the page.
+	Message string
+}
+
+func (err SiteCheckError) Error() string {
+	return fmt.Sprintf("%s: %s", err.ImportPath, err.Message)
+}
+
+// CheckLiveSite requests the page of every import path from the live site with ?go-get=1, like the go command does,
+// and checks that the page's go-import meta tag points to the configured repository,
+// which catches a stale deployment, or a host that serves the pages of another site.
+// The pages of the gone modules must not carry a go-import meta tag anymore,
+// and the private modules are skipped, since only the server reveals them to authenticated clients.
+//
+// default client: a client that retries the temporary failures
+func CheckLiveSite(ctx context.Context, client *http.Client, metas []Meta) error {
+	client = zerokit.Coalesce(client, discoveryClient())
+	var errs []error
+	for _, meta := range metas {
+		if meta.Private {
+			continue
+		}
+		for _, importPath := range pageImportPaths(meta) {
+			if err := checkLivePage(ctx, client, meta, importPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errorkit.Merge(errs...)
+}
+
+func checkLivePage(ctx context.Context, client *http.Client, meta Meta, importPath string) error {
+	report := func(format string, args ...any) error {
+		return SiteCheckError{ImportPath: importPath, Message: fmt.Sprintf(format, args...)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+importPath+"?go-get=1", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return report("%v", err)
+	}
+	defer resp.Body.Close()
+	// like the go command, the meta tags are read regardless of the status code,
+	// which only matters when the page has none
+	imports, err := parseGoImports(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return report("failed to parse the page: %v", err)
+	}
+	if meta.Gone() {
+		if im, err := matchGoImport(imports, importPath); err == nil {
+			return report("the module is gone, but the page still carries the go-import meta tag %q", im)
+		}
+		return nil
+	}
+	if len(imports) == 0 && (resp.StatusCode < 200 || 299 < resp.StatusCode) {
+		return report("unexpected status code: %s", resp.Status)
+	}
+	im, err := matchGoImport(imports, importPath)
+	if err != nil {
+		return report("%v", err)
+	}
+	if want := expectedGoImport(meta); im != want {
+		return report("the go-import meta tag is %q, rather than %q", im, want)
+	}
+	return nil
+}
+
+// expectedGoImport is the go-import meta tag that the pages of a module carry.
+func expectedGoImport(meta Meta) goImport {
+	return goImport{Prefix: meta.Import.Prefix, VCS: meta.Import.VCS.Name, RepoRoot: meta.Import.VCS.RepoRoot.String()}
+}
+
+// CheckSite resolves every import path of the modules from the pages of the static site with ResolveImportPath,
+// and checks that they resolve to the configured repositories,
+// so CI can prove that go get succeeds for them, without network access to the domain.
+// The gone modules must not resolve anymore, and the private modules are skipped, since they are left out of the static site.
+// It returns the resolutions of the import paths that resolved.
+func CheckSite(site fs.FS, domain string, metas []Meta) ([]Resolution, error) {
+	var (
+		resolutions []Resolution
+		errs        []error
+	)
+	for _, meta := range metas {
+		if meta.Private || !isUnderDomain(meta.Import.Prefix, domain) {
+			continue
+		}
+		for _, importPath := range pageImportPaths(meta) {
+			report := func(format string, args ...any) {
+				errs = append(errs, SiteCheckError{ImportPath: importPath, Message: fmt.Sprintf(format, args...)})
+			}
+			res, err := ResolveImportPath(site, domain, importPath)
+			switch {
+			case meta.Gone():
+				if err == nil {
+					report("the module is gone, but it still resolves to %s %s", res.VCS, res.RepoRoot)
+				}
+				continue
+			case err != nil:
+				report("%v", err)
+				continue
+			}
+			resolutions = append(resolutions, res)
+			if got, want := (goImport{Prefix: res.Prefix, VCS: res.VCS, RepoRoot: res.RepoRoot}), expectedGoImport(meta); got != want {
+				report("resolves to %q, rather than %q", got, want)
+			}
+		}
+	}
+	return resolutions, errorkit.Merge(errs...)
+}
+
+// isUnderDomain tells whether the import path is the domain, or a path under it.
+func isUnderDomain(importPath, domain string) bool {
+	return importPath == domain || strings.HasPrefix(importPath, domain+"/")
+}
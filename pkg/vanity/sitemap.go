@@ -0,0 +1,105 @@
+package vanity
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/port/filesystem"
+)
+
+// sitemapFileName is the name of the sitemap within the output directory.
+const sitemapFileName = "sitemap.xml"
+
+type sitemapDTO struct {
+	XMLName xml.Name          `xml:"urlset"`
+	XMLNS   string            `xml:"xmlns,attr"`
+	URLs    []sitemapEntryDTO `xml:"url"`
+}
+
+type sitemapEntryDTO struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// renderSitemap renders the sitemap of the rendered pages, so the search engines find every page of the domain.
+// The lastmod of a page is kept from the current sitemap of the output directory while the page is unchanged,
+// so an unchanged site leaves the sitemap unchanged too.
+func renderSitemap(fsys filesystem.FileSystem, domain string, files []File) (File, error) {
+	lastMods, err := readSitemapLastMods(fsys)
+	if err != nil {
+		return File{}, err
+	}
+	now := time.Now().UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	sitemap := sitemapDTO{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, file := range files {
+		if path.Ext(file.Path) != ".html" {
+			continue
+		}
+		loc := pageURL(domain, file.Path)
+		lastMod, ok := lastMods[loc]
+		if old, err := readFile(fsys, file.Path); err != nil || !ok || !bytes.Equal(old, file.Content) {
+			lastMod = now
+		}
+		sitemap.URLs = append(sitemap.URLs, sitemapEntryDTO{Loc: loc, LastMod: lastMod})
+	}
+
+	content, err := xml.MarshalIndent(sitemap, "", "  ")
+	if err != nil {
+		return File{}, err
+	}
+	content = append([]byte(xml.Header), content...)
+	return File{Path: sitemapFileName, Content: append(content, '\n')}, nil
+}
+
+// crawledFiles leaves out the files of the modules with NoCrawl, and the guide pages of the private modules.
+func crawledFiles(files []File, metas []Meta) []File {
+	noCrawl := map[string]struct{}{}
+	for _, meta := range metas {
+		if meta.NoCrawl || meta.Private {
+			noCrawl[meta.Import.Prefix] = struct{}{}
+		}
+	}
+	var crawled []File
+	for _, file := range files {
+		if _, ok := noCrawl[file.Module]; !ok {
+			crawled = append(crawled, file)
+		}
+	}
+	return crawled
+}
+
+// pageURL tells the URL of a rendered page, which is the import path of the module that it belongs to.
+func pageURL(domain, pagePath string) string {
+	dir := strings.TrimSuffix(strings.TrimSuffix(pagePath, "index.html"), "/")
+	if dir == "" {
+		return "https://" + domain + "/"
+	}
+	return "https://" + domain + "/" + dir
+}
+
+// readSitemapLastMods reads the lastmod of the pages from the current sitemap of the output directory.
+func readSitemapLastMods(fsys filesystem.FileSystem) (map[string]string, error) {
+	lastMods := map[string]string{}
+	data, err := readFile(fsys, sitemapFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lastMods, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sitemap sitemapDTO
+	if err := xml.Unmarshal(data, &sitemap); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", sitemapFileName, err)
+	}
+	for _, entry := range sitemap.URLs {
+		lastMods[entry.Loc] = entry.LastMod
+	}
+	return lastMods, nil
+}
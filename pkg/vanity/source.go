@@ -0,0 +1,124 @@
+package vanity
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// The forges are the kinds of code hosts, which share their URL conventions.
+const (
+	ForgeGitHub    = "github"
+	ForgeGitLab    = "gitlab"
+	ForgeBitbucket = "bitbucket"
+	ForgeSourcehut = "sourcehut"
+	// ForgeGitea is a Gitea compatible host, like a Forgejo instance or Codeberg.
+	ForgeGitea = "gitea"
+	// ForgeAzure is Azure DevOps, where the files are addressed by query parameters rather than by the URL path.
+	ForgeAzure = "azure"
+)
+
+// detectForge tells the forge of the well-known hosts.
+// A self-hosted forge is only recognised by its name when it is a GitLab, otherwise it has to be declared.
+func detectForge(host string) string {
+	switch {
+	case strings.Contains(host, "github.com"):
+		return ForgeGitHub
+	case isGitLabHost(host):
+		return ForgeGitLab
+	case host == "bitbucket.org":
+		return ForgeBitbucket
+	case host == "git.sr.ht":
+		return ForgeSourcehut
+	case host == "codeberg.org":
+		return ForgeGitea
+	case host == "dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com"):
+		return ForgeAzure
+	default:
+		return ""
+	}
+}
+
+// defaultSource fills the unset go-source values with the URL conventions of the repository's host.
+func defaultSource(imp MetaImport, src MetaSource) MetaSource {
+	repo := imp.VCS.SourceRepo()
+	if src.HomepageURL == "" {
+		src.HomepageURL = repo.RepoRoot.String()
+	}
+
+	var (
+		branch = zerokit.Coalesce(src.Branch, defaultBranch(repo.Name))
+		// the web pages of a repository are not under its .git clone URL
+		web = strings.TrimSuffix(repo.RepoRoot.String(), ".git")
+	)
+	forge := zerokit.Coalesce(src.Forge, detectForge(repo.RepoRoot.Host))
+	if !isForgeVCS(repo.Name) {
+		// a Subversion, Bazaar or Fossil repository has its own layout, even on a forge's host,
+		// and a module proxy without an origin has no browsable source at all
+		forge = ""
+	}
+	switch forge {
+	case ForgeGitHub:
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s/tree/%s{/dir}", repo.RepoRoot.String(), branch)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s/{file}#L{line}", src.DirectoryPattern)
+		}
+
+	case ForgeGitLab:
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s/-/tree/%s{/dir}", web, branch)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s/-/blob/%s{/dir}/{file}#L{line}", web, branch)
+		}
+
+	case ForgeBitbucket:
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s/src/%s{/dir}", web, branch)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s/src/%s{/dir}/{file}#lines-{line}", web, branch)
+		}
+
+	case ForgeSourcehut:
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s/tree/%s/item{/dir}", web, branch)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s/tree/%s/item{/dir}/{file}#L{line}", web, branch)
+		}
+
+	case ForgeGitea:
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s/src/branch/%s{/dir}", web, branch)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s/src/branch/%s{/dir}/{file}#L{line}", web, branch)
+		}
+
+	case ForgeAzure:
+		version := url.QueryEscape("GB" + branch)
+		if zerokit.IsZero(src.DirectoryPattern) {
+			src.DirectoryPattern = fmt.Sprintf("%s?path={/dir}&version=%s", web, version)
+		}
+		if zerokit.IsZero(src.FilePattern) {
+			src.FilePattern = fmt.Sprintf("%s?path={/dir}/{file}&version=%s&line={line}&lineEnd={line}&lineStartColumn=1&lineEndColumn=1", web, version)
+		}
+	}
+
+	return src
+}
+
+// isGitLabHost tells whether the host is gitlab.com, or a self-hosted GitLab by its name, like gitlab.example.com
+func isGitLabHost(host string) bool {
+	for _, label := range strings.Split(strings.ToLower(host), ".") {
+		if label == "gitlab" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package vanity
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SSHBucket is the document root of a plain web server, like the one of a VPS,
+// which is accessed with the ssh command, so the user's ssh config, keys and known hosts apply.
+// The files are synced like with rsync: the changed ones are written, and the deleted ones are removed.
+// The web server tells the Content-Type and the Cache-Control of the files, so the bucket ignores them.
+type SSHBucket struct {
+	// Host is the destination of the ssh command, like deploy@example.com, or a Host of the ssh config.
+	Host string
+	// Port is the SSH port of the server.
+	//
+	// default: the port of the ssh config
+	Port int
+	// Dir is the directory of the site on the server, like /var/www/go.llib.dev.
+	// A relative directory is relative to the home directory of the user.
+	Dir string
+}
+
+// List lists the files under the directory, with their md5sum.
+// A directory that doesn't exist yet is empty.
+func (b SSHBucket) List(ctx context.Context) (map[string]string, error) {
+	// md5 -r is the md5sum of the BSDs
+	out, err := b.run(ctx, nil, "cd "+shellQuote(b.dir())+" 2>/dev/null || exit 0\n"+
+		"if command -v md5sum >/dev/null; then find . -type f -exec md5sum {} +; else find . -type f -exec md5 -r {} +; fi")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		hash, name, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		// md5sum separates the name with two spaces, md5 -r with one
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "./")
+		if escaped, ok := strings.CutPrefix(hash, `\`); ok {
+			// md5sum marks a name with a backslash or a line break in it with a leading backslash, and escapes them in the name
+			hash, name = escaped, md5sumUnescaper.Replace(name)
+		}
+		files[name] = hash
+	}
+	return files, scanner.Err()
+}
+
+// md5sumUnescaper reverts the escapes of the names that md5sum marks with a leading backslash.
+var md5sumUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+// Put writes the file into a temporary file next to it first, and renames it,
+// so the web server never serves a partially written file.
+func (b SSHBucket) Put(ctx context.Context, obj Object) error {
+	name := path.Join(b.dir(), obj.Key)
+	_, err := b.run(ctx, obj.Content, fmt.Sprintf("mkdir -p %s && cat > %s && mv -f %[2]s %[3]s",
+		shellQuote(path.Dir(name)), shellQuote(name+".tmp"), shellQuote(name)))
+	return err
+}
+
+// Delete removes the file, and the directories that it leaves empty.
+func (b SSHBucket) Delete(ctx context.Context, key string) error {
+	script := "cd " + shellQuote(b.dir()) + " && rm -f " + shellQuote(key)
+	if dir := path.Dir(key); dir != "." {
+		// rmdir -p stops at the first directory that is not empty, and doesn't go above the site's directory
+		script += " && { rmdir -p " + shellQuote(dir) + " 2>/dev/null; true; }"
+	}
+	_, err := b.run(ctx, nil, script)
+	return err
+}
+
+func (b SSHBucket) dir() string {
+	return path.Clean(b.Dir)
+}
+
+// run runs a shell script on the server, with the content on its standard input.
+func (b SSHBucket) run(ctx context.Context, stdin []byte, script string) ([]byte, error) {
+	// BatchMode fails the command rather than asking for a password or a host key confirmation
+	args := []string{"-o", "BatchMode=yes"}
+	if b.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(b.Port))
+	}
+	args = append(args, "--", b.Host, script)
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w: %s", b.Host, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// shellQuote quotes a string as a single argument of a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,84 @@
+package vanity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// SumDBProxy forwards the checksum database requests of the go command to the upstream checksum database,
+// so the clients that can only reach the vanity server can verify their modules too.
+//
+// The go command only uses it when the module proxy tells that it supports the checksum database:
+//
+//	GET <proxy>/sumdb/<sumdb-name>/supported
+type SumDBProxy struct {
+	// URL is the address of the upstream checksum database.
+	// Its host is the name of the database, e.g. sum.golang.org
+	URL *url.URL
+	// Transport is the transport of the upstream requests.
+	//
+	// default: http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// NewSumDBProxy creates a SumDBProxy for the checksum database at the raw URL.
+func NewSumDBProxy(rawURL string) (*SumDBProxy, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("checksum database URL must be absolute: %q", rawURL)
+	}
+	return &SumDBProxy{URL: u}, nil
+}
+
+// sumdbEndpoints are the paths of the checksum database protocol,
+// and nothing else is forwarded, so the server doesn't become an open proxy.
+var sumdbEndpoints = []string{"/latest", "/lookup/", "/tile/"}
+
+func (p *SumDBProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	endpoint, ok := strings.CutPrefix(r.URL.Path, "/sumdb/"+p.URL.Host)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if endpoint == "/supported" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !isSumDBEndpoint(endpoint) {
+		http.NotFound(w, r)
+		return
+	}
+	upstream := *p.URL
+	upstream.Path = strings.TrimSuffix(upstream.Path, "/") + endpoint
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL = &upstream
+			req.Host = upstream.Host
+			// the credentials of the private modules are not for the checksum database
+			req.Header.Del("Authorization")
+			req.Header.Del("Cookie")
+		},
+		Transport: p.Transport,
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+func isSumDBEndpoint(endpoint string) bool {
+	for _, e := range sumdbEndpoints {
+		if endpoint == e || (strings.HasSuffix(e, "/") && strings.HasPrefix(endpoint, e)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+//go:build linux
+
+package vanity
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// swapDirs exchanges two directories atomically.
+func swapDirs(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+		// the filesystem doesn't support the exchange
+		return renameSwap(a, b)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package vanity
+
+// swapDirs exchanges two directories.
+func swapDirs(a, b string) error {
+	return renameSwap(a, b)
+}
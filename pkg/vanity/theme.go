@@ -0,0 +1,190 @@
+package vanity
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/enum"
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+//go:embed theme.html
+var themeHTML string
+
+// The color schemes of a theme.
+const (
+	ThemeSchemeLight = "light"
+	ThemeSchemeDark  = "dark"
+	// ThemeSchemeAuto follows the preference of the visitor's system.
+	ThemeSchemeAuto = "auto"
+)
+
+// Theme is the look of the pages, which the default templates are styled with,
+// and the custom templates get with the theme function, like {{ with theme }}{{ .Logo }}{{ end }}.
+type Theme struct {
+	// Base is the built-in theme that the empty fields of the theme are taken from.
+	//
+	// default: default
+	Base string `json:"base,omitempty"`
+	// Scheme is the color scheme of the pages: light, dark, or auto, which follows the preference of the visitor.
+	//
+	// default: auto
+	Scheme string `json:"scheme,omitempty" enum:"light,dark,auto,"`
+	// Light are the colors of the light scheme.
+	Light ThemeColors `json:"light,omitempty"`
+	// Dark are the colors of the dark scheme.
+	Dark ThemeColors `json:"dark,omitempty"`
+	// Logo is the URL of the logo at the top of the pages.
+	Logo string `json:"logo,omitempty"`
+	// Home is where the logo links to.
+	//
+	// default: /
+	Home string `json:"home,omitempty"`
+	// FooterLinks are the links at the bottom of the pages, like the homepage of the organization.
+	FooterLinks []ThemeLink `json:"footer-links,omitempty"`
+}
+
+// ThemeColors are the CSS colors of a color scheme, like #1a1a1a or rgb(26, 26, 26).
+type ThemeColors struct {
+	Background string `json:"background,omitempty"`
+	Text       string `json:"text,omitempty"`
+	Link       string `json:"link,omitempty"`
+	// Accent is the background of the code, and the color of the borders.
+	Accent string `json:"accent,omitempty"`
+}
+
+// ThemeLink is a link of the footer.
+type ThemeLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// BuiltinThemes are the themes that ship with the generator, by their names.
+var BuiltinThemes = map[string]Theme{
+	"default": {
+		Scheme: ThemeSchemeAuto,
+		Light:  ThemeColors{Background: "#ffffff", Text: "#1f2328", Link: "#0969da", Accent: "#eff1f3"},
+		Dark:   ThemeColors{Background: "#0d1117", Text: "#e6edf3", Link: "#4493f8", Accent: "#1f242c"},
+	},
+	"gopher": {
+		Scheme: ThemeSchemeAuto,
+		Light:  ThemeColors{Background: "#ffffff", Text: "#202224", Link: "#007d9c", Accent: "#e0ebf5"},
+		Dark:   ThemeColors{Background: "#1b1f23", Text: "#dfe3e8", Link: "#00add8", Accent: "#253443"},
+	},
+	"paper": {
+		Scheme: ThemeSchemeLight,
+		Light:  ThemeColors{Background: "#fbf8f1", Text: "#2e2a24", Link: "#8a4b08", Accent: "#efe8d8"},
+		Dark:   ThemeColors{Background: "#2e2a24", Text: "#fbf8f1", Link: "#e3a857", Accent: "#3d372f"},
+	},
+	"midnight": {
+		Scheme: ThemeSchemeDark,
+		Light:  ThemeColors{Background: "#f5f7ff", Text: "#161b33", Link: "#3d5afe", Accent: "#e3e8fc"},
+		Dark:   ThemeColors{Background: "#0b1026", Text: "#d8def5", Link: "#8c9eff", Accent: "#1a2147"},
+	},
+}
+
+// LoadTheme loads a built-in theme by its name, or a theme file, which is a JSON object of the Theme,
+// whose empty fields are taken from its base theme.
+func LoadTheme(nameOrPath string) (*Theme, error) {
+	if theme, ok := BuiltinThemes[nameOrPath]; ok {
+		return &theme, nil
+	}
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a built-in theme (%s) nor a theme file: %w",
+			nameOrPath, strings.Join(builtinThemeNames(), ", "), err)
+	}
+	var theme Theme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return nil, fmt.Errorf("failed to decode the theme file: %w", err)
+	}
+	base, ok := BuiltinThemes[zerokit.Coalesce(theme.Base, "default")]
+	if !ok {
+		return nil, fmt.Errorf("the base of the theme is not a built-in theme: %s", theme.Base)
+	}
+	theme.Scheme = zerokit.Coalesce(theme.Scheme, base.Scheme)
+	theme.Light = theme.Light.withDefaults(base.Light)
+	theme.Dark = theme.Dark.withDefaults(base.Dark)
+	if err := theme.validate(); err != nil {
+		return nil, fmt.Errorf("invalid theme file: %w", err)
+	}
+	return &theme, nil
+}
+
+func builtinThemeNames() []string {
+	names := make([]string, 0, len(BuiltinThemes))
+	for name := range BuiltinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c ThemeColors) withDefaults(base ThemeColors) ThemeColors {
+	return ThemeColors{
+		Background: zerokit.Coalesce(c.Background, base.Background),
+		Text:       zerokit.Coalesce(c.Text, base.Text),
+		Link:       zerokit.Coalesce(c.Link, base.Link),
+		Accent:     zerokit.Coalesce(c.Accent, base.Accent),
+	}
+}
+
+// cssColorPattern matches the CSS colors that a theme can have, so a color can't break out of the stylesheet.
+var cssColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|rgba|hsl|hsla)\([0-9.,%/ ]+\))$`)
+
+func (t Theme) validate() error {
+	if err := enum.ValidateStruct(t); err != nil {
+		return fmt.Errorf("the scheme is light, dark or auto: %w", err)
+	}
+	for _, color := range []string{
+		t.Light.Background, t.Light.Text, t.Light.Link, t.Light.Accent,
+		t.Dark.Background, t.Dark.Text, t.Dark.Link, t.Dark.Accent,
+	} {
+		if !cssColorPattern.MatchString(color) {
+			return fmt.Errorf("%q is not a CSS color", color)
+		}
+	}
+	for _, link := range t.FooterLinks {
+		if link.Text == "" {
+			return fmt.Errorf("a footer link has no text")
+		}
+		if _, err := url.Parse(link.URL); err != nil || link.URL == "" {
+			return fmt.Errorf("the %s footer link has no valid URL", link.Text)
+		}
+	}
+	return nil
+}
+
+// HomeURL is where the logo links to.
+func (t Theme) HomeURL() string {
+	return zerokit.Coalesce(t.Home, "/")
+}
+
+// Stylesheet is the CSS of the theme, which styles the pages of the default templates.
+func (t Theme) Stylesheet() template.CSS {
+	var b strings.Builder
+	b.WriteString("body{max-width:48rem;margin:2rem auto;padding:0 1rem;font-family:system-ui,sans-serif;line-height:1.5}")
+	b.WriteString("pre{padding:.75rem;overflow-x:auto}table{border-collapse:collapse}th,td{padding:.25rem .5rem;text-align:left}")
+	b.WriteString("header img{height:2rem}footer{margin-top:2rem;padding-top:.5rem}footer a{margin-right:1rem}")
+	switch t.Scheme {
+	case ThemeSchemeLight:
+		b.WriteString(":root{color-scheme:light}" + t.Light.css())
+	case ThemeSchemeDark:
+		b.WriteString(":root{color-scheme:dark}" + t.Dark.css())
+	default:
+		b.WriteString(":root{color-scheme:light dark}" + t.Light.css() + "@media (prefers-color-scheme:dark){" + t.Dark.css() + "}")
+	}
+	return template.CSS(b.String())
+}
+
+func (c ThemeColors) css() string {
+	return fmt.Sprintf("body{background:%s;color:%s}a{color:%s}pre,code{background:%s}th,td{border-bottom:1px solid %s}footer{border-top:1px solid %s}",
+		c.Background, c.Text, c.Link, c.Accent, c.Accent, c.Accent)
+}
@@ -0,0 +1,147 @@
+package vanity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+)
+
+// TLSOptions configure the TLS check of a domain.
+type TLSOptions struct {
+	// ExpiryWarning is how long before its expiry a certificate gets a warning,
+	// which leaves time to notice a failing renewal.
+	//
+	// default: 21 days
+	ExpiryWarning time.Duration
+	// Timeout is how long the handshake with the domain may take.
+	//
+	// default: 30s
+	Timeout time.Duration
+	// RootCAs are the certificate authorities that the certificate chain has to lead to.
+	//
+	// default: the certificate authorities of the system, like the go command uses
+	RootCAs *x509.CertPool
+	// Dial connects to an address of the domain, like "go.llib.dev:443".
+	//
+	// default: a net.Dialer
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// TLSReport is the outcome of checking the certificate of a domain.
+type TLSReport struct {
+	// Host is the host of the checked domain.
+	Host string
+	// Subject and Issuer are the common names of the leaf certificate and its issuer.
+	Subject string
+	Issuer  string
+	// NotAfter is when the leaf certificate expires.
+	NotAfter time.Time
+	// DNSNames are the subject alternative names of the leaf certificate.
+	DNSNames []string
+	// Warnings tell what will break soon, like an expiry within the ExpiryWarning.
+	Warnings []string
+	// Err is the failure of the connection or of the certificate verification, which makes go get fail.
+	Err error
+}
+
+// CheckTLS connects to https://domain, and verifies the certificate chain of the host,
+// with the subject alternative names of the leaf certificate, the way the go command does,
+// since go get fails hard on a TLS error, rather than falling back to another scheme.
+// The certificate is inspected even when it doesn't verify, so the report tells what is wrong with it.
+func CheckTLS(ctx context.Context, domain string, opts TLSOptions) TLSReport {
+	var (
+		host, _ = splitDomain(domain)
+		report  = TLSReport{Host: host}
+		timeout = opts.Timeout
+		warning = opts.ExpiryWarning
+		dial    = opts.Dial
+	)
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if warning <= 0 {
+		warning = 21 * 24 * time.Hour
+	}
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		addr = net.JoinHostPort(host, "443")
+	}
+	raw, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	// the verification is done below, so a broken certificate can still be reported
+	conn := tls.Client(raw, &tls.Config{ServerName: hostname(host), InsecureSkipVerify: true})
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		report.Err = fmt.Errorf("TLS handshake with %s: %w", addr, err)
+		return report
+	}
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		report.Err = fmt.Errorf("%s presented no certificate", addr)
+		return report
+	}
+	leaf := certs[0]
+	report.Subject = leaf.Subject.CommonName
+	report.Issuer = leaf.Issuer.CommonName
+	report.NotAfter = leaf.NotAfter
+	report.DNSNames = leaf.DNSNames
+
+	if err := leaf.VerifyHostname(hostname(host)); err != nil {
+		if len(leaf.DNSNames) == 0 {
+			report.Err = fmt.Errorf("the certificate has no subject alternative names, so it doesn't cover %s", hostname(host))
+		} else {
+			report.Err = fmt.Errorf("the certificate doesn't cover %s, only %s", hostname(host), strings.Join(leaf.DNSNames, ", "))
+		}
+		return report
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	now := time.Now()
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       hostname(host),
+		Roots:         opts.RootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}); err != nil {
+		report.Err = err
+		return report
+	}
+	for i, cert := range certs {
+		left := cert.NotAfter.Sub(now)
+		if warning <= left {
+			continue
+		}
+		name := "the certificate"
+		if 0 < i {
+			name = fmt.Sprintf("the %s intermediate certificate", zerokit.Coalesce(cert.Subject.CommonName, cert.Subject.String()))
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s expires in %d days, on %s",
+			name, int(left.Hours()/24), cert.NotAfter.Format(time.DateOnly)))
+	}
+	return report
+}
+
+// hostname is the host without its port.
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
@@ -0,0 +1,230 @@
+package vanity
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/enum"
+	"golang.org/x/mod/module"
+)
+
+// ValidationError describes a single problem with an entry of the imports file.
+type ValidationError struct {
+	// Index is the position of the entry in the imports file.
+	Index int
+	// Prefix is the import prefix of the entry, if it has any.
+	Prefix string
+	// Field is the name of the offending field, as it is written in the imports file.
+	Field string
+	// Message tells what is wrong with the field.
+	Message string
+}
+
+func (err ValidationError) Error() string {
+	entry := fmt.Sprintf("entry #%d", err.Index)
+	if err.Prefix != "" {
+		entry = fmt.Sprintf("%s (%s)", entry, err.Prefix)
+	}
+	return fmt.Sprintf("%s: %s: %s", entry, err.Field, err.Message)
+}
+
+// ValidationErrors are the violations of the entries of an imports file, one per line.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs ValidationErrors) Unwrap() []error {
+	unwrapped := make([]error, 0, len(errs))
+	for _, err := range errs {
+		unwrapped = append(unwrapped, err)
+	}
+	return unwrapped
+}
+
+// ValidateImports checks every entry of the imports file,
+// and reports all the violations at once as ValidationErrors, rather than stopping at the first one.
+// The import prefix of an entry has to be under one of the vanity domains.
+func ValidateImports(dtos []ImportDTO, domains ...string) error {
+	if errs := validateImports(dtos, domains); 0 < len(errs) {
+		return ValidationErrors(errs)
+	}
+	return nil
+}
+
+func validateImports(dtos []ImportDTO, domains []string) []ValidationError {
+	var errs []ValidationError
+	for i, dto := range dtos {
+		report := func(field, format string, args ...any) {
+			errs = append(errs, ValidationError{
+				Index:   i,
+				Prefix:  dto.ImportPrefix,
+				Field:   field,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		// a gone module has no repository to point to
+		gone := dto.Type == TombstoneGone
+
+		switch {
+		case dto.VCS == "" && gone:
+		case dto.VCS == "":
+			report("vcs", "is required")
+		case enum.ValidateStruct(MetaImportVCS{Name: dto.VCS}) != nil:
+			report("vcs", "%q is not a supported version control system", dto.VCS)
+		}
+
+		domain, ok := domainOf(dto.ImportPrefix, domains)
+		switch {
+		case dto.ImportPrefix == "":
+			report("import-prefix", "is required")
+		case !ok:
+			report("import-prefix", "must be under the %s domain", strings.Join(domains, " or the "))
+		}
+
+		if dto.RootRepo == "" {
+			if !gone {
+				report("root-repo", "is required")
+			}
+		} else if u, err := url.Parse(dto.RootRepo); err != nil {
+			report("root-repo", "invalid URL: %s", err.Error())
+		} else if !u.IsAbs() || u.Host == "" {
+			report("root-repo", "must be an absolute URL, e.g. https://github.com/org/repo")
+		}
+
+		for _, major := range dto.Majors {
+			if !isMajorPath(strings.Trim(major, "/")) {
+				report("majors", "%q is not a major version path, like v2 or adapter/kafka/v2", major)
+			}
+		}
+
+		switch {
+		case dto.Type != "" && enum.ValidateStruct(MetaTombstone{Kind: dto.Type}) != nil:
+			report("type", "%q is not an entry type, use moved or gone", dto.Type)
+		case dto.Type == TombstoneMoved && dto.MovedTo == "":
+			report("moved-to", "is required for a moved module")
+		case dto.MovedTo != "" && dto.Type != TombstoneMoved:
+			report("moved-to", "only applies to moved modules, set the type to moved too")
+		case dto.MovedTo != "" && module.CheckPath(dto.MovedTo) != nil:
+			report("moved-to", "%q is not a valid module path", dto.MovedTo)
+		}
+
+		switch {
+		case dto.Replacement == "":
+		case dto.Deprecated == "":
+			report("replacement", "only applies to deprecated modules, set the deprecated message too")
+		case module.CheckPath(dto.Replacement) != nil:
+			report("replacement", "%q is not a valid module path", dto.Replacement)
+		}
+
+		switch {
+		case dto.Origin != "" && dto.VCS != VCSMod:
+			report("origin", "only applies to mod entries")
+		case dto.Origin != "":
+			if u, err := url.Parse(dto.Origin); err != nil || !u.IsAbs() || u.Host == "" {
+				report("origin", "must be an absolute URL, e.g. https://github.com/org/repo")
+			}
+		case dto.VCS == VCSMod && isDomainURL(dto.RootRepo, domain):
+			report("origin", "is required, since the module proxy of %s builds the module from it", domain)
+		}
+
+		for _, sub := range dto.Submodules {
+			if strings.Trim(sub, "/") == "" {
+				report("submodules", "empty submodule path")
+			}
+		}
+
+		for _, target := range dto.Redirect {
+			if !isRedirectTarget(target) {
+				report("redirect", "%q is not a redirect target, use one of %s", target, strings.Join(redirectTargets, ", "))
+			}
+		}
+
+		switch {
+		case dto.RedirectMode != "" && enum.ValidateStruct(MetaRedirect{Mode: dto.RedirectMode}) != nil:
+			report("redirect-mode", "%q is not a redirect mode, use one of script, refresh, delayed, none", dto.RedirectMode)
+		case dto.RedirectDelay < 0:
+			report("redirect-delay", "must be a number of seconds")
+		case dto.RedirectDelay != 0 && dto.RedirectMode != RedirectModeDelayed:
+			report("redirect-delay", "only applies to the delayed redirect, set the redirect-mode to delayed too")
+		}
+
+		switch {
+		case dto.Forge == "":
+		case enum.ValidateStruct(MetaSource{Forge: dto.Forge}) != nil:
+			report("forge", "%q is not a supported forge, use one of github, gitlab, bitbucket, sourcehut, gitea, azure", dto.Forge)
+		case dto.VCS != "" && !isForgeVCS(dto.VCS) && !(dto.VCS == VCSMod && dto.Origin != ""):
+			report("forge", "only applies to git and hg repositories, set the directory-pattern and file-pattern of the %s repository instead", dto.VCS)
+		}
+
+		if dto.Template != "" && dto.Template != TemplateLanding && (path.Ext(dto.Template) != ".html" || path.Base(dto.Template) != dto.Template) {
+			report("template", "%q is not a template, use landing, or the name of an .html file in the templates directory", dto.Template)
+		}
+
+		if dto.Image != "" {
+			if u, err := url.Parse(dto.Image); err != nil || !u.IsAbs() || u.Host == "" {
+				report("image", "must be an absolute URL, since the link previews are fetched from elsewhere")
+			}
+		}
+
+		seen := map[string]struct{}{}
+		for _, tag := range dto.Tags {
+			if _, ok := seen[tag]; ok {
+				report("tags", "%q is listed twice", tag)
+			}
+			seen[tag] = struct{}{}
+			if strings.TrimSpace(tag) == "" {
+				report("tags", "empty tag")
+			}
+		}
+
+		if dto.DocsURL != "" {
+			if u, err := url.Parse(dto.DocsURL); err != nil || !u.IsAbs() {
+				report("docs-url", "must be an absolute URL")
+			}
+		}
+	}
+	return errs
+}
+
+// domainOf tells which of the vanity domains the import path is under.
+// A wildcard domain, like *.llib.dev, covers the import paths on a subdomain of it, like foo.llib.dev/bar,
+// and then the subdomain is returned.
+// When it is under none of them, the first domain is returned.
+func domainOf(importPath string, domains []string) (string, bool) {
+	for _, domain := range domains {
+		if parent, ok := strings.CutPrefix(domain, wildcardPrefix); ok {
+			// like a wildcard TLS certificate, it covers a single level of subdomains
+			host, _, _ := strings.Cut(importPath, "/")
+			if label, ok := strings.CutSuffix(host, "."+parent); ok && label != "" && !strings.Contains(label, ".") {
+				return host, true
+			}
+			continue
+		}
+		if importPath == domain || strings.HasPrefix(importPath, domain+"/") {
+			return domain, true
+		}
+	}
+	if len(domains) == 0 {
+		return "", false
+	}
+	return domains[0], false
+}
+
+// isDomainURL tells whether the URL points to the vanity domain itself, like the built-in module proxy of the server.
+func isDomainURL(rawURL, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host, _, _ := strings.Cut(domain, "/")
+	return u.Host == host
+}
@@ -0,0 +1,127 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+)
+
+// The version control systems of the repositories, as they are named in the go-import meta.
+const (
+	VCSGit = "git"
+	// VCSMercurial is Mercurial, which is named after its hg command.
+	VCSMercurial  = "hg"
+	VCSSubversion = "svn"
+	// VCSBazaar is Bazaar, or its Breezy fork, which are named after the bzr command.
+	VCSBazaar = "bzr"
+	VCSFossil = "fossil"
+	// VCSMod is a module proxy, which serves the modules whose repositories can't be fetched directly.
+	VCSMod = "mod"
+)
+
+// isForgeVCS tells whether the version control system is hosted by the forges,
+// whose URL layouts the default go-source patterns assume.
+// The repositories of the other systems only get go-source patterns when they are configured.
+func isForgeVCS(vcs string) bool {
+	return vcs == VCSGit || vcs == VCSMercurial
+}
+
+// isClonable tells whether the repositories of the version control system can be cloned for a look into their files.
+func isClonable(vcs string) bool {
+	return vcs == VCSGit || vcs == VCSMercurial
+}
+
+// defaultBranch is the branch that a new repository of the version control system starts with.
+func defaultBranch(vcs string) string {
+	if vcs == VCSMercurial {
+		return "default"
+	}
+	return "master"
+}
+
+// repoClone is a temporary clone of a repository, which reads the files of its default branch.
+type repoClone struct {
+	vcs string
+	dir string
+}
+
+// cloneRepo clones the repository into a temporary directory,
+// which also tells whether the repository can be reached at all.
+// The clone has to be closed, to remove it from the disk.
+func cloneRepo(ctx context.Context, vcs, repoURL string) (repoClone, error) {
+	switch vcs {
+	case VCSGit:
+		dir, err := shallowClone(ctx, repoURL)
+		if err != nil {
+			return repoClone{}, err
+		}
+		return repoClone{vcs: vcs, dir: dir}, nil
+	case VCSMercurial:
+		dir, err := os.MkdirTemp("", "clone-*.hg")
+		if err != nil {
+			return repoClone{}, err
+		}
+		// Mercurial has no shallow clones, but the working directory is left out at least
+		if _, err := hg(ctx, "", "clone", "--noupdate", repoURL, dir); err != nil {
+			return repoClone{}, errorkit.Merge(err, os.RemoveAll(dir))
+		}
+		return repoClone{vcs: vcs, dir: dir}, nil
+	default:
+		return repoClone{}, fmt.Errorf("%s repositories are not supported", vcs)
+	}
+}
+
+// ReadFile reads a file of the default branch, by its slash separated path from the repository root.
+func (c repoClone) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	if c.vcs == VCSMercurial {
+		return hg(ctx, c.dir, "cat", "--rev", defaultBranch(c.vcs), "path:"+name)
+	}
+	return git(ctx, c.dir, "show", "HEAD:"+name)
+}
+
+// Files lists every file of the default branch.
+func (c repoClone) Files(ctx context.Context) ([]string, error) {
+	var (
+		out []byte
+		err error
+	)
+	if c.vcs == VCSMercurial {
+		out, err = hg(ctx, c.dir, "files", "--rev", defaultBranch(c.vcs))
+	} else {
+		out, err = git(ctx, c.dir, "ls-tree", "-r", "--name-only", "HEAD")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(string(out), "\n") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+func (c repoClone) Close() error {
+	return os.RemoveAll(c.dir)
+}
+
+func hg(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	// HGPLAIN keeps the user's configuration from changing the output
+	cmd := exec.CommandContext(ctx, "hg", append([]string{"--noninteractive"}, args...)...)
+	cmd.Env = append(os.Environ(), "HGPLAIN=1")
+	// the listed file paths are relative to the working directory
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("hg %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package vanity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.llib.dev/frameless/pkg/errorkit"
+	"go.llib.dev/frameless/pkg/logger"
+	"go.llib.dev/frameless/pkg/logging"
+	"go.llib.dev/frameless/pkg/zerokit"
+	"golang.org/x/mod/semver"
+)
+
+// versionsDir is the directory of the version listing within the directory of a module's page.
+const versionsDir = "versions"
+
+// MetaVersion is a tagged version of a module.
+type MetaVersion struct {
+	Version string
+	// Time is when the version was tagged, which is the date of an annotated tag, or of the commit of a lightweight tag.
+	Time time.Time
+	// ReleaseNotesURL is the release page of the version's tag on the forge of the repository.
+	// It is empty when the forge has no release pages.
+	ReleaseNotesURL string
+}
+
+// Date is the day of the version's publishing, like 2024-05-17.
+func (v MetaVersion) Date() string {
+	if v.Time.IsZero() {
+		return ""
+	}
+	return v.Time.UTC().Format(time.DateOnly)
+}
+
+// DetectVersions sets the versions of the modules, their nested modules and their major versions,
+// from the semantic version tags of their git repositories, with the time of their tagging.
+// Only the tags are fetched, without the history and the files of the repository.
+// A repository that can't be reached is only warned about, and its modules are left without versions.
+func DetectVersions(ctx context.Context, metas []Meta) []Meta {
+	for i, meta := range metas {
+		repo := meta.Import.VCS.SourceRepo()
+		if repo.Name != VCSGit || meta.Private || meta.Tombstone.Kind != "" {
+			continue
+		}
+		tags, err := tagTimes(ctx, repo.RepoRoot.String())
+		if err != nil {
+			logger.Warn(ctx, "version detection failed",
+				logging.Field("module", meta.Import.Prefix),
+				logging.ErrField(err))
+			continue
+		}
+		mods := []ProxyModule{{Path: meta.Import.Prefix, RepoRoot: repo.RepoRoot.String()}}
+		for _, nested := range meta.Nested {
+			mods = append(mods, ProxyModule{Path: nested.ImportPath, RepoRoot: repo.RepoRoot.String(), Dir: nested.Path})
+		}
+		for _, major := range meta.Majors {
+			mods = append(mods, ProxyModule{Path: major, RepoRoot: repo.RepoRoot.String()})
+		}
+		versions := map[string][]MetaVersion{}
+		for _, mod := range mods {
+			for tag, t := range tags {
+				version, ok := strings.CutPrefix(tag, tagPrefix(mod))
+				if !ok || !isModuleVersion(mod, version) {
+					continue
+				}
+				versions[mod.Path] = append(versions[mod.Path], MetaVersion{
+					Version:         version,
+					Time:            t,
+					ReleaseNotesURL: releaseNotesURL(meta, tag),
+				})
+			}
+			// the newest version first
+			sort.Slice(versions[mod.Path], func(i, j int) bool {
+				return semver.Compare(versions[mod.Path][i].Version, versions[mod.Path][j].Version) > 0
+			})
+		}
+		metas[i].Versions = versions
+	}
+	return metas
+}
+
+// tagTimes fetches the tags of the git repository into an empty repository,
+// with only the tagged commits and without their files, and tells the time of each tag by its name.
+func tagTimes(ctx context.Context, repoURL string) (_ map[string]time.Time, rErr error) {
+	dir, err := os.MkdirTemp("", "tags-*.git")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { rErr = errorkit.Merge(rErr, os.RemoveAll(dir)) }()
+
+	if _, err := git(ctx, "", "init", "--quiet", "--bare", dir); err != nil {
+		return nil, err
+	}
+	if _, err := git(ctx, dir, "fetch", "--quiet", "--depth=1", "--filter=tree:0", repoURL, "+refs/tags/*:refs/tags/*"); err != nil {
+		return nil, err
+	}
+	out, err := git(ctx, dir, "for-each-ref", "--format=%(refname:strip=2)%09%(creatordate:iso-strict)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]time.Time{}
+	for _, line := range strings.Split(string(out), "\n") {
+		tag, date, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("the date of the %s tag: %w", tag, err)
+		}
+		tags[tag] = t
+	}
+	return tags, nil
+}
+
+// releaseNotesURL is the release page of a tag on the forge of the module's repository,
+// or empty when the forge has no release pages.
+func releaseNotesURL(meta Meta, tag string) string {
+	repo := meta.Import.VCS.SourceRepo()
+	if !isForgeVCS(repo.Name) {
+		return ""
+	}
+	web := strings.TrimSuffix(repo.RepoRoot.String(), ".git")
+	switch zerokit.Coalesce(meta.Source.Forge, detectForge(repo.RepoRoot.Host)) {
+	case ForgeGitHub, ForgeGitea:
+		return web + "/releases/tag/" + tag
+	case ForgeGitLab:
+		return web + "/-/releases/" + tag
+	case ForgeSourcehut:
+		return web + "/refs/" + tag
+	default:
+		return ""
+	}
+}
+
+// versionsPage is the data of a version listing page.
+type versionsPage struct {
+	ImportPath string
+	// PageURL is the path of the module's page on the site.
+	PageURL  string
+	Versions []MetaVersion
+}
+
+// versionsURL is the path of an import path's version listing on the site.
+func versionsURL(domain, importPath string) string {
+	_, basePath := splitDomain(domain)
+	return basePath + "/" + path.Join(path.Dir(pagePath(domain, importPath)), versionsDir) + "/"
+}
+
+// renderVersions renders the version listing of an import path into the versions directory of its page.
+// An import path without versions has no listing.
+func renderVersions(tmpl *template.Template, domain string, meta Meta, importPath string) ([]File, error) {
+	versions, ok := meta.Versions[importPath]
+	if !ok || len(versions) == 0 {
+		return nil, nil
+	}
+	_, basePath := splitDomain(domain)
+	page := versionsPage{
+		ImportPath: importPath,
+		PageURL:    pageURLPaths(basePath, pagePath(domain, importPath))[0],
+		Versions:   versions,
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "versions", page); err != nil {
+		return nil, fmt.Errorf("versions template execution failed: %w", err)
+	}
+	return []File{{
+		Path:    path.Join(path.Dir(pagePath(domain, importPath)), versionsDir, "index.html"),
+		Content: buf.Bytes(),
+		Module:  meta.Import.Prefix,
+	}}, nil
+}
@@ -0,0 +1,144 @@
+package vanity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.llib.dev/frameless/pkg/zerokit"
+	"golang.org/x/mod/module"
+)
+
+// WarmUpOptions configure how the module proxy and pkg.go.dev are asked about the modules.
+type WarmUpOptions struct {
+	// ProxyURL is the module proxy, which fetches the latest version of a module when it is asked about it.
+	//
+	// default: https://proxy.golang.org
+	ProxyURL string
+	// PkgGoDevURL is the documentation site, which indexes the versions that the module proxy fetched.
+	//
+	// default: https://pkg.go.dev
+	PkgGoDevURL string
+	// Workers is the number of modules warmed up concurrently.
+	//
+	// default: 4
+	Workers int
+	// Timeout is how long the warm-up of a single module may take.
+	//
+	// default: 30s
+	Timeout time.Duration
+}
+
+// WarmUp is the outcome of warming up a module.
+type WarmUp struct {
+	// ImportPath is the module path, which is a module's, a nested module's, or a major version's.
+	ImportPath string
+	// Version is the latest version of the module, according to the module proxy.
+	Version string
+	// Documented tells whether pkg.go.dev has the page of the latest version already.
+	// A new version is indexed by pkg.go.dev a few minutes after the module proxy fetched it.
+	Documented bool
+	// Err is the failure of asking the module proxy, like a module without a version.
+	Err error
+}
+
+// WarmUpModules asks the module proxy for the latest version of every public module, nested module and major version,
+// and pkg.go.dev for the page of that version, so the new modules and the new versions are fetched and indexed
+// right after their pages go live, rather than when someone first asks for them.
+// The modules are warmed up concurrently, each within the timeout, and the results are in the order of the modules.
+// The private and the gone modules are left out, since the public services can't fetch them.
+func WarmUpModules(ctx context.Context, metas []Meta, opts WarmUpOptions) []WarmUp {
+	var importPaths []string
+	for _, meta := range metas {
+		if meta.Private || meta.Tombstone.Kind != "" {
+			continue
+		}
+		importPaths = append(importPaths, pageImportPaths(meta)...)
+	}
+	var (
+		results = make([]WarmUp, len(importPaths))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+		workers = opts.Workers
+	)
+	if workers < 1 {
+		workers = 4
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = warmUp(ctx, importPaths[i], opts)
+			}
+		}()
+	}
+	for i := range importPaths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func warmUp(ctx context.Context, importPath string, opts WarmUpOptions) WarmUp {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := WarmUp{ImportPath: importPath}
+	version, err := latestProxyVersion(ctx, zerokit.Coalesce(opts.ProxyURL, "https://proxy.golang.org"), importPath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Version = version
+
+	pageURL := strings.TrimSuffix(zerokit.Coalesce(opts.PkgGoDevURL, "https://pkg.go.dev"), "/") + "/" + importPath + "@" + version
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp, err := discoveryClient().Do(req)
+	if err != nil {
+		// the module proxy has the version already, and pkg.go.dev catches up with it on its own
+		return result
+	}
+	result.Documented = resp.StatusCode == http.StatusOK
+	_ = drain(resp)
+	return result
+}
+
+// latestProxyVersion asks the module proxy for the latest version of the module,
+// which makes the proxy fetch it from the origin of the module when it doesn't have it yet.
+func latestProxyVersion(ctx context.Context, proxyURL, modulePath string) (string, error) {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(proxyURL, "/")+"/"+escaped+"/@latest", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := discoveryClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = drain(resp) }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: unexpected status code: %s", req.URL, resp.Status)
+	}
+	var info ModuleInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("the latest version of %s can't be decoded: %w", modulePath, err)
+	}
+	return info.Version, nil
+}
@@ -0,0 +1,207 @@
+package vanity
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The code hosts whose push webhooks the Webhook receives.
+const (
+	WebhookGitHub = "github"
+	WebhookGitLab = "gitlab"
+	WebhookGitea  = "gitea"
+)
+
+// PushEvent is a push to a repository, as the webhook of its code host tells it.
+type PushEvent struct {
+	// Provider is the code host that sent the event, like github.
+	Provider string
+	// Repository is the web URL of the pushed repository.
+	Repository string
+	// CloneURLs are the URLs that the repository is cloned from, over HTTPS and SSH.
+	CloneURLs []string
+	// Ref is the pushed ref, like refs/heads/main or refs/tags/v1.2.0.
+	Ref string
+}
+
+// Of tells whether the push is of the repository with the URL,
+// which can be its web URL, or any of its clone URLs.
+func (e PushEvent) Of(repoURL string) bool {
+	want := webhookRepoKey(repoURL)
+	for _, u := range append([]string{e.Repository}, e.CloneURLs...) {
+		if u != "" && webhookRepoKey(u) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookRepoKey is the repoKey of a repository URL, which also understands the scp-like SSH URLs,
+// like git@github.com:adamluzsi/testcase.git.
+func webhookRepoKey(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		if userHost, p, ok := strings.Cut(rawURL, ":"); ok {
+			_, host, _ := strings.Cut(userHost, "@")
+			if host == "" {
+				host = userHost
+			}
+			rawURL = "ssh://" + host + "/" + strings.TrimPrefix(p, "/")
+		}
+	}
+	return repoKey(rawURL)
+}
+
+// Webhook receives the push webhooks of GitHub, GitLab and Gitea,
+// so the pushes to the repositories can keep the pages fresh, without waiting for a periodic refresh.
+// The requests have to carry the shared secret: as the HMAC signature of GitHub and Gitea, or as the token of GitLab.
+// The ping events are answered, and the events other than a push are accepted, but ignored.
+type Webhook struct {
+	// Secret is the secret of the webhook, configured at the code host too.
+	// Without a secret, every request is rejected, since anyone could trigger the pushes.
+	Secret string
+	// OnPush is called with every verified push.
+	// It's called while the code host waits for the response, so it should only schedule the work.
+	OnPush func(ctx context.Context, event PushEvent)
+}
+
+// webhookMaxPayload is the size limit of a webhook payload, which is the limit of GitHub.
+const webhookMaxPayload = 25 << 20
+
+func (h *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxPayload+1))
+	if err != nil {
+		http.Error(w, "failed to read the payload", http.StatusBadRequest)
+		return
+	}
+	if webhookMaxPayload < len(body) {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Gitea sends the headers of GitHub too, so it's recognised first
+	var provider, event string
+	switch {
+	case r.Header.Get("X-Gitea-Event") != "":
+		provider, event = WebhookGitea, r.Header.Get("X-Gitea-Event")
+	case r.Header.Get("X-Gitlab-Event") != "":
+		provider, event = WebhookGitLab, r.Header.Get("X-Gitlab-Event")
+	case r.Header.Get("X-GitHub-Event") != "":
+		provider, event = WebhookGitHub, r.Header.Get("X-GitHub-Event")
+	default:
+		http.Error(w, "unknown webhook", http.StatusBadRequest)
+		return
+	}
+	if !h.verify(provider, r.Header, body) {
+		http.Error(w, "invalid webhook signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch event {
+	case "ping":
+		_, _ = io.WriteString(w, "pong\n")
+		return
+	case "push", "Push Hook", "Tag Push Hook":
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if provider == WebhookGitHub && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "invalid form payload", http.StatusBadRequest)
+			return
+		}
+		body = []byte(form.Get("payload"))
+	}
+	push, err := parsePushEvent(provider, body)
+	if err != nil {
+		http.Error(w, "invalid push payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.OnPush != nil {
+		h.OnPush(r.Context(), push)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verify checks the secret of the webhook request, the way its code host sends it.
+func (h *Webhook) verify(provider string, header http.Header, body []byte) bool {
+	if h.Secret == "" {
+		return false
+	}
+	switch provider {
+	case WebhookGitLab:
+		return secureEqual(header.Get("X-Gitlab-Token"), h.Secret)
+	case WebhookGitea:
+		return validHMAC(header.Get("X-Gitea-Signature"), h.Secret, body)
+	default:
+		signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		return ok && validHMAC(signature, h.Secret, body)
+	}
+}
+
+// validHMAC tells whether the hex encoded signature is the HMAC-SHA256 of the body with the secret.
+func validHMAC(signature, secret string, body []byte) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+type githubPushDTO struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		HTMLURL  string `json:"html_url"`
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+	} `json:"repository"`
+}
+
+type gitlabPushDTO struct {
+	Ref     string `json:"ref"`
+	Project struct {
+		WebURL     string `json:"web_url"`
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+	} `json:"project"`
+}
+
+// parsePushEvent reads the payload of a push, which has the same shape at GitHub and Gitea.
+func parsePushEvent(provider string, body []byte) (PushEvent, error) {
+	event := PushEvent{Provider: provider}
+	if provider == WebhookGitLab {
+		var dto gitlabPushDTO
+		if err := json.Unmarshal(body, &dto); err != nil {
+			return PushEvent{}, err
+		}
+		event.Ref, event.Repository = dto.Ref, dto.Project.WebURL
+		event.CloneURLs = []string{dto.Project.GitHTTPURL, dto.Project.GitSSHURL}
+	} else {
+		var dto githubPushDTO
+		if err := json.Unmarshal(body, &dto); err != nil {
+			return PushEvent{}, err
+		}
+		event.Ref, event.Repository = dto.Ref, dto.Repository.HTMLURL
+		event.CloneURLs = []string{dto.Repository.CloneURL, dto.Repository.SSHURL}
+	}
+	if event.Repository == "" {
+		return PushEvent{}, fmt.Errorf("the payload has no repository")
+	}
+	return event, nil
+}